@@ -15,32 +15,83 @@
 package commander
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/timburks/gott/operations"
 	gott "github.com/timburks/gott/types"
 )
 
 // The Commander converts user input into commands to the editor.
 type Commander struct {
-	editor         gott.Editor
-	batch          bool     // true if commander is running a lisp script
-	mode           int      // editor mode
-	debug          bool     // debug mode displays information about events (key codes, etc)
-	editKeys       string   // edit key sequences in progress
-	commandText    string   // command as it is being typed on the command line
-	searchText     string   // text for searches as it is being typed
-	searchForward  bool	// true to search forward, false to search backward
-	lispText       string   // lisp command as it is being typed
-	multiplierText string   // multiplier string as it is being entered
-	message        string   // status message
-	lastKey        gott.Key // last key pressed
-	lastCh         rune     // last character pressed (if key == 0)
+	editor           gott.Editor
+	batch            bool          // true if commander is running a lisp script
+	allowShell       bool          // true if scripts in batch mode can run shell commands
+	mode             int           // editor mode
+	debug            bool          // debug mode displays information about events (key codes, etc)
+	editKeys         string        // edit key sequences in progress
+	editKeysTime     time.Time     // time when the edit key sequence was started
+	commandText      string        // command as it is being typed on the command line
+	searchText       string        // text for searches as it is being typed
+	searchForward    bool          // true to search forward, false to search backward
+	searchWholeWord  bool          // true to only match whole words, as * and # do
+	lispText         string        // lisp command as it is being typed
+	multiplierText   string        // multiplier string as it is being entered
+	message          string        // status message
+	lastKey          gott.Key      // last key pressed
+	lastCh           rune          // last character pressed (if key == 0)
+	dragging         bool          // true while the left mouse button is down
+	dragStart        gott.Point    // cursor position where a mouse drag started
+	promptLabel      string        // question asked in prompt mode
+	paletteText      string        // query typed in palette mode
+	paletteItems     []string      // items that can be chosen in palette mode
+	paletteAction    func(string)  // performed on the item chosen in palette mode
+	promptText       string        // answer as it is being typed in prompt mode
+	display          gott.Display  // display used to read answers in prompt mode
+	lastSubstitution *substitution // most recent substitution, for repeats
+	yanking          bool          // true if the current event yanked text
+	yanked           bool          // true if the previous event yanked text, so yank-pop can replace it
+	literal          bool          // true after ^V in insert mode, while a literal character is read
+	literalText      string        // prefix and hex digits of a literal character code
+	paused           time.Duration // time that input has been paused for while timeouts are pending
 }
 
+// rangeCommands are the commands that can be preceded by a line range.
+var rangeCommands = map[string]bool{
+	"w":             true,
+	"w!":            true,
+	"reflow":        true,
+	"align":         true,
+	"reverse":       true,
+	"join":          true,
+	"rot13":         true,
+	"base64-encode": true,
+	"base64-decode": true,
+}
+
+// Edit key sequences that aren't completed within this time are abandoned.
+const editKeysTimeout = 2 * time.Second
+
 func NewCommander(e gott.Editor) *Commander {
-	return &Commander{editor: e, mode: gott.ModeEdit}
+	c := &Commander{editor: e, mode: gott.ModeEdit}
+	// hooks run for files opened or saved by keys, ex commands, and scripts alike
+	e.SetFileHandlers(func(path string) {
+		c.runHook(hookOpen, path)
+	}, func(path string) {
+		c.runHook(hookSave, path)
+	})
+	return c
+}
+
+// SetDisplay sets the display that scripts use to ask questions.
+func (c *Commander) SetDisplay(d gott.Display) {
+	c.display = d
 }
 
 func (c *Commander) getLastKey() gott.Key {
@@ -73,6 +124,12 @@ func (c *Commander) getModeName() string {
 		return "search-backward"
 	case gott.ModeLisp:
 		return "lisp"
+	case gott.ModeVisual:
+		return "visual"
+	case gott.ModePrompt:
+		return "prompt"
+	case gott.ModePalette:
+		return "palette"
 	case gott.ModeQuit:
 		return "quit"
 	default:
@@ -85,23 +142,76 @@ func (c *Commander) IsRunning() bool {
 }
 
 func (c *Commander) ProcessEvent(event *gott.Event) error {
+	defer c.checkModeChange(c.mode)
 	if c.debug {
 		c.message = fmt.Sprintf("event=%+v", event)
 	}
 	switch event.Type {
 	case gott.EventKey:
+		c.yanked, c.yanking = c.yanking, false
+		if event.Mod&gott.ModAlt != 0 && event.Ch == 'y' && c.mode == gott.ModeEdit {
+			c.parseEval("(yank-pop)")
+			return nil
+		}
+		if event.Mod&gott.ModAlt != 0 {
+			// Alt keys aren't bound, so handle them as Esc followed by the key
+			c.processKey(&gott.Event{Type: gott.EventKey, Key: gott.KeyEsc})
+			key := *event
+			key.Mod &^= gott.ModAlt
+			return c.processKey(&key)
+		}
 		return c.processKey(event)
 	case gott.EventResize:
 		return c.processResize(event)
+	case gott.EventMouse:
+		return c.processMouse(event)
 	default:
 		return nil
 	}
 }
 
+// checkModeChange runs the mode change hook if the mode differs from the previous mode.
+func (c *Commander) checkModeChange(previous int) {
+	if c.mode != previous {
+		c.runHook(hookModeChange, c.getModeName())
+	}
+}
+
 func (c *Commander) processResize(event *gott.Event) error {
 	return nil
 }
 
+// Mouse presses move the cursor and drags select text in the originating window.
+func (c *Commander) processMouse(event *gott.Event) error {
+	e := c.editor
+	if c.mode != gott.ModeEdit && c.mode != gott.ModeVisual {
+		return nil
+	}
+	switch event.Key {
+	case gott.KeyMouseLeft:
+		if c.dragging {
+			// motion with the button down extends the selection
+			e.MoveCursorToPosition(event.Position)
+			if c.mode != gott.ModeVisual && e.GetCursor() != c.dragStart {
+				e.StartSelection(c.dragStart)
+				c.mode = gott.ModeVisual
+			}
+			return nil
+		}
+		c.dragging = true
+		if c.mode == gott.ModeVisual {
+			e.ClearSelection()
+			c.mode = gott.ModeEdit
+		}
+		e.SelectWindowAtPosition(event.Position)
+		e.MoveCursorToPosition(event.Position)
+		c.dragStart = e.GetCursor()
+	case gott.KeyMouseRelease:
+		c.dragging = false
+	}
+	return nil
+}
+
 func (c *Commander) processKeyEditMode(event *gott.Event) error {
 	key := event.Key
 	ch := event.Ch
@@ -109,13 +219,26 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 	c.lastKey = event.Key
 	c.lastCh = event.Ch
 
+	// abandon multikey commands that weren't finished in time
+	if len(c.editKeys) > 0 && time.Since(c.editKeysTime) > editKeysTimeout {
+		c.editKeys = ""
+		c.multiplierText = ""
+	}
 	// multikey commands have highest precedence
 	if len(c.editKeys) > 0 {
-		switch c.editKeys {
+		editKeys := c.editKeys
+		c.editKeys = ""
+		handled := true
+		switch editKeys {
 		case "c":
 			switch ch {
 			case 'w':
 				c.parseEval("(change-word)")
+			case 'i', 'a', 's': // text objects and surroundings
+				c.editKeys = editKeys + string(ch)
+				c.editKeysTime = time.Now()
+			default:
+				handled = false
 			}
 		case "d":
 			switch ch {
@@ -123,26 +246,136 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 				c.parseEval("(delete-row)")
 			case 'w':
 				c.parseEval("(delete-word)")
+			case 'i', 'a', 's': // text objects and surroundings
+				c.editKeys = editKeys + string(ch)
+				c.editKeysTime = time.Now()
+			default:
+				handled = false
+			}
+		case "gc":
+			switch ch {
+			case 'c':
+				c.parseEval("(toggle-comment)")
+			case 'j':
+				c.parseEval("(toggle-comment-down)")
+			case 'k':
+				c.parseEval("(toggle-comment-up)")
+			case 'i', 'a': // text objects
+				c.editKeys = editKeys + string(ch)
+				c.editKeysTime = time.Now()
+			default:
+				handled = false
+			}
+		case "ci", "ca", "di", "da", "gci", "gca":
+			switch editKeys + string(ch) {
+			case "gcip":
+				c.parseEval("(toggle-comment-inner-paragraph)")
+			case "gcap":
+				c.parseEval("(toggle-comment-around-paragraph)")
+			case "cip":
+				c.parseEval("(change-inner-paragraph)")
+			case "cap":
+				c.parseEval("(change-around-paragraph)")
+			case "dip":
+				c.parseEval("(delete-inner-paragraph)")
+			case "dap":
+				c.parseEval("(delete-around-paragraph)")
+			case "ciw":
+				c.parseEval("(change-inner-word)")
+			case "caw":
+				c.parseEval("(change-around-word)")
+			case "diw":
+				c.parseEval("(delete-inner-word)")
+			case "daw":
+				c.parseEval("(delete-around-word)")
+			default:
+				handled = false
 			}
 		case "r":
 			if (key != 0 && key == gott.KeySpace) || (ch != 0) {
 				c.parseEval("(replace-character)")
+			} else {
+				handled = false
 			}
 		case "y":
 			switch ch {
 			case 'y': // YankRow
 				c.parseEval("(yank-row)")
+			case 's': // surround
+				c.editKeys = editKeys + string(ch)
+				c.editKeysTime = time.Now()
 			default:
-				break
+				handled = false
 			}
+		case "g":
+			switch ch {
+			case 'g':
+				c.parseEval("(goto-line)")
+			case 'j':
+				c.parseEval("(down-display-line)")
+			case 'k':
+				c.parseEval("(up-display-line)")
+			case '&':
+				c.parseEval("(repeat-substitution-everywhere)")
+			case 'q':
+				c.parseEval("(reflow-paragraph)")
+			case 'a':
+				c.parseEval("(character-info)")
+			case 'S':
+				c.parseEval("(split-line)")
+			case 'f':
+				c.parseEval("(goto-file)")
+			case 'x':
+				c.parseEval("(open-externally)")
+			case 'c': // comment operator
+				c.editKeys = editKeys + string(ch)
+				c.editKeysTime = time.Now()
+			case 'v':
+				c.parseEval("(reselect)")
+			case '(': // ( alone opens lisp mode
+				c.parseEval("(previous-sentence)")
+			default:
+				handled = false
+			}
+		case "z":
+			switch ch {
+			case 'z':
+				c.parseEval("(center-cursor)")
+			case 'g':
+				c.parseEval("(spell-add)")
+			default:
+				handled = false
+			}
+		case "]", "[":
+			switch ch {
+			case 'd':
+				if editKeys == "]" {
+					c.parseEval("(next-diagnostic)")
+				} else {
+					c.parseEval("(previous-diagnostic)")
+				}
+			case 's':
+				if editKeys == "]" {
+					c.parseEval("(next-misspelling)")
+				} else {
+					c.parseEval("(previous-misspelling)")
+				}
+			default:
+				handled = false
+			}
+		default:
+			handled = c.continueSurround(editKeys, ch)
+		}
+		// unrecognized keys (including Esc) cancel the sequence and its multiplier
+		if !handled {
+			c.multiplierText = ""
 		}
-		c.editKeys = ""
 		return nil
 	}
 	if key != 0 {
 		switch key {
 		case gott.KeyEsc:
-			break
+			c.multiplierText = ""
 		case gott.KeyCtrlB, gott.KeyPgup:
 			c.parseEval("(page-up)")
 		case gott.KeyCtrlF, gott.KeyPgdn:
@@ -151,6 +384,12 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 			c.parseEval("(half-page-down)")
 		case gott.KeyCtrlU:
 			c.parseEval("(half-page-up)")
+		case gott.KeyCtrlR:
+			c.parseEval("(redo)")
+		case gott.KeyCtrlY:
+			c.parseEval("(yank)")
+		case gott.KeyCtrlP:
+			c.openPalette(paletteCandidates(), c.runPaletteCommand)
 		case gott.KeyCtrlA, gott.KeyHome:
 			c.parseEval("(beginning-of-line)")
 		case gott.KeyCtrlE, gott.KeyEnd:
@@ -160,9 +399,17 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 		case gott.KeyArrowDown:
 			c.parseEval("(down)")
 		case gott.KeyArrowLeft:
-			c.parseEval("(left)")
+			if event.Mod&gott.ModCtrl != 0 {
+				c.parseEval("(previous-word)")
+			} else {
+				c.parseEval("(left)")
+			}
 		case gott.KeyArrowRight:
-			c.parseEval("(right)")
+			if event.Mod&gott.ModCtrl != 0 {
+				c.parseEval("(next-word)")
+			} else {
+				c.parseEval("(right)")
+			}
 		}
 	}
 	if ch != 0 {
@@ -198,6 +445,25 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 			} else {
 				c.parseEval("(repeat-search-backward)")
 			}
+		case '*':
+			c.parseEval("(search-word-forward)")
+		case '#':
+			c.parseEval("(search-word-backward)")
+		case 'N':
+			if c.searchForward {
+				c.parseEval("(repeat-search-backward)")
+			} else {
+				c.parseEval("(repeat-search-forward)")
+			}
+		//
+		// jump to a line, by default the last one
+		//
+		case 'G':
+			if c.multiplierText == "" {
+				c.parseEval("(last-line)")
+			} else {
+				c.parseEval("(goto-line)")
+			}
 		//
 		// cursor movement isn't logged
 		//
@@ -213,6 +479,14 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 			c.parseEval("(next-word)")
 		case 'b':
 			c.parseEval("(previous-word)")
+		case '}':
+			c.parseEval("(next-paragraph)")
+		case '%':
+			c.parseEval("(matching-bracket)")
+		case '{':
+			c.parseEval("(previous-paragraph)")
+		case ')':
+			c.parseEval("(next-sentence)")
 		case '>':
 			c.parseEval("(change-window)")
 		//
@@ -238,17 +512,14 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 			c.parseEval("(paste)")
 		case '~':
 			c.parseEval("(reverse-case-character)")
+		case 'v':
+			c.parseEval("(visual-mode)")
 		//
 		// a few keys open multi-key commands
 		//
-		case 'c':
-			c.editKeys = "c"
-		case 'd':
-			c.editKeys = "d"
-		case 'y':
-			c.editKeys = "y"
-		case 'r':
-			c.editKeys = "r"
+		case 'c', 'd', 'y', 'r', 'g', 'z', '[', ']':
+			c.editKeys = string(ch)
+			c.editKeysTime = time.Now()
 		//
 		// undo
 		//
@@ -259,6 +530,96 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 		//
 		case '.':
 			c.parseEval("(repeat)")
+		case '&':
+			c.parseEval("(repeat-substitution)")
+		}
+	}
+	// commands that don't use a multiplier drop it
+	if c.editKeys == "" && (ch < '0' || ch > '9') {
+		c.multiplierText = ""
+	}
+	return nil
+}
+
+func (c *Commander) processKeyVisualMode(event *gott.Event) error {
+	key := event.Key
+	ch := event.Ch
+	if key != 0 {
+		switch key {
+		case gott.KeyEsc:
+			c.editor.ClearSelection()
+			c.mode = gott.ModeEdit
+		case gott.KeyCtrlA, gott.KeyHome:
+			c.parseEval("(beginning-of-line)")
+		case gott.KeyCtrlE, gott.KeyEnd:
+			c.parseEval("(end-of-line)")
+		case gott.KeyArrowUp:
+			c.parseEval("(up)")
+		case gott.KeyArrowDown:
+			c.parseEval("(down)")
+		case gott.KeyArrowLeft:
+			if event.Mod&gott.ModCtrl != 0 {
+				c.parseEval("(previous-word)")
+			} else {
+				c.parseEval("(left)")
+			}
+		case gott.KeyArrowRight:
+			if event.Mod&gott.ModCtrl != 0 {
+				c.parseEval("(next-word)")
+			} else {
+				c.parseEval("(right)")
+			}
+		}
+	}
+	// g is only a prefix for gc, which comments the selected rows
+	editKeys := c.editKeys
+	c.editKeys = ""
+	if editKeys == "g" && ch == 'c' {
+		c.parseEval("(toggle-comment-selection)")
+		return nil
+	}
+	if ch != 0 {
+		switch ch {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			c.multiplierText += string(ch)
+		case 'g':
+			c.editKeys = "g"
+			c.editKeysTime = time.Now()
+		case 'h':
+			c.parseEval("(left)")
+		case 'j':
+			c.parseEval("(down)")
+		case 'k':
+			c.parseEval("(up)")
+		case 'l':
+			c.parseEval("(right)")
+		case 'w':
+			c.parseEval("(next-word)")
+		case 'b':
+			c.parseEval("(previous-word)")
+		case '}':
+			c.parseEval("(next-paragraph)")
+		case '%':
+			c.parseEval("(matching-bracket)")
+		case '{':
+			c.parseEval("(previous-paragraph)")
+		case ')':
+			c.parseEval("(next-sentence)")
+		case '(':
+			c.parseEval("(previous-sentence)")
+		case 'v':
+			c.editor.ClearSelection()
+			c.mode = gott.ModeEdit
+		case 'o', 'O': // selections are character-wise, so O is the same as o
+			c.parseEval("(swap-selection-ends)")
+		case 'y':
+			c.parseEval("(yank-selection)")
+		case ':':
+			c.parseEval("(selection-command-mode)")
+		case 'J':
+			c.parseEval("(join-selection)")
+		case 'I':
+			c.parseEval("(insert-at-selection-column)")
 		}
 	}
 	return nil
@@ -267,25 +628,54 @@ func (c *Commander) processKeyEditMode(event *gott.Event) error {
 func (c *Commander) processKeyInsertMode(event *gott.Event) error {
 	e := c.editor
 
+	if c.literal {
+		return c.processLiteralKey(event)
+	}
 	key := event.Key
 	ch := event.Ch
 	if key != 0 {
 		switch key {
+		case gott.KeyCtrlV:
+			c.literal = true
+			c.literalText = ""
 		case gott.KeyEsc: // end an insert operation.
 			e.CloseInsert()
+			e.GetActiveWindow().SetSecondaryCursors(nil)
 			c.mode = gott.ModeEdit
 			e.KeepCursorInRow()
 		case gott.KeyBackspace2:
 			e.BackspaceChar()
 		case gott.KeyTab:
-			e.InsertChar(' ')
-			for {
-				if e.GetCursor().Col%8 == 0 {
+			if e.GetLiteralTabs() {
+				e.InsertChar('\t')
+			} else {
+				// insert spaces up to the next tab stop
+				tabWidth := e.GetTabWidth()
+				column := e.GetActiveWindow().GetCursorDisplayColumn()
+				for n := tabWidth - column%tabWidth; n > 0; n-- {
+					e.InsertChar(' ')
+				}
+			}
+		case gott.KeyBacktab:
+			// delete inserted spaces back to the previous tab stop
+			tabWidth := e.GetTabWidth()
+			cursor := e.GetCursor()
+			column := e.GetActiveWindow().GetCursorDisplayColumn()
+			for n := (column-1)%tabWidth + 1; n > 0 && cursor.Col > 0; n-- {
+				previous := e.GetActiveWindow().GetBuffer().TextFromPosition(cursor.Row, cursor.Col-1)
+				if !strings.HasPrefix(previous, " ") || e.BackspaceChar() != ' ' {
 					break
 				}
-				e.InsertChar(' ')
+				cursor = e.GetCursor()
 			}
 		case gott.KeyEnter:
+			// secondary cursors only edit their own rows, so the text that
+			// they inserted is ended as its own undo step before the line break
+			if len(e.GetActiveWindow().GetSecondaryCursors()) > 0 {
+				e.CloseInsert()
+				e.GetActiveWindow().SetSecondaryCursors(nil)
+				e.Perform(&operations.Insert{Position: gott.InsertAtCursor, Commander: c}, 1)
+			}
 			e.InsertChar('\n')
 		case gott.KeySpace:
 			e.InsertChar(' ')
@@ -293,10 +683,49 @@ func (c *Commander) processKeyInsertMode(event *gott.Event) error {
 	}
 	if ch != 0 {
 		e.InsertChar(ch)
+		c.autoWrap()
 	}
 	return nil
 }
 
+// autoWrap moves the word at the cursor to a new line when it is typed past the text width.
+// Lines are only broken at spaces that were typed in the current insert, so the
+// wrapping is undone and repeated along with the rest of the insert.
+func (c *Commander) autoWrap() {
+	e := c.editor
+	cursor := e.GetCursor()
+	if !e.GetAutoWrap() || cursor.Col <= e.GetTextWidth() {
+		return
+	}
+	line := []rune(e.GetActiveWindow().GetBuffer().GetRowString(cursor.Row))
+	// after a line break, the whole row before the cursor was typed
+	inserted := e.GetInsertOperation().Length()
+	if inserted > cursor.Col {
+		inserted = cursor.Col
+	}
+	space := cursor.Col - 1
+	for space >= 0 && line[space] != ' ' {
+		space--
+	}
+	// wait for a word to be typed, and don't leave a line that is blank
+	if space < cursor.Col-inserted || space == cursor.Col-1 || strings.TrimSpace(string(line[0:space])) == "" {
+		return
+	}
+	word := line[space+1 : cursor.Col]
+	for i := 0; i <= len(word); i++ {
+		e.BackspaceChar()
+	}
+	// remove any other spaces that would end the line
+	for n := inserted - len(word) - 1; n > 0 && line[space-1] == ' '; n-- {
+		e.BackspaceChar()
+		space--
+	}
+	e.InsertChar('\n')
+	for _, c := range word {
+		e.InsertChar(c)
+	}
+}
+
 func (c *Commander) processKeyCommandMode(event *gott.Event) error {
 	key := event.Key
 	ch := event.Ch
@@ -317,12 +746,15 @@ func (c *Commander) processKeyCommandMode(event *gott.Event) error {
 	if ch != 0 {
 		c.commandText = c.commandText + string(ch)
 	}
+	if c.mode == gott.ModeCommand {
+		c.previewSubstitution()
+	} else {
+		c.editor.SetHighlightPattern("")
+	}
 	return nil
 }
 
 func (c *Commander) processKeySearchMode(event *gott.Event) error {
-	e := c.editor
-
 	key := event.Key
 	ch := event.Ch
 	if key != 0 {
@@ -330,13 +762,9 @@ func (c *Commander) processKeySearchMode(event *gott.Event) error {
 		case gott.KeyEsc:
 			c.mode = gott.ModeEdit
 		case gott.KeyEnter:
-			if c.mode == gott.ModeSearchForward {
-				c.searchForward = true
-				e.PerformSearchForward(c.searchText)
-			} else {
-				c.searchForward = false
-				e.PerformSearchBackward(c.searchText)
-			}
+			c.searchForward = c.mode == gott.ModeSearchForward
+			c.searchWholeWord = false
+			c.performSearch(c.searchForward)
 			c.mode = gott.ModeEdit
 		case gott.KeyBackspace2:
 			if len(c.searchText) > 0 {
@@ -352,6 +780,44 @@ func (c *Commander) processKeySearchMode(event *gott.Event) error {
 	return nil
 }
 
+// Search for the current search text in either direction.
+func (c *Commander) performSearch(forward bool) {
+	e := c.editor
+	if e.GetSearchCenter() {
+		// center the match if the search moves the cursor
+		defer func(cursor gott.Point) {
+			if e.GetCursor() != cursor {
+				e.CenterCursor()
+			}
+		}(e.GetCursor())
+	}
+	switch {
+	case forward && c.searchWholeWord:
+		e.PerformWholeWordSearchForward(c.searchText)
+	case forward:
+		e.PerformSearchForward(c.searchText)
+	case c.searchWholeWord:
+		e.PerformWholeWordSearchBackward(c.searchText)
+	default:
+		e.PerformSearchBackward(c.searchText)
+	}
+}
+
+// Search for the word under the cursor.
+func (c *Commander) searchForWordAtCursor(forward bool, multiplier int) {
+	word := c.editor.GetWordAtCursor()
+	if word == "" {
+		c.message = "No word under the cursor"
+		return
+	}
+	c.searchText = word
+	c.searchForward = forward
+	c.searchWholeWord = true
+	for i := 0; i < multiplier; i++ {
+		c.performSearch(forward)
+	}
+}
+
 func (c *Commander) processKeyLispMode(event *gott.Event) error {
 	key := event.Key
 	ch := event.Ch
@@ -360,7 +826,11 @@ func (c *Commander) processKeyLispMode(event *gott.Event) error {
 		case gott.KeyEsc:
 			c.mode = gott.ModeEdit
 		case gott.KeyEnter:
-			c.message = c.parseEval(c.lispText)
+			// show the result unless evaluation set a message
+			c.message = ""
+			if result := c.parseEval(c.lispText); c.message == "" {
+				c.message = result
+			}
 			// if evaluation didn't change the mode, set it back to edit
 			if c.mode == gott.ModeLisp {
 				c.mode = gott.ModeEdit
@@ -380,6 +850,54 @@ func (c *Commander) processKeyLispMode(event *gott.Event) error {
 	return nil
 }
 
+// Ask a question on the message bar and wait for the answer, reading events directly from the display.
+// This returns an empty string in batch mode, when there is no display, or if the user cancels with Esc.
+func (c *Commander) ask(prompt string) string {
+	if c.batch || c.display == nil {
+		return ""
+	}
+	mode := c.mode
+	c.mode = gott.ModePrompt
+	c.promptLabel = prompt
+	c.promptText = ""
+	for c.mode == gott.ModePrompt {
+		c.display.Render(c.editor, c)
+		event := c.display.GetNextEvent()
+		if event == nil {
+			c.promptText = ""
+			break
+		}
+		if event.Type == gott.EventKey {
+			c.processKeyPromptMode(event)
+		}
+	}
+	c.mode = mode
+	return c.promptText
+}
+
+func (c *Commander) processKeyPromptMode(event *gott.Event) error {
+	key := event.Key
+	ch := event.Ch
+	if key != 0 {
+		switch key {
+		case gott.KeyEsc:
+			c.promptText = ""
+			c.mode = gott.ModeEdit
+		case gott.KeyEnter:
+			c.mode = gott.ModeEdit
+		case gott.KeyBackspace2:
+			_, size := utf8.DecodeLastRuneInString(c.promptText)
+			c.promptText = c.promptText[0 : len(c.promptText)-size]
+		case gott.KeySpace:
+			c.promptText += " "
+		}
+	}
+	if ch != 0 {
+		c.promptText = c.promptText + string(ch)
+	}
+	return nil
+}
+
 func (c *Commander) processKey(event *gott.Event) error {
 	var err error
 	switch c.mode {
@@ -395,6 +913,12 @@ func (c *Commander) processKey(event *gott.Event) error {
 		err = c.processKeySearchMode(event)
 	case gott.ModeLisp:
 		err = c.processKeyLispMode(event)
+	case gott.ModeVisual:
+		err = c.processKeyVisualMode(event)
+	case gott.ModePrompt:
+		err = c.processKeyPromptMode(event)
+	case gott.ModePalette:
+		err = c.processKeyPaletteMode(event)
 	}
 	return err
 }
@@ -403,6 +927,13 @@ func (c *Commander) performCommand() {
 
 	e := c.editor
 
+	// substitutions can contain spaces, so they are handled before the command is split
+	if c.performSubstitution(c.commandText) {
+		c.commandText = ""
+		c.mode = gott.ModeEdit
+		return
+	}
+
 	parts := strings.Split(c.commandText, " ")
 	if len(parts) > 0 {
 
@@ -410,17 +941,37 @@ func (c *Commander) performCommand() {
 		if err == nil {
 			e.MoveCursorToLine(int(i))
 		}
+		start, end, verb, ranged := c.parseLineRange(parts[0])
+		if ranged {
+			if !rangeCommands[verb] {
+				c.message = fmt.Sprintf("Line ranges can't be used with %s", verb)
+				c.commandText = ""
+				c.mode = gott.ModeEdit
+				return
+			}
+			parts[0] = verb
+		}
 		switch parts[0] {
 		case "q":
+			if e.GetActiveWindow().GetName() == helpBufferName {
+				e.CloseActiveWindow()
+				break
+			}
 			c.mode = gott.ModeQuit
 			return
 		case "quit":
 			c.mode = gott.ModeQuit
 			return
+		case "help":
+			c.parseEval("(help)")
+		case "commands":
+			c.parseEval("(list-primitives)")
 		case "r":
 			if len(parts) == 2 {
 				filename := parts[1]
-				e.ReadFile(filename)
+				if err := e.ReadFile(filename); err != nil {
+					c.message = err.Error()
+				}
 			}
 		case "debug":
 			if len(parts) == 2 {
@@ -431,14 +982,33 @@ func (c *Commander) performCommand() {
 					c.message = ""
 				}
 			}
-		case "w":
+		case "w", "w!":
+			// writing part of the buffer over its own file needs to be forced
+			if ranged && len(parts) == 1 && parts[0] != "w!" {
+				c.message = "Use w! to write part of the buffer to its file"
+				break
+			}
 			var filename string
-			if len(parts) == 2 {
+			appending := false
+			if len(parts) == 3 && parts[1] == ">>" {
+				filename = parts[2]
+				appending = true
+			} else if len(parts) == 2 {
 				filename = parts[1]
 			} else {
 				filename = e.GetFileName()
 			}
-			e.WriteFile(filename)
+			var err error
+			if ranged {
+				err = e.WriteLines(filename, start, end, appending)
+			} else if appending {
+				err = e.AppendFile(filename)
+			} else {
+				err = e.WriteFile(filename)
+			}
+			if err != nil {
+				c.message = err.Error()
+			}
 		case "wq":
 			var filename string
 			if len(parts) == 2 {
@@ -446,9 +1016,117 @@ func (c *Commander) performCommand() {
 			} else {
 				filename = e.GetFileName()
 			}
-			e.WriteFile(filename)
+			if err := e.WriteFile(filename); err != nil {
+				c.message = err.Error()
+				break
+			}
 			c.mode = gott.ModeQuit
 			return
+		case "new":
+			e.CreateScratchWindow()
+		case "snapshot":
+			e.CreateSnapshotWindow()
+		case "u!":
+			c.parseEval("(undo-to-save)")
+		case "ascii":
+			c.parseEval("(character-info)")
+		case "earlier", "later":
+			arg := ""
+			if len(parts) > 1 {
+				arg = parts[1]
+			}
+			c.moveInHistory(arg, parts[0] == "earlier")
+		case "e", "e!":
+			if len(parts) > 1 {
+				c.message = "Only the current file can be reloaded"
+				break
+			}
+			if !strings.HasSuffix(parts[0], "!") && e.GetActiveWindow().GetBuffer().GetModified() {
+				c.message = "No write since last change (add ! to override)"
+				break
+			}
+			if err := e.ReloadActiveBuffer(); err != nil {
+				c.message = err.Error()
+			}
+		case "reflow":
+			if ranged {
+				c.reflowRows(start-1, end-1)
+			} else {
+				c.parseEval("(reflow-paragraph)")
+			}
+		case "align":
+			if len(parts) != 2 {
+				c.message = "Usage: align <delimiter>"
+			} else if ranged {
+				c.alignRows(start-1, end-1, parts[1])
+			} else {
+				start, end := e.ParagraphObjectRange(false)
+				c.alignRows(start.Row, end.Row, parts[1])
+			}
+		case "join":
+			if ranged {
+				c.joinRows(start-1, end-1)
+			} else {
+				c.parseEval("(join-line)")
+			}
+		case "reverse":
+			if ranged {
+				c.reverseRows(start-1, end-1)
+			} else {
+				c.parseEval("(reverse-lines)")
+			}
+		case "rot13", "base64-encode", "base64-decode":
+			if ranged {
+				c.transformRows(start-1, end-1, textTransforms[parts[0]])
+			} else {
+				c.parseEval("(" + parts[0] + ")")
+			}
+		case "retab":
+			c.parseEval("(tabs-to-spaces)")
+		case "retab!":
+			c.parseEval("(tabs-to-spaces #t)")
+		case "mksession":
+			if len(parts) == 2 {
+				err := ioutil.WriteFile(parts[1], []byte(e.SessionScript()), 0644)
+				if err != nil {
+					c.message = err.Error()
+				}
+			}
+		case "source":
+			if len(parts) == 2 {
+				// scripts can change the mode, so set it first
+				c.commandText = ""
+				c.mode = gott.ModeEdit
+				if err := c.sourceFile(parts[1]); err != nil {
+					c.message = err.Error()
+				}
+				return
+			}
+		case "set":
+			if len(parts) < 2 {
+				c.message = "Usage: set option=value"
+			}
+			for _, setting := range parts[1:] {
+				c.setOption(setting)
+			}
+		case "hash":
+			c.message = bufferHash(e.Bytes())
+		case "echo":
+			// show the result unless evaluation set a message
+			// the expression is the rest of the command, with its spacing
+			c.message = ""
+			if result := c.parseEval(c.commandText[len(parts[0]):]); c.message == "" {
+				c.message = result
+			}
+		case "replay":
+			if len(parts) == 2 {
+				c.commandText = ""
+				c.mode = gott.ModeEdit
+				if err := c.replayFile(parts[1]); err != nil {
+					c.message = err.Error()
+				}
+				return
+			}
 		case "fmt":
 			out, err := e.Gofmt(e.GetFileName(), e.Bytes())
 			if err == nil {
@@ -479,18 +1157,46 @@ func (c *Commander) performCommand() {
 			e.SelectWindowPrevious()
 		case "windows":
 			e.ListWindows()
+		case "diffthis":
+			c.parseEval("(diff-this)")
+		case "diffoff":
+			c.parseEval("(diff-off)")
+		case "find":
+			// the file finder changes the mode, so set it first
+			c.commandText = ""
+			c.mode = gott.ModeEdit
+			c.parseEval("(find-file)")
+			return
+		case "recent":
+			// the file finder changes the mode, so set it first
+			c.commandText = ""
+			c.mode = gott.ModeEdit
+			c.parseEval("(find-recent-file)")
+			return
+		case "changes":
+			if count, err := e.ListChanges(); err != nil {
+				c.message = err.Error()
+			} else if count == 0 {
+				c.message = "No changes since the last save"
+			}
 		case "clear":
 			e.LoadBytes([]byte{})
 		case "eval":
 			output := c.parseEval(string(e.Bytes()))
 			e.SelectWindow(0)
 			e.AppendBytes([]byte(output))
-		case "split":
-			e.SplitWindowVertically()
-		case "vsplit":
-			e.SplitWindowVertically()
-		case "hsplit":
-			e.SplitWindowHorizontally()
+		case "split", "vsplit", "hsplit":
+			if parts[0] == "hsplit" {
+				e.SplitWindowHorizontally()
+			} else {
+				e.SplitWindowVertically()
+			}
+			// optionally show a different file in the new window
+			if len(parts) == 2 {
+				if err := e.ReadFileIntoActiveWindow(parts[1]); err != nil {
+					c.message = err.Error()
+				}
+			}
 		case "close":
 			e.CloseActiveWindow()
 		case "layout":
@@ -503,6 +1209,78 @@ func (c *Commander) performCommand() {
 	c.mode = gott.ModeEdit
 }
 
+// Parse a line range like "1,10" at the start of a command and return the command that follows it.
+// Line numbers start at 1, "." is the cursor line, and "$" is the last line.
+func (c *Commander) parseLineRange(command string) (start, end int, verb string, ok bool) {
+	i := strings.IndexFunc(command, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != ',' && r != '.' && r != '$'
+	})
+	if i <= 0 {
+		return 0, 0, command, false
+	}
+	bounds := strings.Split(command[0:i], ",")
+	if len(bounds) != 2 {
+		return 0, 0, command, false
+	}
+	start, err := c.lineNumber(bounds[0])
+	if err != nil {
+		return 0, 0, command, false
+	}
+	end, err = c.lineNumber(bounds[1])
+	if err != nil {
+		return 0, 0, command, false
+	}
+	return start, end, command[i:], true
+}
+
+// setOption sets an option from a name=value pair.
+func (c *Commander) setOption(setting string) {
+	name, value := setting, ""
+	if i := strings.Index(setting, "="); i >= 0 {
+		name, value = setting[0:i], setting[i+1:]
+	}
+	switch name {
+	case "ff", "fileformat":
+		c.setFileFormat(value)
+	case "binary":
+		if err := c.editor.ViewBinary(); err != nil {
+			c.message = err.Error()
+		}
+	case "ts", "tabstop", "sw", "shiftwidth":
+		// the indentation of a buffer overrides the indentation that was found in it
+		width, err := strconv.Atoi(value)
+		if err != nil || width <= 0 {
+			c.message = "Invalid width: " + value
+			break
+		}
+		c.editor.GetActiveWindow().GetBuffer().SetIndentation(c.editor.GetLiteralTabs(), width)
+	case "et", "expandtab", "noet", "noexpandtab":
+		c.editor.GetActiveWindow().GetBuffer().SetIndentation(strings.HasPrefix(name, "no"), c.editor.GetTabWidth())
+	case "detectindent", "nodetectindent":
+		c.editor.SetDetectIndentation(name == "detectindent")
+	case "byteoffset", "nobyteoffset":
+		c.editor.SetShowByteOffset(name == "byteoffset")
+	default:
+		c.message = "Unknown option: " + name
+	}
+}
+
+// bufferHash returns the SHA-256 hash of buffer contents in hex.
+func bufferHash(bytes []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(bytes))
+}
+
+func (c *Commander) lineNumber(s string) (int, error) {
+	switch s {
+	case ".":
+		return c.editor.GetCursor().Row + 1, nil
+	case "$":
+		return c.editor.GetActiveWindow().GetBuffer().GetRowCount(), nil
+	default:
+		return strconv.Atoi(s)
+	}
+}
+
 func (c *Commander) getMultiplier() int {
 	if c.multiplierText == "" {
 		return 1
@@ -543,8 +1321,34 @@ func (c *Commander) GetMessageBarText(length int) string {
 		line += "?" + c.getSearchText()
 	case gott.ModeLisp:
 		line += c.getLispText()
+	case gott.ModePrompt:
+		line += c.promptLabel + c.promptText
+	case gott.ModePalette:
+		line += "> " + c.paletteText
 	default:
 		line += c.getMessage()
+		if line == "" {
+			b := c.editor.GetActiveWindow().GetBuffer()
+			if d, ok := b.GetDiagnosticForRow(c.editor.GetCursor().Row); ok {
+				line = fmt.Sprintf("%d:%d: %s", d.Row+1, d.Col+1, d.Message)
+			} else if b.GetRecovered() {
+				line = fmt.Sprintf("Recovered unsaved changes from %s; :w to keep them or :e! to discard them", b.GetSwapFileName())
+			} else if b.GetMixedLineEndings() && !b.GetModified() && b.GetLineEnding() == "mixed" {
+				line = "This file has mixed line endings; :set ff=unix or :set ff=dos to make them consistent"
+			}
+		}
+		// show a count or command that is being typed at the right
+		pending := c.multiplierText + c.editKeys
+		if c.literal {
+			pending = "^V" + c.literalText
+		}
+		if pending != "" && len(pending) < length {
+			width := length - len(pending) - 1
+			if len(line) > width {
+				line = line[0:width]
+			}
+			line += strings.Repeat(" ", length-len(line)-len(pending)) + pending
+		}
 	}
 	if len(line) > length {
 		line = line[0:length]
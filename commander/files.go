@@ -0,0 +1,200 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// These limit the files that are offered by the file finder.
+const (
+	finderDepth    = 8     // directories deeper than this aren't searched
+	finderMaxFiles = 10000 // the search stops after this many files
+)
+
+// ignoredDirectories are never searched for files.
+var ignoredDirectories = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+}
+
+// errFinderFull stops a walk that has found enough files.
+var errFinderFull = errors.New("too many files")
+
+// WalkFiles returns the paths of the files in a directory tree, relative to its root.
+// Ignored directories and directories that are too deep are skipped.
+func WalkFiles(root string) []string {
+	var files []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// skip anything that can't be read
+			return nil
+		}
+		relative, err := filepath.Rel(root, path)
+		if err != nil || relative == "." {
+			return nil
+		}
+		if info.IsDir() {
+			depth := strings.Count(relative, string(filepath.Separator)) + 1
+			if ignoredDirectories[info.Name()] || depth > finderDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(files) == finderMaxFiles {
+			return errFinderFull
+		}
+		files = append(files, relative)
+		return nil
+	})
+	return files
+}
+
+// editFile shows a file in the focused window.
+func (c *Commander) editFile(path string) {
+	if err := c.editor.ReadFileIntoActiveWindow(path); err != nil {
+		c.message = err.Error()
+	}
+}
+
+// isPathCharacter returns true for characters that can appear in a file path under the cursor.
+func isPathCharacter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("/.-_~", r)
+}
+
+// isURLCharacter returns true for characters that can appear in a URL under the cursor.
+func isURLCharacter(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune("\"'`<>()[]{}", r)
+}
+
+// tokenAtCursor returns the span of matching characters around the cursor.
+func (c *Commander) tokenAtCursor(matches func(rune) bool) string {
+	b := c.editor.GetActiveWindow().GetBuffer()
+	cursor := c.editor.GetCursor()
+	if cursor.Row >= b.GetRowCount() {
+		return ""
+	}
+	line := []rune(b.GetRowString(cursor.Row))
+	if cursor.Col >= len(line) || !matches(line[cursor.Col]) {
+		return ""
+	}
+	start := cursor.Col
+	for start > 0 && matches(line[start-1]) {
+		start--
+	}
+	end := cursor.Col
+	for end < len(line) && matches(line[end]) {
+		end++
+	}
+	return string(line[start:end])
+}
+
+// pathAtCursor returns the file path under the cursor, or an empty string if there is none.
+// Relative paths are resolved from the directory of the file in the active buffer.
+func (c *Commander) pathAtCursor() string {
+	path := c.tokenAtCursor(isPathCharacter)
+	if path == "" {
+		return ""
+	}
+	b := c.editor.GetActiveWindow().GetBuffer()
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(b.GetFileName()), path)
+	}
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+	return path
+}
+
+// gotoFile shows the file under the cursor in the focused window.
+func (c *Commander) gotoFile() {
+	path := c.pathAtCursor()
+	if path == "" {
+		c.message = "No file name under the cursor"
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		c.message = "Can't find file " + path
+		return
+	}
+	c.editFile(path)
+}
+
+// urlAtCursor returns the URL under the cursor, or an empty string if there is none.
+func (c *Commander) urlAtCursor() string {
+	token := strings.TrimRight(c.tokenAtCursor(isURLCharacter), ".,;:!?")
+	if strings.Contains(token, "://") || strings.HasPrefix(token, "mailto:") {
+		return token
+	}
+	return ""
+}
+
+// openerCommand returns the program that opens files and URLs with the system's handlers.
+func openerCommand() (string, []string) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "open", nil
+	case "windows":
+		// rundll32 passes the target on without a shell interpreting characters like &
+		return "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		return "xdg-open", nil
+	}
+}
+
+// StartOpener runs a program to open a file or URL without waiting for it to finish.
+// Tests can replace it to see what would be opened.
+var StartOpener = func(name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return errors.New("No program to open files was found: " + name)
+	}
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// wait in the background so that the finished program doesn't linger as a zombie
+	go cmd.Wait()
+	return nil
+}
+
+// openExternally opens the URL or file under the cursor with the system's handler.
+func (c *Commander) openExternally() {
+	target := c.urlAtCursor()
+	if target == "" {
+		target = c.pathAtCursor()
+		if target == "" {
+			c.message = "No URL or file name under the cursor"
+			return
+		}
+		if _, err := os.Stat(target); err != nil {
+			c.message = "Can't find file " + target
+			return
+		}
+	}
+	name, args := openerCommand()
+	if err := StartOpener(name, append(args, target)...); err != nil {
+		c.message = err.Error()
+		return
+	}
+	c.message = "Opened " + target
+}
@@ -0,0 +1,155 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// helpBufferName names the buffer that displays help.
+const helpBufferName = "*help*"
+
+// A binding describes keys or a command and what they do.
+type binding struct {
+	keys        string
+	description string
+}
+
+// editBindings describes the keys handled in edit mode.
+var editBindings = []binding{
+	{"h j k l", "move left, down, up, right"},
+	{"w b", "move to the next or previous word"},
+	{"} {", "move to the next or previous paragraph"},
+	{"%", "move to the matching bracket"},
+	{"]d [d", "move to the next or previous diagnostic"},
+	{"]s [s", "move to the next or previous misspelling"},
+	{"zg", "add the word at the cursor to the user dictionary"},
+	{") g(", "move to the next or previous sentence"},
+	{"gg G", "go to a line, by default the first or last"},
+	{"gj gk", "move down or up a display line"},
+	{"^A ^E", "move to the beginning or end of a line"},
+	{"^F ^B ^D ^U", "page down, up, half down, half up"},
+	{"zz", "scroll the cursor row to the middle of the window"},
+	{"i a I A o O", "insert text"},
+	{"x", "delete a character"},
+	{"dd dw", "delete a row or word"},
+	{"cw", "change a word"},
+	{"diw daw ciw caw", "delete or change a word object"},
+	{"dip dap cip cap", "delete or change a paragraph object"},
+	{"ysiw cs ds", "add, change, or delete surrounding delimiters"},
+	{"r", "replace a character"},
+	{"~", "reverse the case of a character"},
+	{"J", "join lines, or the rows of a selection"},
+	{"gS", "split the row at the cursor"},
+	{"gq", "rewrap the paragraph at the cursor to the text width"},
+	{"ga", "show the code of the character at the cursor"},
+	{"gf", "open the file whose path is under the cursor"},
+	{"gx", "open the URL or file under the cursor with the system's handler"},
+	{"gcc gcj gck gcip", "comment or uncomment rows, or a selection with gc"},
+	{"yy p", "yank a row and paste"},
+	{"^Y M-y", "insert deleted text, then replace it with older deletions"},
+	{"^V", "in insert mode, insert a key literally or a character by code (uXXXX, UXXXXXXXX, xXX)"},
+	{"v gv", "start or restore a visual selection"},
+	{"v :", "enter a command for the rows of a selection"},
+	{"v I", "insert at the same column of each row of a selection"},
+	{"u ^R", "undo or redo"},
+	{".", "repeat the last change"},
+	{"/ ?", "search forward or backward"},
+	{"n N", "repeat a search"},
+	{"* #", "search for the word under the cursor"},
+	{"& g&", "repeat a substitution on a row or everywhere"},
+	{">", "change windows"},
+	{":", "enter a command"},
+	{"^P", "find a command by name and run it"},
+	{"(", "evaluate lisp"},
+}
+
+// commandBindings describes the commands entered after ':'.
+var commandBindings = []binding{
+	{"w [file]", "write a file"},
+	{"w >> file", "append to a file"},
+	{"start,endw file", "write a range of lines, with w! to write them to the buffer's file"},
+	{"wq", "write and quit"},
+	{"q", "quit, or close help"},
+	{"r file", "read a file"},
+	{"earlier later [n|time]", "undo or redo n edits, or edits within a time like 30s"},
+	{"u!", "undo edits back to the last save"},
+	{"ascii", "show the code of the character at the cursor"},
+	{"e e!", "reload the current file, with ! discarding changes"},
+	{"s/pattern/replacement/", "substitute text"},
+	{"split vsplit hsplit [file]", "split a window"},
+	{"close", "close a window"},
+	{"new", "open a scratch buffer"},
+	{"snapshot", "open a read-only copy of the buffer"},
+	{"find", "find a file in the working directory and open it"},
+	{"recent", "find a recently opened file and open it"},
+	{"windows", "list windows"},
+	{"changes", "list lines changed since the last save"},
+	{"hash", "show the SHA-256 hash of the buffer"},
+	{"echo expr", "show the value of a lisp expression"},
+	{"set ff=unix|dos|mac", "convert line endings to LF, CRLF, or CR"},
+	{"set binary", "show the buffer as a read-only hex dump"},
+	{"set ts=N", "set the indentation width of the buffer"},
+	{"set et noet", "indent the buffer with spaces or tabs"},
+	{"set detectindent", "use the indentation found in files"},
+	{"set byteoffset", "show the byte offset of the cursor on the info bar"},
+	{"diffthis diffoff", "compare the buffers of two windows, or stop comparing"},
+	{"[range]reflow", "rewrap a range or the paragraph at the cursor to the text width"},
+	{"[range]align delimiter", "line up a delimiter in a range or the paragraph at the cursor"},
+	{"[range]join", "join the rows of a range, or the cursor row with the next"},
+	{"[range]reverse", "reverse the order of the rows in a range or the whole buffer"},
+	{"[range]rot13", "rotate the letters of a range or the whole buffer by 13"},
+	{"[range]base64-encode", "encode a range or the whole buffer in base64"},
+	{"[range]base64-decode", "decode a range or the whole buffer from base64"},
+	{"retab retab!", "convert tabs to spaces"},
+	{"mksession file", "save the window layout"},
+	{"source file", "run a lisp script"},
+	{"replay file", "apply the edits in a lisp script, starting at the top of the buffer"},
+	{"help", "show this help"},
+	{"commands", "list lisp primitives and their argument counts"},
+}
+
+// helpText returns a reference to keys, commands, and lisp primitives.
+func helpText() string {
+	var s strings.Builder
+	writeBindings := func(title string, bindings []binding) {
+		s.WriteString(title + "\n\n")
+		width := 0
+		for _, b := range bindings {
+			if len(b.keys) > width {
+				width = len(b.keys)
+			}
+		}
+		for _, b := range bindings {
+			fmt.Fprintf(&s, "  %-*s  %s\n", width, b.keys, b.description)
+		}
+		s.WriteString("\n")
+	}
+	s.WriteString("gott help\n\n")
+	writeBindings("Keys", editBindings)
+	writeBindings("Commands", commandBindings)
+	s.WriteString("Lisp primitives\n\n")
+	names := make([]string, len(primitives))
+	for i, p := range primitives {
+		names[i] = p.name
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.WriteString("  (" + name + ")\n")
+	}
+	return s.String()
+}
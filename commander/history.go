@@ -0,0 +1,65 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// moveInHistory undoes (earlier) or redoes (later) edits.
+// The argument is a count of edits, or a duration like "30s" that
+// selects the edits made within that time of the current state.
+func (c *Commander) moveInHistory(arg string, earlier bool) {
+	e := c.editor
+	step, next := e.PerformRedo, e.GetRedoTime
+	if earlier {
+		step, next = e.PerformUndo, e.GetUndoTime
+	}
+	if arg == "" {
+		arg = "1"
+	}
+	if count, err := strconv.Atoi(arg); err == nil {
+		for i := 0; i < count; i++ {
+			step()
+		}
+		return
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		c.message = fmt.Sprintf("Invalid count or time: %s", arg)
+		return
+	}
+	// the current state is as of the most recent edit that hasn't been undone,
+	// or just before the first edit if all edits have been undone
+	current, ok := e.GetUndoTime()
+	if !ok {
+		current, _ = e.GetRedoTime()
+	}
+	for {
+		t, ok := next()
+		if !ok {
+			return
+		}
+		if earlier && !t.After(current.Add(-d)) {
+			return
+		}
+		if !earlier && t.After(current.Add(d)) {
+			return
+		}
+		step()
+	}
+}
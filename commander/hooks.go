@@ -0,0 +1,102 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/steelseries/golisp"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// Hooks are lisp functions that are called when editor events occur.
+const (
+	hookOpen       = "on-open"        // called with the name of a file that was read
+	hookSave       = "on-save"        // called with the name of a file that was written
+	hookModeChange = "on-mode-change" // called with the name of the new mode
+	hookIdle       = "on-idle"        // called with the name of the active window when input pauses
+)
+
+// hooks holds the functions registered for each hook.
+var hooks = make(map[string][]*golisp.Data)
+
+func addHook(name string, function *golisp.Data) {
+	hooks[name] = append(hooks[name], function)
+}
+
+// NextEvent waits for the next input event from a display.
+// While input is paused, swap files are written and the idle hook is run, each once, when their
+// timeouts pass. NextEvent returns nil after each of these so that the display can be redrawn.
+func (c *Commander) NextEvent(d gott.Display) *gott.Event {
+	swap := time.Duration(c.editor.GetSwapInterval()) * time.Millisecond
+	idle := time.Duration(c.editor.GetIdleTimeout()) * time.Millisecond
+	wait := time.Duration(0)
+	for _, timeout := range []time.Duration{swap, idle} {
+		if timeout > c.paused && (wait == 0 || timeout-c.paused < wait) {
+			wait = timeout - c.paused
+		}
+	}
+	if wait == 0 {
+		c.paused = 0
+		return d.GetNextEvent()
+	}
+	if event := d.GetNextEventWithTimeout(wait); event != nil {
+		c.paused = 0
+		return event
+	}
+	c.paused += wait
+	if swap == c.paused {
+		c.Autosave()
+	}
+	if idle == c.paused {
+		c.Idle()
+	}
+	return nil
+}
+
+// Autosave saves swap files. It is called when no input has arrived for the swap interval.
+func (c *Commander) Autosave() {
+	if err := c.editor.WriteSwapFiles(); err != nil {
+		c.message = err.Error()
+	}
+}
+
+// Idle runs the idle hook. It is called when no input has arrived for the idle timeout.
+func (c *Commander) Idle() {
+	c.runHook(hookIdle, c.editor.GetActiveWindow().GetName())
+}
+
+// runHook calls the functions registered for a hook with a single argument.
+// Errors are reported as messages and don't stop the remaining functions.
+func (c *Commander) runHook(name string, argument string) {
+	functions := hooks[name]
+	if len(functions) == 0 {
+		return
+	}
+	commander = c
+	editor = c.editor
+	args := golisp.InternalMakeList(golisp.StringWithValue(argument))
+	for _, function := range functions {
+		if _, err := golisp.ApplyWithoutEval(function, args, golisp.Global); err != nil {
+			c.message = fmt.Sprintf("%s hook failed: %+v", name, err)
+			if c.batch {
+				os.Stderr.Write([]byte(c.message + "\n"))
+			}
+		}
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/steelseries/golisp"
 	"github.com/timburks/gott/operations"
@@ -29,8 +31,55 @@ import (
 var commander *Commander
 var editor gott.Editor
 
+// A primitive records the name and allowed argument counts of a primitive function.
+type primitive struct {
+	name     string
+	argCount string // e.g. "0|1"
+}
+
+// primitives lists the primitive functions in the order they were defined.
+var primitives []primitive
+
+// definePrimitive defines a primitive function and records it.
+func definePrimitive(name string, argCount string, function func(*golisp.Data, *golisp.SymbolTableFrame) (*golisp.Data, error)) {
+	primitives = append(primitives, primitive{name: name, argCount: argCount})
+	golisp.MakePrimitiveFunction(name, argCount, function)
+}
+
+// defineSpecialForm defines a primitive that receives its arguments unevaluated and records it.
+func defineSpecialForm(name string, argCount string, function func(*golisp.Data, *golisp.SymbolTableFrame) (*golisp.Data, error)) {
+	primitives = append(primitives, primitive{name: name, argCount: argCount})
+	golisp.MakeSpecialForm(name, argCount, function)
+}
+
+// evalBody evaluates each expression in a list and returns the last result.
+func evalBody(body *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+	for ; golisp.NotNilP(body); body = golisp.Cdr(body) {
+		if result, err = golisp.Eval(golisp.Car(body), env); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// isAfter returns true if a follows b in the buffer.
+func isAfter(a, b gott.Point) bool {
+	return a.Row > b.Row || (a.Row == b.Row && a.Col > b.Col)
+}
+
+// primitiveListing returns the names and argument counts of all primitives, sorted by name.
+func primitiveListing() string {
+	sorted := append([]primitive{}, primitives...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	var s strings.Builder
+	for _, p := range sorted {
+		fmt.Fprintf(&s, "%-32s %s\n", p.name, p.argCount)
+	}
+	return s.String()
+}
+
 func makePrimitiveFunction(name string, action func()) {
-	golisp.MakePrimitiveFunction(name, "0",
+	definePrimitive(name, "0",
 		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
 			action()
 			return nil, err
@@ -52,7 +101,7 @@ func argumentCountValue(name string, args *golisp.Data, env *golisp.SymbolTableF
 }
 
 func makePrimitiveFunctionWithMultiplier(name string, action func(multiplier int)) {
-	golisp.MakePrimitiveFunction(name, "0|1",
+	definePrimitive(name, "0|1",
 		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
 			if n, err := argumentCountValue(name, args, env); err == nil {
 				action(n)
@@ -74,7 +123,7 @@ func argumentStringValue(name string, args *golisp.Data, env *golisp.SymbolTable
 }
 
 func makePrimitiveFunctionWithString(name string, action func(s string)) {
-	golisp.MakePrimitiveFunction(name, "1",
+	definePrimitive(name, "1",
 		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
 			if n, err := argumentStringValue(name, args, env); err == nil {
 				action(n)
@@ -83,6 +132,44 @@ func makePrimitiveFunctionWithString(name string, action func(s string)) {
 		})
 }
 
+func argumentIntegerValue(name string, args *golisp.Data, env *golisp.SymbolTableFrame) (int, error) {
+	val := golisp.Car(args)
+	if !golisp.IntegerP(val) {
+		return 0, errors.New(fmt.Sprintf("%s requires an integer argument", name))
+	}
+	return int(golisp.IntegerValue(val)), nil
+}
+
+func makePrimitiveFunctionWithInteger(name string, action func(i int)) {
+	definePrimitive(name, "1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			i, err := argumentIntegerValue(name, args, env)
+			if err == nil {
+				action(i)
+			}
+			return nil, err
+		})
+}
+
+func argumentBooleanValue(name string, args *golisp.Data, env *golisp.SymbolTableFrame) (bool, error) {
+	val := golisp.Car(args)
+	if !golisp.BooleanP(val) {
+		return false, errors.New(fmt.Sprintf("%s requires a boolean argument", name))
+	}
+	return golisp.BooleanValue(val), nil
+}
+
+func makePrimitiveFunctionWithBoolean(name string, action func(b bool)) {
+	definePrimitive(name, "1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			b, err := argumentBooleanValue(name, args, env)
+			if err == nil {
+				action(b)
+			}
+			return nil, err
+		})
+}
+
 func init() {
 	golisp.Global.BindTo(
 		golisp.SymbolWithName("TWO"),
@@ -96,6 +183,14 @@ func init() {
 		editor.MoveCursor(gott.MoveUp, m)
 	})
 
+	makePrimitiveFunctionWithMultiplier("down-display-line", func(m int) {
+		editor.MoveCursorDisplayLine(gott.MoveDown, m)
+	})
+
+	makePrimitiveFunctionWithMultiplier("up-display-line", func(m int) {
+		editor.MoveCursorDisplayLine(gott.MoveUp, m)
+	})
+
 	makePrimitiveFunctionWithMultiplier("left", func(m int) {
 		editor.MoveCursor(gott.MoveLeft, m)
 	})
@@ -136,6 +231,37 @@ func init() {
 		editor.MoveCursorToPreviousWord(m)
 	})
 
+	makePrimitiveFunction("matching-bracket", func() {
+		editor.MoveCursorToMatchingBracket()
+	})
+
+	// diagnostic motions clear the message so that the diagnostic is shown instead
+	makePrimitiveFunctionWithMultiplier("next-diagnostic", func(m int) {
+		editor.MoveToNextDiagnostic(m)
+		commander.message = ""
+	})
+
+	makePrimitiveFunctionWithMultiplier("previous-diagnostic", func(m int) {
+		editor.MoveToPreviousDiagnostic(m)
+		commander.message = ""
+	})
+
+	makePrimitiveFunctionWithMultiplier("next-paragraph", func(m int) {
+		editor.MoveToNextParagraph(m)
+	})
+
+	makePrimitiveFunctionWithMultiplier("previous-paragraph", func(m int) {
+		editor.MoveToPreviousParagraph(m)
+	})
+
+	makePrimitiveFunctionWithMultiplier("next-sentence", func(m int) {
+		editor.MoveToNextSentence(m)
+	})
+
+	makePrimitiveFunctionWithMultiplier("previous-sentence", func(m int) {
+		editor.MoveToPreviousSentence(m)
+	})
+
 	makePrimitiveFunctionWithMultiplier("change-window", func(m int) {
 		editor.SelectWindow(m)
 	})
@@ -172,10 +298,32 @@ func init() {
 		editor.Perform(&operations.JoinLine{}, m)
 	})
 
+	makePrimitiveFunction("split-line", func() {
+		editor.Perform(&operations.SplitLine{}, 1)
+	})
+
 	makePrimitiveFunctionWithMultiplier("paste", func(m int) {
 		editor.Perform(&operations.Paste{}, m)
 	})
 
+	makePrimitiveFunction("yank", func() {
+		if text := editor.GetKill(); text != "" {
+			editor.Perform(&operations.Insert{Position: gott.InsertAtCursor, Text: text}, 1)
+			commander.yanking = true
+		}
+	})
+
+	// yank-pop replaces just-yanked text with the next older text in the kill ring
+	makePrimitiveFunction("yank-pop", func() {
+		if !commander.yanking && !commander.yanked {
+			return
+		}
+		editor.PerformUndo()
+		editor.RotateKillRing()
+		editor.Perform(&operations.Insert{Position: gott.InsertAtCursor, Text: editor.GetKill()}, 1)
+		commander.yanking = true
+	})
+
 	makePrimitiveFunctionWithMultiplier("reverse-case-character", func(m int) {
 		editor.Perform(&operations.ReverseCaseCharacter{}, m)
 	})
@@ -184,8 +332,47 @@ func init() {
 		editor.PerformUndo()
 	})
 
-	makePrimitiveFunctionWithMultiplier("repeat", func(m int) {
-		editor.Repeat()
+	makePrimitiveFunction("diff-this", func() {
+		if err := editor.DiffThis(); err != nil {
+			commander.message = err.Error()
+		}
+	})
+
+	makePrimitiveFunction("diff-off", func() {
+		editor.DiffOff()
+	})
+
+	makePrimitiveFunction("undo-to-save", func() {
+		if err := editor.UndoToSave(); err != nil {
+			commander.message = err.Error()
+		}
+	})
+
+	makePrimitiveFunctionWithMultiplier("redo", func(m int) {
+		for i := 0; i < m; i++ {
+			editor.PerformRedo()
+		}
+	})
+
+	definePrimitive("repeat", "0|1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			// without an argument or a pending multiplier, repeat with the original multiplier
+			m := 0
+			if golisp.Car(args) != nil || commander.multiplierText != "" {
+				m, err = argumentCountValue("repeat", args, env)
+			}
+			if err == nil {
+				editor.Repeat(m)
+			}
+			return nil, err
+		})
+
+	makePrimitiveFunction("repeat-substitution", func() {
+		commander.repeatSubstitution(false)
+	})
+
+	makePrimitiveFunction("repeat-substitution-everywhere", func() {
+		commander.repeatSubstitution(true)
 	})
 
 	makePrimitiveFunctionWithMultiplier("change-word", func(m int) {
@@ -196,6 +383,164 @@ func init() {
 		editor.Perform(&operations.DeleteRow{}, m)
 	})
 
+	makePrimitiveFunction("delete-inner-paragraph", func() {
+		commander.deleteParagraph(false)
+	})
+
+	makePrimitiveFunction("delete-around-paragraph", func() {
+		commander.deleteParagraph(true)
+	})
+
+	makePrimitiveFunction("reflow-paragraph", func() {
+		commander.reflowParagraph()
+	})
+
+	makePrimitiveFunctionWithMultiplier("toggle-comment", func(m int) {
+		row := editor.GetCursor().Row
+		commander.commentRows(row, row+m-1)
+	})
+
+	makePrimitiveFunctionWithMultiplier("toggle-comment-down", func(m int) {
+		row := editor.GetCursor().Row
+		commander.commentRows(row, row+m)
+	})
+
+	makePrimitiveFunctionWithMultiplier("toggle-comment-up", func(m int) {
+		row := editor.GetCursor().Row
+		commander.commentRows(row-m, row)
+	})
+
+	makePrimitiveFunction("toggle-comment-inner-paragraph", func() {
+		commander.commentParagraph(false)
+	})
+
+	makePrimitiveFunction("toggle-comment-around-paragraph", func() {
+		commander.commentParagraph(true)
+	})
+
+	makePrimitiveFunction("toggle-comment-selection", func() {
+		if start, end, ok := editor.GetSelection(); ok {
+			commander.commentRows(start.Row, end.Row)
+		}
+		editor.ClearSelection()
+		commander.mode = gott.ModeEdit
+	})
+
+	makePrimitiveFunction("join-selection", func() {
+		if start, end, ok := editor.GetSelection(); ok {
+			commander.joinRows(start.Row, end.Row)
+		}
+		editor.ClearSelection()
+		commander.mode = gott.ModeEdit
+	})
+
+	makePrimitiveFunction("insert-at-selection-column", func() {
+		if start, end, ok := editor.GetSelection(); ok {
+			col := start.Col
+			if end.Col < col {
+				col = end.Col
+			}
+			editor.ClearSelection()
+			commander.insertWithCursors(start.Row, end.Row, col)
+		}
+	})
+
+	makePrimitiveFunction("reverse-lines", func() {
+		if start, end, ok := editor.GetSelection(); ok {
+			commander.reverseRows(start.Row, end.Row)
+			editor.ClearSelection()
+			commander.mode = gott.ModeEdit
+		} else {
+			// the empty row after a final newline stays at the end
+			b := editor.GetActiveWindow().GetBuffer()
+			last := b.GetRowCount() - 1
+			if last > 0 && b.GetRowString(last) == "" {
+				last--
+			}
+			commander.reverseRows(0, last)
+		}
+	})
+
+	makePrimitiveFunction("rot13", func() {
+		commander.transformSelection(rot13)
+	})
+
+	makePrimitiveFunction("base64-encode", func() {
+		commander.transformSelection(base64Encode)
+	})
+
+	makePrimitiveFunction("base64-decode", func() {
+		commander.transformSelection(base64Decode)
+	})
+
+	definePrimitive("buffer-hash", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			return golisp.StringWithValue(bufferHash(editor.Bytes())), nil
+		})
+
+	makePrimitiveFunction("character-info", func() {
+		buffer := editor.GetActiveWindow().GetBuffer()
+		commander.message = CharacterInfo(buffer.GetCharacterAtCursor(editor.GetCursor()))
+	})
+
+	makePrimitiveFunction("change-inner-paragraph", func() {
+		commander.changeParagraph(false)
+	})
+
+	makePrimitiveFunction("change-around-paragraph", func() {
+		commander.changeParagraph(true)
+	})
+
+	makePrimitiveFunction("delete-inner-word", func() {
+		commander.deleteWord(false)
+	})
+
+	makePrimitiveFunction("delete-around-word", func() {
+		commander.deleteWord(true)
+	})
+
+	makePrimitiveFunction("change-inner-word", func() {
+		commander.changeWord(false)
+	})
+
+	makePrimitiveFunction("change-around-word", func() {
+		commander.changeWord(true)
+	})
+
+	makePrimitiveFunctionWithString("surround-inner-word", func(s string) {
+		if s != "" {
+			commander.surroundWord(false, []rune(s)[0])
+		}
+	})
+
+	makePrimitiveFunctionWithString("surround-around-word", func(s string) {
+		if s != "" {
+			commander.surroundWord(true, []rune(s)[0])
+		}
+	})
+
+	definePrimitive("change-surrounding", "2",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			delimiter, err := argumentStringValue("change-surrounding", args, env)
+			if err != nil {
+				return nil, err
+			}
+			replacement, err := argumentStringValue("change-surrounding", golisp.Cdr(args), env)
+			if err != nil {
+				return nil, err
+			}
+			if delimiter != "" && replacement != "" {
+				commander.changeSurrounding([]rune(delimiter)[0], []rune(replacement)[0])
+			}
+			return nil, nil
+		})
+
+	makePrimitiveFunctionWithString("delete-surrounding", func(s string) {
+		if s != "" {
+			commander.changeSurrounding([]rune(s)[0], 0)
+		}
+	})
+
 	makePrimitiveFunctionWithMultiplier("delete-word", func(m int) {
 		editor.Perform(&operations.DeleteWord{}, m)
 	})
@@ -204,11 +549,117 @@ func init() {
 		editor.YankRow(m)
 	})
 
+	makePrimitiveFunction("visual-mode", func() {
+		commander.mode = gott.ModeVisual
+		editor.StartSelection(editor.GetCursor())
+	})
+
+	makePrimitiveFunction("reselect", func() {
+		if editor.ReselectSelection() {
+			commander.mode = gott.ModeVisual
+		}
+	})
+
+	makePrimitiveFunction("swap-selection-ends", func() {
+		editor.SwapSelectionEnds()
+	})
+
+	makePrimitiveFunction("yank-selection", func() {
+		editor.YankSelection()
+		editor.ClearSelection()
+		commander.mode = gott.ModeEdit
+	})
+
+	makePrimitiveFunctionWithString("edit-file", func(s string) {
+		commander.editFile(s)
+	})
+
+	makePrimitiveFunction("find-file", func() {
+		commander.openPalette(WalkFiles("."), commander.editFile)
+	})
+
+	makePrimitiveFunction("find-recent-file", func() {
+		commander.openPalette(editor.GetRecentFiles(), commander.editFile)
+	})
+
+	makePrimitiveFunctionWithMultiplier("goto-line", func(m int) {
+		editor.MoveCursorToLine(m)
+	})
+
+	// goto-column, like goto-line, counts from 1
+	makePrimitiveFunctionWithMultiplier("goto-column", func(m int) {
+		cursor := editor.GetCursor()
+		cursor.Col = m - 1
+		editor.SetCursor(cursor)
+		editor.KeepCursorInRow()
+	})
+
+	definePrimitive("cursor-column", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			return golisp.IntegerWithValue(int64(editor.GetCursor().Col + 1)), nil
+		})
+
+	// cursor-char returns the character under the cursor, or an empty string on an empty row
+	definePrimitive("cursor-char", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			c := editor.GetActiveWindow().GetBuffer().GetCharacterAtCursor(editor.GetCursor())
+			if c == 0 {
+				return golisp.StringWithValue(""), nil
+			}
+			return golisp.StringWithValue(string(c)), nil
+		})
+
+	// char-forward and char-backward cross rows and return 0 within a row,
+	// 1 after moving to another row, or 2 if the end of the buffer was reached
+	definePrimitive("char-forward", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			return golisp.IntegerWithValue(int64(editor.MoveCursorForward())), nil
+		})
+
+	definePrimitive("char-backward", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			return golisp.IntegerWithValue(int64(editor.MoveCursorBackward())), nil
+		})
+
+	makePrimitiveFunction("center-cursor", func() {
+		editor.CenterCursor()
+	})
+
+	makePrimitiveFunction("last-line", func() {
+		editor.MoveCursorToLine(1e9)
+	})
+
+	makePrimitiveFunction("vsplit", func() {
+		editor.SplitWindowVertically()
+	})
+
+	makePrimitiveFunction("hsplit", func() {
+		editor.SplitWindowHorizontally()
+	})
+
+	makePrimitiveFunction("next-window", func() {
+		editor.SelectWindowNext()
+	})
+
+	makePrimitiveFunction("previous-window", func() {
+		editor.SelectWindowPrevious()
+	})
+
 	makePrimitiveFunction("command-mode", func() {
 		commander.mode = gott.ModeCommand
 		commander.commandText = ""
 	})
 
+	// commands typed for a selection start with the range of its rows
+	makePrimitiveFunction("selection-command-mode", func() {
+		commander.mode = gott.ModeCommand
+		commander.commandText = ""
+		if start, end, ok := editor.GetSelection(); ok {
+			commander.commandText = fmt.Sprintf("%d,%d", start.Row+1, end.Row+1)
+		}
+		editor.ClearSelection()
+	})
+
 	makePrimitiveFunction("lisp-mode", func() {
 		commander.mode = gott.ModeLisp
 		commander.lispText = "("
@@ -224,14 +675,69 @@ func init() {
 		commander.searchText = ""
 	})
 
-	makePrimitiveFunction("repeat-search-forward", func() {
-		editor.PerformSearchForward(commander.searchText)
+	makePrimitiveFunctionWithMultiplier("repeat-search-forward", func(m int) {
+		for i := 0; i < m; i++ {
+			commander.performSearch(true)
+		}
+	})
+
+	makePrimitiveFunctionWithMultiplier("repeat-search-backward", func(m int) {
+		for i := 0; i < m; i++ {
+			commander.performSearch(false)
+		}
+	})
+
+	makePrimitiveFunctionWithMultiplier("search-word-forward", func(m int) {
+		commander.searchForWordAtCursor(true, m)
 	})
 
-	makePrimitiveFunction("repeat-search-backward", func() {
-		editor.PerformSearchBackward(commander.searchText)
+	makePrimitiveFunctionWithMultiplier("search-word-backward", func(m int) {
+		commander.searchForWordAtCursor(false, m)
 	})
 
+	// (repeat-times n expr...) evaluates the expressions n times.
+	defineSpecialForm("repeat-times", ">=2",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			count, err := golisp.Eval(golisp.Car(args), env)
+			if err != nil {
+				return nil, err
+			}
+			if !golisp.IntegerP(count) {
+				return nil, errors.New("repeat-times requires an integer argument")
+			}
+			for i := 0; i < int(golisp.IntegerValue(count)); i++ {
+				if result, err = evalBody(golisp.Cdr(args), env); err != nil {
+					return nil, err
+				}
+			}
+			return result, nil
+		})
+
+	// (while-search "text" expr...) searches forward for text and evaluates the
+	// expressions at each match, stopping when no match follows the cursor.
+	defineSpecialForm("while-search", ">=2",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			text, err := golisp.Eval(golisp.Car(args), env)
+			if err != nil {
+				return nil, err
+			}
+			if !golisp.StringP(text) {
+				return nil, errors.New("while-search requires a string argument")
+			}
+			for {
+				cursor := editor.GetCursor()
+				editor.PerformSearchForward(golisp.StringValue(text))
+				// searches wrap around, so a match that doesn't follow the cursor ends the loop
+				if !isAfter(editor.GetCursor(), cursor) {
+					editor.SetCursor(cursor)
+					return result, nil
+				}
+				if result, err = evalBody(golisp.Cdr(args), env); err != nil {
+					return nil, err
+				}
+			}
+		})
+
 	makePrimitiveFunctionWithMultiplier("replace-character", func(m int) {
 		if commander.getLastKey() == gott.KeySpace {
 			editor.Perform(&operations.ReplaceCharacter{Character: rune(' ')}, m)
@@ -240,6 +746,269 @@ func init() {
 		}
 	})
 
+	definePrimitive("tabs-to-spaces", "0|1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			// by default only tabs in indentation are converted
+			all := false
+			if golisp.Car(args) != nil {
+				all, err = argumentBooleanValue("tabs-to-spaces", args, env)
+			}
+			if err == nil {
+				editor.Perform(&operations.Retab{LeadingOnly: !all}, 1)
+			}
+			return nil, err
+		})
+
+	makePrimitiveFunction("spaces-to-tabs", func() {
+		editor.Perform(&operations.Retab{ToTabs: true}, 1)
+	})
+
+	makePrimitiveFunctionWithInteger("set-tab-width", func(i int) {
+		editor.SetTabWidth(i)
+	})
+
+	makePrimitiveFunctionWithInteger("set-textwidth", func(i int) {
+		editor.SetTextWidth(i)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-search-center", func(b bool) {
+		editor.SetSearchCenter(b)
+	})
+
+	makePrimitiveFunctionWithString("set-fileformat", func(s string) {
+		commander.setFileFormat(s)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-virtualedit", func(b bool) {
+		editor.SetVirtualEdit(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-autowrap", func(b bool) {
+		editor.SetAutoWrap(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-literal-tabs", func(b bool) {
+		editor.SetLiteralTabs(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-detect-indentation", func(b bool) {
+		editor.SetDetectIndentation(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-show-byte-offset", func(b bool) {
+		editor.SetShowByteOffset(b)
+	})
+
+	makePrimitiveFunctionWithString("set-statusline", func(s string) {
+		editor.SetStatusLine(s)
+	})
+
+	makePrimitiveFunctionWithString("set-theme", func(s string) {
+		if err := editor.SetTheme(s); err != nil {
+			commander.message = err.Error()
+		}
+	})
+
+	makePrimitiveFunctionWithBoolean("set-dim-inactive", func(b bool) {
+		editor.SetDimInactive(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-minimap", func(b bool) {
+		editor.SetMinimap(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-change-signs", func(b bool) {
+		editor.SetChangeSigns(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-spell", func(b bool) {
+		if err := editor.SetSpell(b); err != nil {
+			commander.message = err.Error()
+		}
+	})
+
+	definePrimitive("spell-add", "0|1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			// by default the word at the cursor is added
+			word := editor.GetWordAtCursor()
+			if golisp.Car(args) != nil {
+				if word, err = argumentStringValue("spell-add", args, env); err != nil {
+					return nil, err
+				}
+			}
+			if err := editor.AddSpellingWord(word); err != nil {
+				commander.message = err.Error()
+			}
+			return nil, nil
+		})
+
+	makePrimitiveFunctionWithMultiplier("next-misspelling", func(m int) {
+		editor.MoveToNextMisspelling(m)
+	})
+
+	makePrimitiveFunctionWithMultiplier("previous-misspelling", func(m int) {
+		editor.MoveToPreviousMisspelling(m)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-ignorecase", func(b bool) {
+		editor.SetIgnoreCase(b)
+	})
+
+	makePrimitiveFunctionWithBoolean("set-smartcase", func(b bool) {
+		editor.SetSmartCase(b)
+	})
+
+	makePrimitiveFunctionWithInteger("set-esc-timeout", func(i int) {
+		editor.SetEscTimeout(i)
+	})
+
+	makePrimitiveFunctionWithInteger("set-idle-timeout", func(i int) {
+		editor.SetIdleTimeout(i)
+	})
+
+	makePrimitiveFunctionWithInteger("set-swap-interval", func(i int) {
+		editor.SetSwapInterval(i)
+	})
+
+	makePrimitiveFunction("quit", func() {
+		if editor.HasModifiedBuffers() {
+			commander.message = "There are unsaved changes; use (quit-all) to discard them"
+		} else {
+			commander.mode = gott.ModeQuit
+		}
+	})
+
+	makePrimitiveFunction("quit-all", func() {
+		commander.mode = gott.ModeQuit
+	})
+
+	makePrimitiveFunctionWithString("message", func(s string) {
+		commander.message = s
+		if commander.batch {
+			os.Stderr.Write([]byte(s + "\n"))
+		}
+	})
+
+	definePrimitive("ask", "1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			prompt, err := argumentStringValue("ask", args, env)
+			if err != nil {
+				return nil, err
+			}
+			return golisp.StringWithValue(commander.ask(prompt)), nil
+		})
+
+	definePrimitive("buffer-set", "2",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			key := golisp.Car(args)
+			if !golisp.SymbolP(key) && !golisp.StringP(key) {
+				return nil, errors.New("buffer-set requires a symbol or string key")
+			}
+			value := golisp.Cadr(args)
+			editor.GetActiveWindow().GetBuffer().SetVariable(golisp.StringValue(key), value)
+			return value, nil
+		})
+
+	definePrimitive("buffer-get", "1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			key := golisp.Car(args)
+			if !golisp.SymbolP(key) && !golisp.StringP(key) {
+				return nil, errors.New("buffer-get requires a symbol or string key")
+			}
+			value, _ := editor.GetActiveWindow().GetBuffer().GetVariable(golisp.StringValue(key)).(*golisp.Data)
+			return value, nil
+		})
+
+	definePrimitive("byte-offset", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			offset := editor.GetActiveWindow().GetBuffer().ByteOffset(editor.GetCursor())
+			return golisp.IntegerWithValue(int64(offset)), nil
+		})
+
+	makePrimitiveFunction("goto-file", func() {
+		commander.gotoFile()
+	})
+
+	makePrimitiveFunction("open-externally", func() {
+		commander.openExternally()
+	})
+
+	definePrimitive("url-at-cursor", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			return golisp.StringWithValue(commander.urlAtCursor()), nil
+		})
+
+	definePrimitive("file-at-cursor", "0",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			return golisp.StringWithValue(commander.pathAtCursor()), nil
+		})
+
+	definePrimitive("read-file", "1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			path, err := argumentStringValue("read-file", args, env)
+			if err != nil {
+				return nil, err
+			}
+			bytes, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return golisp.StringWithValue(string(bytes)), nil
+		})
+
+	definePrimitive("write-file", "2",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			path, err := argumentStringValue("write-file", args, env)
+			if err != nil {
+				return nil, err
+			}
+			contents := golisp.Cadr(args)
+			if !golisp.StringP(contents) {
+				return nil, errors.New("write-file requires a string argument")
+			}
+			if err = ioutil.WriteFile(path, []byte(golisp.StringValue(contents)), 0644); err != nil {
+				return nil, err
+			}
+			commander.runHook(hookSave, path)
+			return golisp.BooleanWithValue(true), nil
+		})
+
+	definePrimitive("shell", "1",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			command, err := argumentStringValue("shell", args, env)
+			if err != nil {
+				return nil, err
+			}
+			output, err := commander.runShell(command)
+			if err != nil {
+				return nil, err
+			}
+			return golisp.StringWithValue(output), nil
+		})
+
+	definePrimitive("add-hook", "2",
+		func(args *golisp.Data, env *golisp.SymbolTableFrame) (result *golisp.Data, err error) {
+			name, err := argumentStringValue("add-hook", args, env)
+			if err != nil {
+				return nil, err
+			}
+			function := golisp.Cadr(args)
+			if !golisp.FunctionOrPrimitiveP(function) {
+				return nil, errors.New("add-hook requires a function argument")
+			}
+			addHook(name, function)
+			return nil, nil
+		})
+
+	makePrimitiveFunction("list-primitives", func() {
+		editor.SelectWindow(0)
+		editor.LoadBytes([]byte(primitiveListing()))
+	})
+
+	makePrimitiveFunction("help", func() {
+		editor.ShowText(helpBufferName, helpText())
+	})
+
 	makePrimitiveFunctionWithString("print", func(s string) {
 		if commander.batch {
 			// if we are running in batch (eval) mode, write to output
@@ -263,6 +1032,26 @@ func (c *Commander) parseEval(command string) string {
 	}
 }
 
+// Evaluate a lisp file in the running editor.
+func (c *Commander) sourceFile(filename string) error {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if result := c.parseEval(string(bytes)); strings.HasPrefix(result, "ERR ") {
+		return errors.New(result)
+	}
+	return nil
+}
+
+// Apply the edits in a lisp script to the active buffer.
+// Scripts start at the top of the buffer, so recorded motions lead to the same rows.
+func (c *Commander) replayFile(filename string) error {
+	c.editor.ClearSelection()
+	c.editor.SetCursor(gott.Point{})
+	return c.sourceFile(filename)
+}
+
 func (c *Commander) ParseEvalFile(filename string) string {
 	bytes, err := ioutil.ReadFile(filename)
 	if err == nil {
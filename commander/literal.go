@@ -0,0 +1,108 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// literalCodeLengths gives the number of hex digits read after each literal code prefix.
+var literalCodeLengths = map[byte]int{'u': 4, 'U': 8, 'x': 2, 'X': 2}
+
+// processLiteralKey handles the keys typed after ^V in insert mode.
+// A prefix of u, U, or x starts a hex character code, which ends after
+// its full length or at the first key that isn't a hex digit. Other keys
+// are inserted literally, including control keys.
+func (c *Commander) processLiteralKey(event *gott.Event) error {
+	e := c.editor
+	if c.literalText == "" {
+		if _, ok := literalCodeLengths[byte(event.Ch)]; ok && event.Ch < utf8.RuneSelf {
+			c.literalText = string(event.Ch)
+			return nil
+		}
+		c.literal = false
+		if r := literalRune(event); r != 0 {
+			e.InsertChar(r)
+		}
+		return nil
+	}
+	digits := c.literalText[1:]
+	if isHexDigit(event.Ch) {
+		digits += string(event.Ch)
+		c.literalText += string(event.Ch)
+		if len(digits) < literalCodeLengths[c.literalText[0]] {
+			return nil
+		}
+		c.literal = false
+		c.insertCharacterCode(digits)
+		return nil
+	}
+	// an incomplete code is inserted before the key that ended it
+	c.literal = false
+	if digits == "" {
+		e.InsertChar(rune(c.literalText[0]))
+	} else {
+		c.insertCharacterCode(digits)
+	}
+	return c.processKeyInsertMode(event)
+}
+
+// insertCharacterCode inserts the character with a hex code.
+func (c *Commander) insertCharacterCode(digits string) {
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(value)) || value == 0 {
+		c.message = "Invalid character code: " + digits
+		return
+	}
+	c.editor.InsertChar(rune(value))
+}
+
+// literalRune returns the character for a key typed after ^V.
+func literalRune(event *gott.Event) rune {
+	if event.Ch != 0 {
+		return event.Ch
+	}
+	switch key := event.Key; {
+	case key >= gott.KeyCtrlA && key <= gott.KeyCtrlZ:
+		return rune(key-gott.KeyCtrlA) + 1
+	case key == gott.KeyEnter:
+		return '\r'
+	case key == gott.KeyEsc:
+		return 0x1b
+	case key == gott.KeyTab:
+		return '\t'
+	case key == gott.KeySpace:
+		return ' '
+	case key == gott.KeyBackspace2:
+		return 0x7f
+	}
+	return 0
+}
+
+// CharacterInfo describes the code of a character, as it is shown by ga.
+func CharacterInfo(r rune) string {
+	if r == 0 {
+		return "Nothing under the cursor"
+	}
+	return fmt.Sprintf("dec=%d hex=%x U+%04X", r, r, r)
+}
+
+func isHexDigit(ch rune) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
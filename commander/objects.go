@@ -0,0 +1,272 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/timburks/gott/operations"
+	gott "github.com/timburks/gott/types"
+)
+
+// Delete the rows of the paragraph around the cursor.
+func (c *Commander) deleteParagraph(around bool) {
+	start, end := c.editor.ParagraphObjectRange(around)
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.DeleteRow{}, end.Row-start.Row+1)
+}
+
+// Rewrap the rows of the paragraph around the cursor to the text width.
+func (c *Commander) reflowParagraph() {
+	start, end := c.editor.ParagraphObjectRange(false)
+	c.reflowRows(start.Row, end.Row)
+}
+
+// Rewrap a range of rows to the text width. Rows are numbered from zero.
+func (c *Commander) reflowRows(start, end int) {
+	last := c.editor.GetActiveWindow().GetBuffer().GetRowCount() - 1
+	if start < 0 || start > end || end > last {
+		c.message = "Invalid range"
+		return
+	}
+	c.editor.SetCursor(gott.Point{Row: start})
+	c.editor.Perform(&operations.Reflow{EndRow: end}, 1)
+}
+
+// Comment or uncomment a range of rows. Rows are numbered from zero.
+func (c *Commander) commentRows(start, end int) {
+	b := c.editor.GetActiveWindow().GetBuffer()
+	if b.GetCommentPrefix() == "" {
+		c.message = "No comment prefix for this file"
+		return
+	}
+	if start > end {
+		start, end = end, start
+	}
+	if last := b.GetRowCount() - 1; end > last {
+		end = last
+	}
+	if start < 0 {
+		start = 0
+	}
+	cursor := c.editor.GetCursor()
+	c.editor.SetCursor(gott.Point{Row: start})
+	c.editor.Perform(&operations.ToggleComment{}, end-start+1)
+	c.editor.SetCursor(cursor)
+	c.editor.KeepCursorInRow()
+}
+
+// Line up the first delimiter in each of a range of rows. Rows are numbered from zero.
+func (c *Commander) alignRows(start, end int, delimiter string) {
+	last := c.editor.GetActiveWindow().GetBuffer().GetRowCount() - 1
+	if start < 0 || start > end || end > last {
+		c.message = "Invalid range"
+		return
+	}
+	c.editor.SetCursor(gott.Point{Row: start})
+	c.editor.Perform(&operations.Align{Delimiter: delimiter}, end-start+1)
+}
+
+// Reverse the order of a range of rows. Rows are numbered from zero.
+func (c *Commander) reverseRows(start, end int) {
+	last := c.editor.GetActiveWindow().GetBuffer().GetRowCount() - 1
+	if start < 0 || start > end || end > last {
+		c.message = "Invalid range"
+		return
+	}
+	c.editor.SetCursor(gott.Point{Row: start})
+	c.editor.Perform(&operations.ReverseRows{}, end-start+1)
+}
+
+// Join a range of rows into one. A range of one row is joined with the next row.
+// Rows are numbered from zero.
+func (c *Commander) joinRows(start, end int) {
+	last := c.editor.GetActiveWindow().GetBuffer().GetRowCount() - 1
+	if start < 0 || start > end || end > last {
+		c.message = "Invalid range"
+		return
+	}
+	count := end - start
+	if count == 0 {
+		count = 1
+	}
+	c.editor.SetCursor(gott.Point{Row: start})
+	c.editor.Perform(&operations.JoinLine{}, count)
+}
+
+// Start inserting at a column of a range of rows, with a cursor on each row.
+// Rows that are shorter than the column are skipped.
+func (c *Commander) insertWithCursors(start, end, col int) {
+	e := c.editor
+	b := e.GetActiveWindow().GetBuffer()
+	var cursors []gott.Point
+	for row := start + 1; row <= end; row++ {
+		if utf8.RuneCountInString(b.GetRowString(row)) >= col {
+			cursors = append(cursors, gott.Point{Row: row, Col: col})
+		}
+	}
+	e.SetCursor(gott.Point{Row: start, Col: col})
+	e.GetActiveWindow().SetSecondaryCursors(cursors)
+	e.Perform(&operations.Insert{Position: gott.InsertAtCursor, Commander: c}, 1)
+}
+
+// Convert line endings to LF for "unix", CRLF for "dos", or CR for "mac".
+func (c *Commander) setFileFormat(format string) {
+	switch format {
+	case "unix", "dos", "mac":
+		c.editor.Perform(&operations.LineEndings{Format: format}, 1)
+	default:
+		c.message = "Unknown file format: " + format
+	}
+}
+
+// Comment or uncomment the rows of the paragraph around the cursor.
+func (c *Commander) commentParagraph(around bool) {
+	start, end := c.editor.ParagraphObjectRange(around)
+	c.commentRows(start.Row, end.Row)
+}
+
+// Replace the text of the paragraph around the cursor with text typed in insert mode.
+func (c *Commander) changeParagraph(around bool) {
+	start, end := c.editor.ParagraphObjectRange(around)
+	// the change ends after the last character of the paragraph
+	b := c.editor.GetActiveWindow().GetBuffer()
+	end.Col = len([]rune(b.TextFromPosition(end.Row, 0)))
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.Change{End: end, Commander: c}, 1)
+}
+
+// Delete the word under the cursor.
+func (c *Commander) deleteWord(around bool) {
+	start, end := c.editor.WordObjectRange(around)
+	end.Col++
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.DeleteRange{End: end}, 1)
+}
+
+// Replace the word under the cursor with text typed in insert mode.
+func (c *Commander) changeWord(around bool) {
+	start, end := c.editor.WordObjectRange(around)
+	end.Col++
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.Change{End: end, Commander: c}, 1)
+}
+
+// Return the delimiters that surround text for a delimiter character.
+// As in vim-surround, opening brackets add spaces inside the pair.
+func delimiterPair(ch rune) (open, close string) {
+	switch ch {
+	case '(':
+		return "( ", " )"
+	case ')':
+		return "(", ")"
+	case '[':
+		return "[ ", " ]"
+	case ']':
+		return "[", "]"
+	case '{':
+		return "{ ", " }"
+	case '}':
+		return "{", "}"
+	case '<':
+		return "< ", " >"
+	case '>':
+		return "<", ">"
+	}
+	return string(ch), string(ch)
+}
+
+// Return the characters of a delimiter pair, given either one of them.
+func delimiterCharacters(ch rune) (open, close rune) {
+	for _, pair := range []string{"()", "[]", "{}", "<>"} {
+		p := []rune(pair)
+		if ch == p[0] || ch == p[1] {
+			return p[0], p[1]
+		}
+	}
+	return ch, ch
+}
+
+// Surround the word under the cursor with delimiters.
+func (c *Commander) surroundWord(around bool, delimiter rune) {
+	start, end := c.editor.WordObjectRange(around)
+	open, close := delimiterPair(delimiter)
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.Surround{Start: start.Col, End: end.Col, Open: open, Close: close}, 1)
+}
+
+// Replace the delimiters around the cursor with other ones, or if replacement is zero, delete them.
+func (c *Commander) changeSurrounding(delimiter, replacement rune) {
+	start, end, ok := c.editor.DelimiterRange(delimiterCharacters(delimiter))
+	if !ok {
+		c.message = "No surrounding " + string(delimiter)
+		return
+	}
+	var open, close string
+	if replacement != 0 {
+		open, close = delimiterPair(replacement)
+	}
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.Surround{Start: start.Col, End: end.Col, Open: open, Close: close, Replace: true}, 1)
+}
+
+// Continue a surround command like "ysiw)", "cs\"'", or "ds(".
+// This returns false if the keys don't continue a surround command.
+func (c *Commander) continueSurround(editKeys string, ch rune) bool {
+	if ch == 0 {
+		return false
+	}
+	keys := []rune(editKeys + string(ch))
+	switch string(keys[0:2]) {
+	case "ys":
+		switch len(keys) {
+		case 3:
+			if ch != 'i' && ch != 'a' {
+				return false
+			}
+		case 4:
+			if ch != 'w' {
+				return false
+			}
+		default:
+			c.parseEval(fmt.Sprintf("(surround-%s-word %s)", objectScope(keys[2]), strconv.Quote(string(ch))))
+			return true
+		}
+	case "cs":
+		if len(keys) == 4 {
+			c.parseEval(fmt.Sprintf("(change-surrounding %s %s)", strconv.Quote(string(keys[2])), strconv.Quote(string(ch))))
+			return true
+		}
+	case "ds":
+		c.parseEval(fmt.Sprintf("(delete-surrounding %s)", strconv.Quote(string(ch))))
+		return true
+	default:
+		return false
+	}
+	c.editKeys = string(keys)
+	c.editKeysTime = time.Now()
+	return true
+}
+
+// Name the scope of a text object, which is selected with 'i' or 'a'.
+func objectScope(ch rune) string {
+	if ch == 'a' {
+		return "around"
+	}
+	return "inner"
+}
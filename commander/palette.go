@@ -0,0 +1,180 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// The palette finds an item, like a command or a file, by fuzzy matching its name as it is typed.
+// Enter chooses the best match.
+
+// paletteSize is the maximum number of matches that are shown.
+const paletteSize = 8
+
+// commandNamePattern matches the words in command bindings that name commands.
+var commandNamePattern = regexp.MustCompile(`^(\[range\])?([a-z]+!?)$`)
+
+// paletteCandidates returns the names of the commands that can run without arguments.
+// Ex commands start with ':' and the others are lisp primitives.
+func paletteCandidates() []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, b := range commandBindings {
+		for _, word := range strings.Fields(b.keys) {
+			m := commandNamePattern.FindStringSubmatch(word)
+			if m == nil || m[2] == "file" || seen[m[2]] {
+				continue
+			}
+			seen[m[2]] = true
+			candidates = append(candidates, ":"+m[2])
+		}
+	}
+	for _, p := range primitives {
+		if p.argCount == "0" || strings.HasPrefix(p.argCount, "0|") {
+			candidates = append(candidates, p.name)
+		}
+	}
+	return candidates
+}
+
+// FuzzyMatch returns a score for a candidate that contains the characters of a query
+// in order, ignoring case, or false if it doesn't. Characters that start words and
+// characters that follow the previous match score higher.
+func FuzzyMatch(query, candidate string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	c := []rune(strings.ToLower(candidate))
+	score := 0
+	j := 0
+	previous := -2
+	for i := 0; i < len(c) && j < len(q); i++ {
+		if c[i] != q[j] {
+			continue
+		}
+		score++
+		if i == 0 || !unicode.IsLetter(c[i-1]) && !unicode.IsDigit(c[i-1]) {
+			score += 2
+		}
+		if i == previous+1 {
+			score++
+		}
+		previous = i
+		j++
+	}
+	return score, j == len(q)
+}
+
+// FuzzyRank returns the candidates that match a query, best first.
+// Candidates with the same score are sorted by length and then by name.
+func FuzzyRank(query string, candidates []string) []string {
+	type match struct {
+		candidate string
+		score     int
+	}
+	var matches []match
+	for _, candidate := range candidates {
+		if score, ok := FuzzyMatch(query, candidate); ok {
+			matches = append(matches, match{candidate: candidate, score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.candidate) != len(b.candidate) {
+			return len(a.candidate) < len(b.candidate)
+		}
+		return a.candidate < b.candidate
+	})
+	ranked := make([]string, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.candidate
+	}
+	return ranked
+}
+
+// openPalette starts palette mode with a list of items and the action to perform on the chosen one.
+func (c *Commander) openPalette(items []string, action func(string)) {
+	c.paletteText = ""
+	c.paletteItems = items
+	c.paletteAction = action
+	c.mode = gott.ModePalette
+}
+
+func (c *Commander) processKeyPaletteMode(event *gott.Event) error {
+	key := event.Key
+	ch := event.Ch
+	if key != 0 {
+		switch key {
+		case gott.KeyEsc:
+			c.mode = gott.ModeEdit
+		case gott.KeyEnter:
+			c.mode = gott.ModeEdit
+			if matches := FuzzyRank(c.paletteText, c.paletteItems); len(matches) > 0 {
+				c.paletteAction(matches[0])
+			} else {
+				c.message = "No match"
+			}
+		case gott.KeyBackspace2:
+			_, size := utf8.DecodeLastRuneInString(c.paletteText)
+			c.paletteText = c.paletteText[0 : len(c.paletteText)-size]
+		}
+	}
+	if ch != 0 {
+		c.paletteText += string(ch)
+	}
+	return nil
+}
+
+// runPaletteCommand runs an ex command or a lisp primitive chosen in the palette.
+func (c *Commander) runPaletteCommand(name string) {
+	if strings.HasPrefix(name, ":") {
+		c.commandText = name[1:]
+		c.performCommand()
+	} else {
+		c.parseEval("(" + name + ")")
+	}
+}
+
+// GetPaletteLines returns the best matches for the palette text, padded to a length,
+// when the palette is open. The best match is first.
+func (c *Commander) GetPaletteLines(count, length int) []string {
+	if c.mode != gott.ModePalette {
+		return nil
+	}
+	if count > paletteSize {
+		count = paletteSize
+	}
+	matches := FuzzyRank(c.paletteText, c.paletteItems)
+	if len(matches) > count {
+		matches = matches[0:count]
+	}
+	lines := make([]string, len(matches))
+	for i, m := range matches {
+		line := []rune(" " + m)
+		if len(line) < length {
+			line = append(line, []rune(strings.Repeat(" ", length-len(line)))...)
+		}
+		lines[i] = string(line[0:length])
+	}
+	return lines
+}
@@ -0,0 +1,46 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// SetAllowShell allows scripts that run in batch mode to run shell commands.
+func (c *Commander) SetAllowShell(allow bool) {
+	c.allowShell = allow
+}
+
+// Run a command with sh and return its standard output.
+// If the command fails, the error holds its standard error.
+func (c *Commander) runShell(command string) (string, error) {
+	if c.batch && !c.allowShell {
+		return "", errors.New("shell commands in scripts require the --allow-shell option")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,138 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/timburks/gott/operations"
+	gott "github.com/timburks/gott/types"
+)
+
+// A substitution replaces text that matches a regular expression.
+type substitution struct {
+	pattern     string
+	replacement string
+	global      bool // true to replace every match in a row
+}
+
+// Parse a command like "s/pattern/replacement/g", optionally preceded by a line range or "%",
+// into the range of lines and the parts that follow the "s". The command may be incomplete.
+// This returns false if the command isn't a substitution.
+func (c *Commander) parseSubstitution(command string) (first, last int, parts []string, ok bool) {
+	first = c.editor.GetCursor().Row + 1
+	last = first
+	if strings.HasPrefix(command, "%") {
+		first = 1
+		last = c.editor.GetActiveWindow().GetBuffer().GetRowCount()
+		command = command[1:]
+	} else if start, end, verb, ok := c.parseLineRange(command); ok {
+		first, last, command = start, end, verb
+	}
+	if len(command) < 2 || command[0] != 's' ||
+		unicode.IsLetter(rune(command[1])) || unicode.IsDigit(rune(command[1])) || command[1] == ' ' {
+		return 0, 0, nil, false
+	}
+	return first, last, splitSubstitution(command[2:], command[1]), true
+}
+
+// Perform a substitution command.
+// This returns false if the command isn't a substitution.
+func (c *Commander) performSubstitution(command string) bool {
+	first, last, parts, ok := c.parseSubstitution(command)
+	if !ok {
+		return false
+	}
+	if len(parts) < 2 {
+		c.message = "Substitutions need a pattern and a replacement"
+		return true
+	}
+	s := &substitution{pattern: parts[0], replacement: parts[1]}
+	if len(parts) > 2 {
+		s.global = strings.Contains(parts[2], "g")
+	}
+	c.substitute(s, first, last)
+	return true
+}
+
+// Highlight the text that the substitution being typed would replace.
+// Other commands remove the highlighting, and patterns that don't compile yet leave it unchanged.
+func (c *Commander) previewSubstitution() {
+	pattern := ""
+	if _, _, parts, ok := c.parseSubstitution(c.commandText); ok {
+		pattern = parts[0]
+	}
+	c.editor.SetHighlightPattern(pattern)
+}
+
+// Split the parts of a substitution at delimiters that aren't escaped with backslashes.
+func splitSubstitution(text string, delimiter byte) []string {
+	parts := make([]string, 0)
+	var part string
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && text[i+1] == delimiter {
+			part += string(delimiter)
+			i++
+		} else if text[i] == delimiter {
+			parts = append(parts, part)
+			part = ""
+		} else {
+			part += string(text[i])
+		}
+	}
+	return append(parts, part)
+}
+
+// Substitute in a range of lines, which are numbered from 1.
+func (c *Commander) substitute(s *substitution, first, last int) {
+	if _, err := regexp.Compile(s.pattern); err != nil {
+		c.message = err.Error()
+		return
+	}
+	c.lastSubstitution = s
+	if first < 1 {
+		first = 1
+	}
+	if last < first {
+		return
+	}
+	c.editor.SetCursor(gott.Point{Row: first - 1, Col: 0})
+	c.editor.Perform(&operations.Substitute{
+		Pattern:     s.pattern,
+		Replacement: s.replacement,
+		Global:      s.global,
+		Rows:        last - first + 1,
+	}, 1)
+}
+
+// Repeat the last substitution on the current line without its flags,
+// or with its flags on every line.
+func (c *Commander) repeatSubstitution(everywhere bool) {
+	if c.lastSubstitution == nil {
+		c.message = "No previous substitution"
+		return
+	}
+	if everywhere {
+		c.substitute(c.lastSubstitution, 1, c.editor.GetActiveWindow().GetBuffer().GetRowCount())
+		return
+	}
+	line := c.editor.GetCursor().Row + 1
+	c.substitute(&substitution{
+		pattern:     c.lastSubstitution.pattern,
+		replacement: c.lastSubstitution.replacement,
+	}, line, line)
+}
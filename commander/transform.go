@@ -0,0 +1,100 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package commander
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/timburks/gott/operations"
+	gott "github.com/timburks/gott/types"
+)
+
+// textTransforms are the transforms that can be applied to selections and line ranges by name.
+var textTransforms = map[string]func(string) (string, error){
+	"rot13":         rot13,
+	"base64-encode": base64Encode,
+	"base64-decode": base64Decode,
+}
+
+// rot13 rotates each letter halfway through the alphabet, so it is its own inverse.
+func rot13(text string) (string, error) {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+		return r
+	}, text), nil
+}
+
+func base64Encode(text string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(text)), nil
+}
+
+// base64Decode ignores the line breaks and spaces that are often used to wrap encoded text.
+func base64Decode(text string) (string, error) {
+	bytes, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// transformSelection replaces the selected text, or else the whole buffer, with the result of a transform.
+func (c *Commander) transformSelection(transform func(string) (string, error)) {
+	e := c.editor
+	start, end, ok := e.GetSelection()
+	if ok {
+		// selections include the character at their end
+		end.Col++
+		e.ClearSelection()
+		c.mode = gott.ModeEdit
+		c.transformRange(start, end, transform)
+		return
+	}
+	// the empty row after a final newline isn't transformed
+	b := e.GetActiveWindow().GetBuffer()
+	last := b.GetRowCount() - 1
+	if last > 0 && b.GetRowString(last) == "" {
+		last--
+	}
+	c.transformRows(0, last, transform)
+}
+
+// transformRows replaces a range of rows with the result of a transform. Rows are numbered from zero.
+func (c *Commander) transformRows(start, end int, transform func(string) (string, error)) {
+	b := c.editor.GetActiveWindow().GetBuffer()
+	if start < 0 || start > end || end >= b.GetRowCount() {
+		c.message = "Invalid range"
+		return
+	}
+	c.transformRange(gott.Point{Row: start}, gott.Point{Row: end, Col: len([]rune(b.GetRowString(end)))}, transform)
+}
+
+// transformRange replaces the text from start up to end with the result of a transform.
+// If the transform fails, the buffer isn't changed.
+func (c *Commander) transformRange(start, end gott.Point, transform func(string) (string, error)) {
+	b := c.editor.GetActiveWindow().GetBuffer()
+	text, err := transform(b.GetText(start, end))
+	if err != nil {
+		c.message = err.Error()
+		return
+	}
+	c.editor.SetCursor(start)
+	c.editor.Perform(&operations.ReplaceRange{End: end, Text: text}, 1)
+}
@@ -0,0 +1,153 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package display provides an in-memory display that can be inspected by tests.
+package display
+
+import (
+	"strings"
+	"time"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// A Cell records what was drawn at a single position on a display.
+type Cell struct {
+	Ch       rune
+	Color    gott.Color
+	Reversed bool
+	Dimmed   bool
+}
+
+// Display records drawn cells in a grid instead of showing them on a terminal.
+type Display struct {
+	size   gott.Size
+	cells  [][]Cell
+	cursor gott.Point
+	events []*gott.Event // events to be returned by GetNextEvent
+}
+
+// NewDisplay creates an in-memory display of the specified size.
+func NewDisplay(size gott.Size) *Display {
+	d := &Display{size: size}
+	d.Clear()
+	return d
+}
+
+// Clear fills a display with blank cells.
+func (d *Display) Clear() {
+	d.cells = make([][]Cell, d.size.Rows)
+	for i := range d.cells {
+		d.cells[i] = make([]Cell, d.size.Cols)
+		for j := range d.cells[i] {
+			d.cells[i][j] = Cell{Ch: ' ', Color: gott.ColorWhite}
+		}
+	}
+}
+
+func (d *Display) Close() {}
+
+// GetNextEvent returns the next queued event, or nil if there are none.
+func (d *Display) GetNextEvent() *gott.Event {
+	if len(d.events) == 0 {
+		return nil
+	}
+	event := d.events[0]
+	d.events = d.events[1:]
+	return event
+}
+
+// GetNextEventWithTimeout returns the next queued event without waiting, or nil if there are none.
+func (d *Display) GetNextEventWithTimeout(timeout time.Duration) *gott.Event {
+	return d.GetNextEvent()
+}
+
+// AddEvent queues an event to be returned by GetNextEvent.
+func (d *Display) AddEvent(event *gott.Event) {
+	d.events = append(d.events, event)
+}
+
+// Render draws an editor and the message bar of its commander, as a Screen does.
+func (d *Display) Render(e gott.Editor, c gott.Commander) {
+	editSize := d.size
+	// Save the last row on the display for the message bar.
+	editSize.Rows -= 1
+	e.SetSize(editSize)
+	e.LayoutWindows()
+	d.Clear()
+	e.RenderWindows(d)
+	// palette matches are drawn up from the message bar
+	for i, line := range c.GetPaletteLines(d.size.Rows-1, d.size.Cols) {
+		for x, ch := range []rune(line) {
+			d.SetCellReversed(x, d.size.Rows-2-i, ch, gott.ColorWhite)
+		}
+	}
+	text := c.GetMessageBarText(d.size.Cols)
+	for x, ch := range []rune(text) {
+		d.SetCell(x, d.size.Rows-1, ch, gott.ColorWhite)
+	}
+}
+
+func (d *Display) SetCell(j int, i int, c rune, color gott.Color) {
+	d.setCell(j, i, Cell{Ch: c, Color: color})
+}
+
+func (d *Display) SetCellReversed(j int, i int, c rune, color gott.Color) {
+	d.setCell(j, i, Cell{Ch: c, Color: color, Reversed: true})
+}
+
+func (d *Display) SetCellDimmed(j int, i int, c rune, color gott.Color) {
+	d.setCell(j, i, Cell{Ch: c, Color: color, Dimmed: true})
+}
+
+// Like a terminal, a display ignores cells that are drawn outside its bounds.
+func (d *Display) setCell(j int, i int, cell Cell) {
+	if i >= 0 && i < d.size.Rows && j >= 0 && j < d.size.Cols {
+		d.cells[i][j] = cell
+	}
+}
+
+func (d *Display) SetCursor(position gott.Point) {
+	d.cursor = position
+}
+
+// GetCursor returns the most recently set cursor position.
+func (d *Display) GetCursor() gott.Point {
+	return d.cursor
+}
+
+// GetSize returns the size of a display.
+func (d *Display) GetSize() gott.Size {
+	return d.size
+}
+
+// GetCell returns the cell drawn at a position.
+func (d *Display) GetCell(p gott.Point) Cell {
+	if p.Row < 0 || p.Row >= d.size.Rows || p.Col < 0 || p.Col >= d.size.Cols {
+		return Cell{}
+	}
+	return d.cells[p.Row][p.Col]
+}
+
+// GetRowText returns the characters drawn on a row without trailing spaces.
+func (d *Display) GetRowText(i int) string {
+	if i < 0 || i >= d.size.Rows {
+		return ""
+	}
+	var s strings.Builder
+	for _, cell := range d.cells[i] {
+		s.WriteRune(cell.Ch)
+	}
+	return strings.TrimRight(s.String(), " ")
+}
@@ -17,11 +17,12 @@ package editor
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	gott "github.com/timburks/gott/types"
 )
 
-// A Buffer represents a file being edited. 
+// A Buffer represents a file being edited.
 // Buffers are displayed in windows but also may be manipulated offscreen.
 type Buffer struct {
 	Name         string
@@ -30,12 +31,34 @@ type Buffer struct {
 	fileName     string
 	languageMode string
 	Highlighted  bool
+	modified     bool
+	variables    map[string]interface{}
+	recovered    bool // true if the contents were recovered from a swap file
+	listeners    []func(gott.Buffer)
+	diagnostics  []gott.Diagnostic // problems found in the buffer, like syntax errors
+	savedBytes   []byte            // contents when the buffer was last read or saved
+	changeSigns  []rune            // signs for rows that differ from the saved contents, or nil if stale
+	version      int               // incremented by each change
+	lineEnding   string            // line ending style: LF, CRLF, CR, or mixed
+	lineBreak    string            // separator between rows: "\n", or "\r" for old Mac files
+	lineEndingAt int               // version of the buffer when its line ending style was found
+	mixedEndings bool              // true if the loaded text had mixed line endings
+	encoding     string            // encoding of the loaded text: UTF-8, binary, or unknown if it isn't valid UTF-8
+	binary       bool              // true if the buffer shows a hex dump of a binary file
+	indentFound  bool              // true if the indentation of the loaded text was found
+	indentSet    bool              // true if the indentation was set, overriding any that was found
+	indentTabs   bool              // true if the buffer is indented with tabs
+	indentWidth  int               // width of each level of indentation, or 0 for the editor's tab width
+	undo         []change          // stack of operations to undo
+	redo         []change          // stack of undone operations to redo
 }
 
 func NewBuffer() *Buffer {
 	b := &Buffer{}
 	b.rows = make([]*Row, 0)
 	b.Highlighted = false
+	b.encoding = "UTF-8"
+	b.lineBreak = "\n"
 	return b
 }
 
@@ -56,10 +79,50 @@ func (b *Buffer) GetReadOnly() bool {
 	return b.ReadOnly
 }
 
+func (b *Buffer) GetModified() bool {
+	return b.modified
+}
+
+func (b *Buffer) SetModified(modified bool) {
+	b.modified = modified
+}
+
+// Mark the buffer as changed. Changes invalidate highlighting and are reported to change listeners.
+// Read-only buffers are never considered modified.
+// Functions that change the buffer call this when they finish, usually with defer.
+func (b *Buffer) markModified() {
+	b.Highlighted = false
+	b.changeSigns = nil
+	b.version++
+	if !b.ReadOnly {
+		b.modified = true
+	}
+	for _, listener := range b.listeners {
+		listener(b)
+	}
+}
+
+// AddChangeListener registers a function to be called after each change to the buffer.
+func (b *Buffer) AddChangeListener(listener func(gott.Buffer)) {
+	b.listeners = append(b.listeners, listener)
+}
+
+func (b *Buffer) GetLanguageMode() string {
+	return b.languageMode
+}
+
 func (b *Buffer) SetFileName(name string) {
 	b.fileName = name
 	if strings.HasSuffix(name, ".go") {
 		b.languageMode = "go"
+	} else if strings.HasSuffix(name, ".md") {
+		b.languageMode = "md"
+	} else if strings.HasSuffix(name, ".py") {
+		b.languageMode = "py"
+	} else if strings.HasSuffix(name, ".sh") {
+		b.languageMode = "sh"
+	} else if strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+		b.languageMode = "yaml"
 	} else {
 		b.languageMode = "txt"
 	}
@@ -69,34 +132,156 @@ func (b *Buffer) SetFileName(name string) {
 func (b *Buffer) LoadBytes(bytes []byte) []byte {
 	previous := b.GetBytes()
 	s := string(bytes)
-	lines := strings.Split(s, "\n")
+	b.lineBreak = lineBreakFor(s)
+	lines := strings.Split(s, b.lineBreak)
+	b.detectIndentation(lines)
 	b.rows = make([]*Row, 0)
 	for _, line := range lines {
 		b.rows = append(b.rows, NewRow(line))
 	}
-	b.Highlighted = false
+	b.variables = nil
+	b.encoding = "UTF-8"
+	if !utf8.Valid(bytes) {
+		b.encoding = "unknown"
+	}
+	b.markModified()
+	b.mixedEndings = b.GetLineEnding() == "mixed"
 	return previous
 }
 
+// lineBreakFor returns the string that ends lines in a text.
+// Text with carriage returns and no newlines uses carriage returns to end lines.
+func lineBreakFor(s string) string {
+	if !strings.Contains(s, "\n") && strings.Contains(s, "\r") {
+		return "\r"
+	}
+	return "\n"
+}
+
+// GetLineEnding returns the line ending style of the buffer: LF, CRLF, CR, or mixed.
+// Carriage returns are kept in the rows, so rows that end with CRLF end with '\r'.
+func (b *Buffer) GetLineEnding() string {
+	if b.lineBreak == "\r" {
+		return "CR"
+	}
+	if b.lineEnding != "" && b.lineEndingAt == b.version {
+		return b.lineEnding
+	}
+	// every row but the last one ends with a line break
+	crlf := 0
+	for _, row := range b.rows[0:clipToRange(len(b.rows)-1, 0, len(b.rows))] {
+		if text := row.GetText(); len(text) > 0 && text[len(text)-1] == '\r' {
+			crlf++
+		}
+	}
+	switch {
+	case crlf == 0:
+		b.lineEnding = "LF"
+	case crlf == len(b.rows)-1:
+		b.lineEnding = "CRLF"
+	default:
+		b.lineEnding = "mixed"
+	}
+	b.lineEndingAt = b.version
+	return b.lineEnding
+}
+
+// GetMixedLineEndings returns true if the text that was loaded into the buffer had mixed line endings.
+func (b *Buffer) GetMixedLineEndings() bool {
+	return b.mixedEndings
+}
+
+// LineEndingRows returns the text of every row with its line ending converted to CRLF or LF.
+func (b *Buffer) LineEndingRows(crlf bool) []string {
+	lines := make([]string, len(b.rows))
+	for i, row := range b.rows {
+		lines[i] = strings.TrimSuffix(row.GetString(), "\r")
+		if crlf && i < len(b.rows)-1 {
+			lines[i] += "\r"
+		}
+	}
+	return lines
+}
+
+// GetLineBreak returns the separator that is written between rows.
+func (b *Buffer) GetLineBreak() string {
+	return b.lineBreak
+}
+
+// SetLineBreak sets the separator that is written between rows.
+func (b *Buffer) SetLineBreak(lineBreak string) {
+	b.lineBreak = lineBreak
+	b.markModified()
+}
+
+// ByteOffset returns the offset in bytes of a position in the text of the buffer.
+func (b *Buffer) ByteOffset(cursor gott.Point) int {
+	offset := 0
+	for i := 0; i < cursor.Row && i < len(b.rows); i++ {
+		offset += len(b.rows[i].GetString()) + len(b.lineBreak)
+	}
+	if cursor.Row < len(b.rows) {
+		text := b.rows[cursor.Row].GetText()
+		offset += len(string(text[0:clipToRange(cursor.Col, 0, len(text))]))
+	}
+	return offset
+}
+
+// GetEncoding returns the encoding of the text that was loaded into the buffer.
+func (b *Buffer) GetEncoding() string {
+	return b.encoding
+}
+
+// SetVariable stores a value in the buffer under a name.
+func (b *Buffer) SetVariable(name string, value interface{}) {
+	if b.variables == nil {
+		b.variables = make(map[string]interface{})
+	}
+	b.variables[name] = value
+}
+
+// GetVariable returns the value stored in the buffer under a name, or nil if there is none.
+func (b *Buffer) GetVariable(name string) interface{} {
+	return b.variables[name]
+}
+
 func (b *Buffer) AppendBytes(bytes []byte) {
 	s := string(bytes)
 	lines := strings.Split(s, "\n")
 	for _, line := range lines {
 		b.rows = append(b.rows, NewRow(line))
 	}
+	b.markModified()
 }
 
 func (b *Buffer) GetBytes() []byte {
 	var s string
 	for i, row := range b.rows {
 		if i > 0 {
-			s += "\n"
+			s += b.lineBreak
 		}
 		s += string(row.GetText())
 	}
 	return []byte(s)
 }
 
+// BytesForRange returns a range of lines, each ending with a newline.
+// Lines are numbered from 1 and the range is clipped to the lines in the buffer.
+func (b *Buffer) BytesForRange(start, end int) []byte {
+	count := len(b.rows)
+	// an empty last row holds the newline that ends the file
+	if count > 0 && b.rows[count-1].Length() == 0 {
+		count--
+	}
+	start = clipToRange(start, 1, count+1)
+	end = clipToRange(end, start-1, count)
+	var s string
+	for _, row := range b.rows[start-1 : end] {
+		s += string(row.GetText()) + "\n"
+	}
+	return []byte(s)
+}
+
 func (b *Buffer) GetRowCount() int {
 	return len(b.rows)
 }
@@ -127,22 +312,66 @@ func (b *Buffer) TextFromPosition(row, col int) string {
 	}
 }
 
+// GetRowString returns the text of a row, or an empty string if the row doesn't exist.
+func (b *Buffer) GetRowString(n int) string {
+	if n >= 0 && n < len(b.rows) {
+		return string(b.rows[n].GetText())
+	}
+	return ""
+}
+
+// GetText returns the text from start up to but not including end.
+// Rows in the range are joined with newlines.
+func (b *Buffer) GetText(start, end gott.Point) string {
+	var s strings.Builder
+	for row := start.Row; row <= end.Row && row < len(b.rows); row++ {
+		if row < 0 {
+			continue
+		}
+		text := b.rows[row].GetText()
+		first := 0
+		if row == start.Row {
+			first = clipToRange(start.Col, 0, len(text))
+		}
+		last := len(text)
+		if row == end.Row {
+			last = clipToRange(end.Col, first, len(text))
+		}
+		if row > start.Row {
+			s.WriteString("\n")
+		}
+		s.WriteString(string(text[first:last]))
+	}
+	return s.String()
+}
+
 func (b *Buffer) InsertCharacter(row, col int, c rune) {
-	b.Highlighted = false
+	defer b.markModified()
 	if row < len(b.rows) {
 		b.rows[row].InsertChar(col, c)
 	}
 }
 
 func (b *Buffer) DeleteRow(row int) {
-	b.Highlighted = false
+	defer b.markModified()
 	if row < len(b.rows) {
 		b.rows = append(b.rows[0:row], b.rows[row+1:]...)
 	}
 }
 
+// ReverseRange reverses the order of the rows from start to end, inclusive.
+func (b *Buffer) ReverseRange(start, end int) {
+	if start < 0 || end >= len(b.rows) || start >= end {
+		return
+	}
+	defer b.markModified()
+	for i, j := start, end; i < j; i, j = i+1, j-1 {
+		b.rows[i], b.rows[j] = b.rows[j], b.rows[i]
+	}
+}
+
 func (b *Buffer) DeleteCharacters(row int, col int, count int, joinLines bool) string {
-	b.Highlighted = false
+	defer b.markModified()
 	deletedText := ""
 	if b.GetRowCount() == 0 {
 		return deletedText
@@ -163,17 +392,133 @@ func (b *Buffer) DeleteCharacters(row int, col int, count int, joinLines bool) s
 	return deletedText
 }
 
-func (b *Buffer) FirstPositionInRowAfterCol(row int, col int, text string) int {
+// Retab returns the text of every row with tabs replaced by spaces that reach
+// the same tab stops. If leadingOnly is true, only tabs in indentation are replaced.
+func (b *Buffer) Retab(tabWidth int, leadingOnly bool) []string {
+	lines := make([]string, len(b.rows))
+	for i, row := range b.rows {
+		var line strings.Builder
+		col := 0
+		indenting := true
+		for _, c := range row.GetText() {
+			if c != ' ' && c != '\t' {
+				indenting = false
+			}
+			if c == '\t' && (indenting || !leadingOnly) {
+				n := tabWidth - col%tabWidth
+				line.WriteString(strings.Repeat(" ", n))
+				col += n
+				continue
+			}
+			line.WriteRune(c)
+			col++
+		}
+		lines[i] = line.String()
+	}
+	return lines
+}
+
+// Entab returns the text of every row with the spaces in its indentation
+// replaced by tabs wherever they reach a tab stop.
+func (b *Buffer) Entab(tabWidth int) []string {
+	lines := make([]string, len(b.rows))
+	for i, row := range b.rows {
+		text := row.GetString()
+		indentation := text[0 : len(text)-len(strings.TrimLeft(text, " \t"))]
+		// measure the indentation, then rebuild it with as many tabs as possible
+		width := 0
+		for _, c := range indentation {
+			if c == '\t' {
+				width += tabWidth - width%tabWidth
+			} else {
+				width++
+			}
+		}
+		lines[i] = strings.Repeat("\t", width/tabWidth) + strings.Repeat(" ", width%tabWidth) + text[len(indentation):]
+	}
+	return lines
+}
+
+// ReflowRange returns the words of the rows from start to end, inclusive, rewrapped into
+// lines that are no longer than width. Every line has the indentation of the first row.
+// Words that are too long to fit are put on lines of their own.
+func (b *Buffer) ReflowRange(start, end, width int) []string {
+	var words []string
+	indentation := ""
+	for row := start; row <= end && row < len(b.rows); row++ {
+		text := b.rows[row].GetString()
+		if row == start {
+			indentation = text[0 : len(text)-len(strings.TrimLeft(text, " \t"))]
+		}
+		words = append(words, strings.Fields(text)...)
+	}
+	var lines []string
+	line := ""
+	for _, word := range words {
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line == "" {
+			line = indentation + word
+		} else {
+			line += " " + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// AlignRange returns the text of the rows from start to end, inclusive, with spaces
+// added before the first delimiter in each row so that the delimiters line up.
+// Rows without the delimiter are unchanged.
+func (b *Buffer) AlignRange(start, end int, delimiter string) []string {
+	if delimiter == "" || start < 0 || start > end || end >= len(b.rows) {
+		return nil
+	}
+	lines := make([]string, 0, end-start+1)
+	column := 0
+	for _, row := range b.rows[start : end+1] {
+		line := row.GetString()
+		lines = append(lines, line)
+		if i := strings.Index(line, delimiter); i >= 0 {
+			if n := utf8.RuneCountInString(line[0:i]); n > column {
+				column = n
+			}
+		}
+	}
+	for i, line := range lines {
+		if j := strings.Index(line, delimiter); j >= 0 {
+			padding := strings.Repeat(" ", column-utf8.RuneCountInString(line[0:j]))
+			lines[i] = line[0:j] + padding + line[j:]
+		}
+	}
+	return lines
+}
+
+// characterCount returns the number of characters from start up to end,
+// counting each line break as one character.
+func (b *Buffer) characterCount(start, end gott.Point) int {
+	count := 0
+	for row := start.Row; row < end.Row && row < b.GetRowCount(); row++ {
+		count += b.rows[row].Length() + 1
+	}
+	return count + end.Col - start.Col
+}
+
+func (b *Buffer) FirstPositionInRowAfterCol(row int, col int, s *search) int {
 	if row < b.GetRowCount() {
-		return b.rows[row].FirstPositionAfterCol(col, text)
+		return b.rows[row].FirstPositionAfterCol(col, s)
 	} else {
 		return -1
 	}
 }
 
-func (b *Buffer) LastPositionInRowBeforeCol(row int, col int, text string) int {
+func (b *Buffer) LastPositionInRowBeforeCol(row int, col int, s *search) int {
 	if row < b.GetRowCount() {
-		return b.rows[row].LastPositionBeforeCol(col, text)
+		return b.rows[row].LastPositionBeforeCol(col, s)
 	} else {
 		return -1
 	}
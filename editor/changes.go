@@ -0,0 +1,261 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// ListChanges shows the lines of the focused buffer that changed since it was last read or saved.
+// Removed lines are numbered as they were saved and added lines as they are now.
+// The listing is loaded into the output window and the number of changed lines is returned.
+func (e *Editor) ListChanges() (int, error) {
+	buffer := e.focusedWindow.(*Window).buffer
+	if buffer.savedBytes == nil {
+		return 0, errors.New("No saved version")
+	}
+	var changes []string
+	for _, edit := range diffLines(buffer.savedBytes, buffer.GetBytes()) {
+		if edit.removed {
+			changes = append(changes, fmt.Sprintf("-%4d %s", edit.oldRow+1, edit.text))
+		} else {
+			changes = append(changes, fmt.Sprintf("+%4d %s", edit.newRow+1, edit.text))
+		}
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+	e.SelectWindow(0)
+	e.focusedWindow.GetBuffer().LoadBytes([]byte(strings.Join(changes, "\n")))
+	return len(changes), nil
+}
+
+// setSavedBytes records the contents of the buffer when it is read or saved.
+func (b *Buffer) setSavedBytes(bytes []byte) {
+	if bytes == nil {
+		bytes = []byte{}
+	}
+	b.savedBytes = bytes
+	b.changeSigns = nil
+}
+
+// getChangeSigns returns the change signs for the rows of the buffer, computing them if they are stale.
+func (b *Buffer) getChangeSigns() []rune {
+	if b.changeSigns == nil && b.savedBytes != nil {
+		b.changeSigns = ChangeSigns(b.savedBytes, b.GetBytes())
+	}
+	return b.changeSigns
+}
+
+// UndoToSave undoes edits until the focused buffer matches its contents when it was last read or saved.
+// Only the buffer's own history is used, and if it can't reach the saved version,
+// the undone edits are redone so that the buffer is left as it was.
+func (e *Editor) UndoToSave() error {
+	buffer := e.focusedWindow.(*Window).buffer
+	if buffer.savedBytes == nil {
+		return errors.New("No saved version")
+	}
+	undone := 0
+	for !bytes.Equal(buffer.GetBytes(), buffer.savedBytes) {
+		if len(buffer.undo) == 0 {
+			for ; undone > 0; undone-- {
+				e.PerformRedo()
+			}
+			return errors.New("Not enough undo history to reach the saved version")
+		}
+		e.PerformUndo()
+		undone++
+	}
+	buffer.SetModified(false)
+	return nil
+}
+
+// A lineEdit is a line that was removed from the old text or added to the new one.
+// Its rows are its position in the old text and the corresponding position in the new one.
+type lineEdit struct {
+	removed bool
+	oldRow  int
+	newRow  int
+	text    string
+}
+
+// splitLines splits a text into lines the way that buffers load it.
+func splitLines(b []byte) []string {
+	s := string(b)
+	return strings.Split(s, lineBreakFor(s))
+}
+
+// maxDiffEdits limits the work done to compare two texts.
+// Texts that differ by more edits are treated as one changed region.
+const maxDiffEdits = 1000
+
+// diffLines compares two texts and returns the lines that were removed and added, in order.
+func diffLines(oldBytes, newBytes []byte) []lineEdit {
+	old := splitLines(oldBytes)
+	new := splitLines(newBytes)
+	// lines at the beginning and end that didn't change are skipped
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	a := old[prefix : len(old)-suffix]
+	b := new[prefix : len(new)-suffix]
+	// the lines between matches were removed from a and added to b,
+	// and the end of both texts is treated as a final match
+	var edits []lineEdit
+	i, j := 0, 0
+	for _, match := range append(matchingLines(a, b), [2]int{len(a), len(b)}) {
+		for ; i < match[0]; i++ {
+			edits = append(edits, lineEdit{removed: true, oldRow: prefix + i, newRow: prefix + j, text: a[i]})
+		}
+		for ; j < match[1]; j++ {
+			edits = append(edits, lineEdit{oldRow: prefix + i, newRow: prefix + j, text: b[j]})
+		}
+		i, j = i+1, j+1
+	}
+	return edits
+}
+
+// matchingLines returns the positions of the lines that a and b have in common, in order.
+// It uses Myers' algorithm, which takes time proportional to the length of the texts times
+// the number of edits between them. If there are more than maxDiffEdits, no lines are matched.
+func matchingLines(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	// v[offset+k] is the furthest position in a reached on diagonal k, where k is the
+	// position in a minus the position in b, and trace holds v after each number of edits
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return nil
+		}
+		done := false
+		for k := -d; k <= d && !done; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // a line added to b
+			} else {
+				x = v[offset+k-1] + 1 // a line removed from a
+			}
+			for y := x - k; x < n && y < m && a[x] == b[y]; y++ {
+				x++
+			}
+			v[offset+k] = x
+			done = x >= n && x-k >= m
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		if done {
+			break
+		}
+	}
+	// follow the edits back from the end, collecting the matches that follow each one
+	var matches [][2]int
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := x - y
+		// the matches start after the edit, which came from the previous diagonal
+		previousX, previousY, startX := 0, 0, 0
+		if d > 0 {
+			previous := trace[d-1]
+			if k == -d || (k != d && previous[k-1+d-1] < previous[k+1+d-1]) {
+				previousX = previous[k+1+d-1]
+				previousY = previousX - (k + 1)
+				startX = previousX
+			} else {
+				previousX = previous[k-1+d-1]
+				previousY = previousX - (k - 1)
+				startX = previousX + 1
+			}
+		}
+		for x > startX {
+			x, y = x-1, y-1
+			matches = append(matches, [2]int{x, y})
+		}
+		x, y = previousX, previousY
+	}
+	for l, r := 0, len(matches)-1; l < r; l, r = l+1, r-1 {
+		matches[l], matches[r] = matches[r], matches[l]
+	}
+	return matches
+}
+
+// ChangeSigns compares saved and current text and returns a sign for each row of the current text.
+// Rows are marked '+' if they were added, '~' if they replaced saved rows, and '-' if saved rows
+// were removed before them. Unchanged rows are marked with zero.
+func ChangeSigns(saved, current []byte) []rune {
+	signs := make([]rune, len(splitLines(current)))
+	for _, h := range diffHunks(diffLines(saved, current)) {
+		for k, edit := range h.added {
+			if k < len(h.removed) {
+				signs[edit.newRow] = '~'
+			} else {
+				signs[edit.newRow] = '+'
+			}
+		}
+		// lines removed from the end are marked on the last row
+		row := clipToRange(h.newRow+len(h.added), 0, len(signs)-1)
+		if len(h.removed) > len(h.added) && signs[row] == 0 {
+			signs[row] = '-'
+		}
+	}
+	return signs
+}
+
+// A diffHunk is a run of removed lines and the added lines that replace them.
+type diffHunk struct {
+	newRow  int // the row in the new text where the hunk starts
+	removed []lineEdit
+	added   []lineEdit
+}
+
+// diffHunks groups edits into hunks of adjacent lines.
+func diffHunks(edits []lineEdit) []diffHunk {
+	var hunks []diffHunk
+	for k := 0; k < len(edits); {
+		h := diffHunk{newRow: edits[k].newRow}
+		for ; k < len(edits) && edits[k].removed && edits[k].newRow == h.newRow; k++ {
+			h.removed = append(h.removed, edits[k])
+		}
+		for ; k < len(edits) && !edits[k].removed && edits[k].newRow == h.newRow+len(h.added); k++ {
+			h.added = append(h.added, edits[k])
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// signColor returns the theme color used to draw a change sign, or false for unchanged rows.
+func signColor(theme *gott.Theme, sign rune) (gott.Color, bool) {
+	switch sign {
+	case '+':
+		return theme.String, true
+	case '~':
+		return theme.Keyword, true
+	case '-':
+		return theme.Error, true
+	}
+	return 0, false
+}
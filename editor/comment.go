@@ -0,0 +1,77 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"strings"
+)
+
+// commentPrefixes are the line comment prefixes of the language modes.
+var commentPrefixes = map[string]string{
+	"go":   "//",
+	"py":   "#",
+	"sh":   "#",
+	"yaml": "#",
+}
+
+// GetCommentPrefix returns the line comment prefix for the buffer's language, or "" if it has none.
+func (b *Buffer) GetCommentPrefix() string {
+	return commentPrefixes[b.languageMode]
+}
+
+// ToggleCommentRange returns the text of the rows from start through end with
+// line comments added, or removed if every nonblank row is already commented.
+// Comments are added at the smallest indentation of the rows so that they line up.
+func (b *Buffer) ToggleCommentRange(start, end int) []string {
+	prefix := b.GetCommentPrefix()
+	if prefix == "" || start < 0 || start > end || end >= len(b.rows) {
+		return nil
+	}
+	lines := make([]string, 0, end-start+1)
+	commented := true
+	indentation := -1
+	for _, row := range b.rows[start : end+1] {
+		line := row.GetString()
+		lines = append(lines, line)
+		text := strings.TrimLeft(line, " \t")
+		if text == "" {
+			continue
+		}
+		if !strings.HasPrefix(text, prefix) {
+			commented = false
+		}
+		if n := len(line) - len(text); indentation < 0 || n < indentation {
+			indentation = n
+		}
+	}
+	if indentation < 0 {
+		// there are only blank rows
+		return nil
+	}
+	for i, line := range lines {
+		text := strings.TrimLeft(line, " \t")
+		if text == "" {
+			continue
+		}
+		if commented {
+			// remove the prefix and the space that follows it
+			text = strings.TrimPrefix(strings.TrimPrefix(text, prefix), " ")
+			lines[i] = line[0:len(line)-len(strings.TrimLeft(line, " \t"))] + text
+		} else {
+			lines[i] = line[0:indentation] + prefix + " " + line[indentation:]
+		}
+	}
+	return lines
+}
@@ -0,0 +1,162 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// Diagnostics are problems found in a buffer, like syntax errors reported by gofmt.
+// Buffers with diagnostics show a gutter that marks the rows that have them.
+
+// Lines of gofmt error output look like "<standard input>:3:5: expected ';', found 'IDENT' x".
+var gofmtErrorPattern = regexp.MustCompile(`:(\d+):(\d+): (.*)$`)
+
+// ParseGofmtErrors converts gofmt error output to diagnostics.
+// Lines that don't report a position are ignored.
+func ParseGofmtErrors(output string) []gott.Diagnostic {
+	var diagnostics []gott.Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		m := gofmtErrorPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		row, _ := strconv.Atoi(m[1])
+		col, _ := strconv.Atoi(m[2])
+		diagnostics = append(diagnostics, gott.Diagnostic{Row: row - 1, Col: col - 1, Message: m[3]})
+	}
+	return diagnostics
+}
+
+func (b *Buffer) SetDiagnostics(diagnostics []gott.Diagnostic) {
+	b.diagnostics = diagnostics
+}
+
+func (b *Buffer) GetDiagnostics() []gott.Diagnostic {
+	return b.diagnostics
+}
+
+// GetDiagnosticForRow returns the first diagnostic for a row, or false if the row has none.
+func (b *Buffer) GetDiagnosticForRow(row int) (gott.Diagnostic, bool) {
+	for _, d := range b.diagnostics {
+		if d.Row == row {
+			return d, true
+		}
+	}
+	return gott.Diagnostic{}, false
+}
+
+// sortedDiagnostics returns the buffer's diagnostics in order of position.
+func (b *Buffer) sortedDiagnostics() []gott.Diagnostic {
+	sorted := append([]gott.Diagnostic{}, b.diagnostics...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Row < sorted[j].Row ||
+			(sorted[i].Row == sorted[j].Row && sorted[i].Col < sorted[j].Col)
+	})
+	return sorted
+}
+
+// MoveToNextDiagnostic moves the cursor to the next diagnostic, wrapping around to the first.
+func (w *Window) MoveToNextDiagnostic(multiplier int) {
+	diagnostics := w.buffer.sortedDiagnostics()
+	if len(diagnostics) == 0 {
+		return
+	}
+	for m := 0; m < multiplier; m++ {
+		next := diagnostics[0]
+		for _, d := range diagnostics {
+			if d.Row > w.cursor.Row || (d.Row == w.cursor.Row && d.Col > w.cursor.Col) {
+				next = d
+				break
+			}
+		}
+		w.moveToDiagnostic(next)
+	}
+}
+
+// MoveToPreviousDiagnostic moves the cursor to the previous diagnostic, wrapping around to the last.
+func (w *Window) MoveToPreviousDiagnostic(multiplier int) {
+	diagnostics := w.buffer.sortedDiagnostics()
+	if len(diagnostics) == 0 {
+		return
+	}
+	for m := 0; m < multiplier; m++ {
+		previous := diagnostics[len(diagnostics)-1]
+		for i := len(diagnostics) - 1; i >= 0; i-- {
+			d := diagnostics[i]
+			if d.Row < w.cursor.Row || (d.Row == w.cursor.Row && d.Col < w.cursor.Col) {
+				previous = d
+				break
+			}
+		}
+		w.moveToDiagnostic(previous)
+	}
+}
+
+func (w *Window) moveToDiagnostic(d gott.Diagnostic) {
+	w.cursor = gott.Point{Row: clipToRange(d.Row, 0, w.buffer.GetRowCount()-1), Col: d.Col}
+	w.KeepCursorInRow()
+}
+
+// The gutter has a marker column and a space before the text.
+const gutterWidth = 2
+
+// gutterWidth returns the width of the gutter, which is only shown when there are diagnostics
+// or when change signs are on for a buffer that was read from a file.
+func (w *Window) gutterWidth() int {
+	if w.size.Cols <= 2*gutterWidth {
+		return 0
+	}
+	if len(w.buffer.diagnostics) > 0 || (w.editor.GetChangeSigns() && w.buffer.savedBytes != nil) {
+		return gutterWidth
+	}
+	return 0
+}
+
+// textLeft returns the screen column where text starts.
+func (w *Window) textLeft() int {
+	return w.origin.Col + w.gutterWidth()
+}
+
+// renderGutter marks the visible rows that changed since the last save and the rows that have diagnostics.
+// Diagnostics are drawn over change signs.
+func (w *Window) renderGutter(setCell func(j int, i int, c rune, color gott.Color)) {
+	if w.gutterWidth() == 0 {
+		return
+	}
+	theme := w.editor.GetTheme()
+	if w.editor.GetChangeSigns() {
+		signs := w.buffer.getChangeSigns()
+		for i := 0; i < w.size.Rows-1 && i+w.offset.Rows < len(signs); i++ {
+			color, ok := signColor(theme, signs[i+w.offset.Rows])
+			if !ok {
+				continue
+			}
+			setCell(w.origin.Col, i+w.origin.Row, signs[i+w.offset.Rows], color)
+		}
+	}
+	color := theme.Error
+	for _, d := range w.buffer.diagnostics {
+		row := d.Row - w.offset.Rows
+		if row >= 0 && row < w.size.Rows-1 {
+			setCell(w.origin.Col, row+w.origin.Row, '>', color)
+		}
+	}
+}
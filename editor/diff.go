@@ -0,0 +1,113 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"errors"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// A diff view compares two buffers and colors the rows that differ in every window that shows them.
+type diffView struct {
+	buffers  []*Buffer // the buffers being compared, in the order they were marked
+	versions [2]int    // versions of the buffers when the signs were computed
+	signs    [2][]rune // signs for the rows of each buffer, or nil if they haven't been computed
+}
+
+// DiffRows compares two texts and returns a sign for each row of each text.
+// Rows of the first text are marked '-' if they were removed and rows of the
+// second text are marked '+' if they were added. Rows that replaced each other
+// are marked '~' in both. Rows that are the same in both texts are marked with zero.
+func DiffRows(a, b []byte) ([]rune, []rune) {
+	aSigns := make([]rune, len(splitLines(a)))
+	bSigns := make([]rune, len(splitLines(b)))
+	for _, h := range diffHunks(diffLines(a, b)) {
+		for k, edit := range h.removed {
+			if k < len(h.added) {
+				aSigns[edit.oldRow] = '~'
+			} else {
+				aSigns[edit.oldRow] = '-'
+			}
+		}
+		for k, edit := range h.added {
+			if k < len(h.removed) {
+				bSigns[edit.newRow] = '~'
+			} else {
+				bSigns[edit.newRow] = '+'
+			}
+		}
+	}
+	return aSigns, bSigns
+}
+
+// DiffThis adds the buffer of the focused window to the diff view.
+// The two most recently added buffers are compared.
+func (e *Editor) DiffThis() error {
+	b := e.focusedWindow.(*Window).buffer
+	if b == nil {
+		return errors.New("No buffer to compare")
+	}
+	buffers := []*Buffer{}
+	for _, d := range e.diff.buffers {
+		if d != b {
+			buffers = append(buffers, d)
+		}
+	}
+	buffers = append(buffers, b)
+	if len(buffers) > 2 {
+		buffers = buffers[len(buffers)-2:]
+	}
+	e.diff = diffView{buffers: buffers}
+	return nil
+}
+
+// DiffOff ends the diff view.
+func (e *Editor) DiffOff() {
+	e.diff = diffView{}
+}
+
+// diffSigns returns the diff signs for the rows of a buffer, or nil if the buffer isn't being compared.
+func (e *Editor) diffSigns(b *Buffer) []rune {
+	d := &e.diff
+	if len(d.buffers) != 2 || (b != d.buffers[0] && b != d.buffers[1]) {
+		return nil
+	}
+	first, second := d.buffers[0], d.buffers[1]
+	if d.signs[0] == nil || first.version != d.versions[0] || second.version != d.versions[1] {
+		d.signs[0], d.signs[1] = DiffRows(first.GetBytes(), second.GetBytes())
+		d.versions = [2]int{first.version, second.version}
+	}
+	if b == first {
+		return d.signs[0]
+	}
+	return d.signs[1]
+}
+
+// diffColors returns the colors for a row, with the whole row in its sign's color if it differs.
+func (w *Window) diffColors(signs []rune, row int, colors []gott.Color) []gott.Color {
+	if row >= len(signs) {
+		return colors
+	}
+	color, ok := signColor(w.editor.GetTheme(), signs[row])
+	if !ok {
+		return colors
+	}
+	colors = make([]gott.Color, len(colors))
+	for j := range colors {
+		colors[j] = color
+	}
+	return colors
+}
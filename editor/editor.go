@@ -20,8 +20,11 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 	"unicode"
 
 	gott "github.com/timburks/gott/types"
@@ -37,13 +40,59 @@ type Editor struct {
 	documentWindows map[int]gott.Window  // all windows that contain documents; some may be offscreen
 	pasteText       string               // used to cut/copy and paste
 	pasteMode       int                  // how to paste the string on the pasteboard
+	killRing        []string             // recently deleted or copied text, oldest first
+	killIndex       int                  // position in the kill ring of the text to yank
+	recentFiles     []string             // recently opened files, most recent first
+	recentFilesPath string               // where the recent files are saved, if anywhere
+	cursorPositions []cursorPosition     // last cursor positions in files, most recent first
+	positionsPath   string               // where the cursor positions are saved, if anywhere
 	previous        gott.Operation       // last operation performed, available to repeat
-	undo            []gott.Operation     // stack of operations to undo
 	insert          gott.InsertOperation // when in insert mode, the current insert operation
-}
+	tabWidth        int                  // distance between tab stops
+	textWidth       int                  // maximum length of reflowed lines
+	autoWrap        bool                 // true to break lines that are typed past the text width
+	literalTabs     bool                 // true to insert tab characters instead of spaces
+	detectIndent    bool                 // true to use the indentation found in each buffer
+	statusLine      string               // format of window info bars
+	theme           *gott.Theme          // colors for highlighting
+	dimInactive     bool                 // true to dim windows that don't have focus
+	minimap         bool                 // true to draw an overview of each buffer at the right edge of its window
+	spell           bool                 // true to mark misspelled words in text buffers
+	spellChecker    *SpellChecker        // dictionary used to check spelling
+	changeSigns     bool                 // true to mark rows that changed since the last save
+	diff            diffView             // buffers being compared
+	ignoreCase      bool                 // true to ignore case in searches
+	searchCenter    bool                 // true to center the rows that searches move to
+	virtualEdit     bool                 // true to let the cursor move just past the end of a row
+	byteOffset      bool                 // true to show the cursor's byte offset on info bars
+	highlight       *regexp.Regexp       // matches are highlighted in every window
+	smartCase       bool                 // true to match case when ignoring case and searching for uppercase letters
+	escTimeout      int                  // milliseconds to wait for a key after Esc; zero to never wait
+	idleTimeout     int                  // milliseconds without input before idle hooks run; zero to never run them
+	swapInterval    int                  // milliseconds without input before swap files are written; zero to never write them
+	onOpen          func(path string)    // called after a file is read into a buffer
+	onSave          func(path string)    // called after a buffer is written to a file
+}
+
+// Version identifies this release of gott.
+const Version = "0.1"
+
+// DefaultTabWidth is the initial distance between tab stops.
+const DefaultTabWidth = 8
+
+// DefaultTextWidth is the initial maximum length of reflowed lines.
+const DefaultTextWidth = 80
+
+// DefaultSwapInterval is the initial number of milliseconds without input before swap files are written.
+const DefaultSwapInterval = 4000
 
 func NewEditor() *Editor {
 	e := &Editor{}
+	e.tabWidth = DefaultTabWidth
+	e.textWidth = DefaultTextWidth
+	e.swapInterval = DefaultSwapInterval
+	e.statusLine = DefaultStatusLine
+	e.theme, _ = findTheme(DefaultTheme)
 	e.documentWindows = make(map[int]gott.Window)
 	w := e.CreateWindow()
 	w.GetBuffer().SetNameAndReadOnly("*output*", true)
@@ -58,8 +107,39 @@ func (e *Editor) CreateWindow() gott.Window {
 	return e.focusedWindow
 }
 
+// CreateScratchWindow replaces the focused window with an editable window that isn't tied to a file.
+func (e *Editor) CreateScratchWindow() {
+	focusedWindow := e.focusedWindow
+	w := e.CreateWindow()
+	w.GetBuffer().SetNameAndReadOnly("*scratch*", false)
+	e.focusedWindow = focusedWindow
+	e.SelectWindow(w.GetNumber())
+}
+
+// CreateSnapshotWindow replaces the focused window with a read-only copy of its buffer.
+func (e *Editor) CreateSnapshotWindow() {
+	focusedWindow := e.focusedWindow
+	source := focusedWindow.(*Window).buffer
+	w := e.CreateWindow().(*Window)
+	w.buffer.SetNameAndReadOnly(source.GetName()+" (snapshot)", true)
+	w.buffer.languageMode = source.languageMode
+	w.buffer.LoadBytes(source.GetBytes())
+	e.focusedWindow = focusedWindow
+	e.SelectWindow(w.GetNumber())
+}
+
+// ShowText splits the focused window and shows read-only text in the upper window.
+func (e *Editor) ShowText(name string, text string) {
+	e.SplitWindowHorizontally()
+	w := e.focusedWindow.(*Window)
+	w.buffer = NewBuffer()
+	w.buffer.SetNameAndReadOnly(name, true)
+	w.buffer.LoadBytes([]byte(text))
+	w.cursor = gott.Point{}
+	w.offset = gott.Size{}
+}
+
 func (e *Editor) ListWindows() {
-	e.SelectWindow(0)
 	var s string
 
 	indices := make([]int, 0)
@@ -74,7 +154,7 @@ func (e *Editor) ListWindows() {
 			if s != "" {
 				s += "\n"
 			}
-			s += fmt.Sprintf(" [%d] %s", i, window.GetName())
+			s += fmt.Sprintf(" [%d] %-3s %s", i, e.windowFlags(window), window.GetName())
 		} else {
 			if s != "" {
 				s += "\n"
@@ -83,9 +163,36 @@ func (e *Editor) ListWindows() {
 		}
 	}
 	listing := []byte(s)
+	e.SelectWindow(0)
 	e.focusedWindow.GetBuffer().LoadBytes(listing)
 }
 
+// HasModifiedBuffers returns true if any buffer has unsaved changes.
+func (e *Editor) HasModifiedBuffers() bool {
+	for _, w := range e.documentWindows {
+		if w.GetBuffer().GetModified() {
+			return true
+		}
+	}
+	return false
+}
+
+// Window listings are annotated with "a" for the active window,
+// "h" for hidden (offscreen) windows, and "+" for modified buffers.
+func (e *Editor) windowFlags(w gott.Window) string {
+	var flags string
+	if w == e.focusedWindow {
+		flags += "a"
+	}
+	if e.rootWindow.FindWindow(w.GetNumber()) != w {
+		flags += "h"
+	}
+	if w.GetBuffer().GetModified() {
+		flags += "+"
+	}
+	return flags
+}
+
 func (e *Editor) SelectWindow(number int) error {
 	// first look for an onscreen window
 	w := e.rootWindow.FindWindow(number)
@@ -144,6 +251,36 @@ func (e *Editor) SelectWindowPrevious() error {
 	return nil
 }
 
+// SelectWindowAtPosition gives focus to the window containing a screen position.
+func (e *Editor) SelectWindowAtPosition(p gott.Point) error {
+	w := e.rootWindow.FindWindowAtPosition(p)
+	if w == nil {
+		return errors.New(fmt.Sprintf("No window exists at position %d,%d", p.Row, p.Col))
+	}
+	e.focusedWindow = w
+	return nil
+}
+
+// SetFileHandlers sets functions to call after files are read into buffers and after buffers,
+// or ranges of their lines, are written or appended to files.
+// Every read and write goes through them, no matter what asked for it.
+func (e *Editor) SetFileHandlers(onOpen, onSave func(path string)) {
+	e.onOpen = onOpen
+	e.onSave = onSave
+}
+
+func (e *Editor) fileOpened(path string) {
+	if e.onOpen != nil {
+		e.onOpen(path)
+	}
+}
+
+func (e *Editor) fileSaved(path string) {
+	if e.onSave != nil {
+		e.onSave(path)
+	}
+}
+
 func (e *Editor) ReadFile(path string) error {
 	// create a new buffer
 	window := e.CreateWindow()
@@ -153,9 +290,97 @@ func (e *Editor) ReadFile(path string) error {
 	if err != nil {
 		return err
 	}
-	window.GetBuffer().LoadBytes(b)
+	window.(*Window).buffer.loadFileBytes(b)
+	window.(*Window).buffer.checkSwap()
+	e.addRecentFile(path)
+	e.restoreCursorPosition(window.(*Window))
 
 	e.rootWindow = window
+	e.fileOpened(path)
+	return nil
+}
+
+// ReloadActiveBuffer replaces the contents of the focused buffer with its file, discarding changes.
+// Operations can't be undone across a reload, so the undo stack is cleared.
+func (e *Editor) ReloadActiveBuffer() error {
+	window := e.focusedWindow.(*Window)
+	path := window.buffer.GetFileName()
+	if path == "" {
+		return errors.New("No file name")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	window.buffer.loadFileBytes(b)
+	window.buffer.RemoveSwap()
+	e.addRecentFile(path)
+	window.cursor = gott.Point{}
+	window.offset = gott.Size{}
+	window.buffer.undo = nil
+	window.buffer.redo = nil
+	e.fileOpened(path)
+	return nil
+}
+
+// isShownElsewhere returns true if a window's buffer is also shown in another document window.
+func (e *Editor) isShownElsewhere(window *Window) bool {
+	for _, w := range e.documentWindows {
+		if other := w.(*Window); other != window && other.buffer == window.buffer {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFile returns true if two paths name the same file,
+// comparing their absolute forms so that relative names match too.
+func sameFile(a, b string) bool {
+	if absolute, err := filepath.Abs(a); err == nil {
+		a = absolute
+	}
+	if absolute, err := filepath.Abs(b); err == nil {
+		b = absolute
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// ReadFileIntoActiveWindow displays a file in the focused window.
+// If the file is already open, its buffer is shared instead of being read again.
+// The window is unchanged if the file can't be read
+// or if it would hide unsaved edits that aren't shown in any other window.
+func (e *Editor) ReadFileIntoActiveWindow(path string) error {
+	window := e.focusedWindow.(*Window)
+	var buffer *Buffer
+	for _, w := range e.documentWindows {
+		if b := w.(*Window).buffer; b != nil && b.GetFileName() != "" && sameFile(b.GetFileName(), path) {
+			buffer = b
+			break
+		}
+	}
+	if buffer == nil {
+		// read the specified file into a new buffer
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		buffer = NewBuffer()
+		buffer.SetFileName(path)
+		buffer.loadFileBytes(b)
+		buffer.checkSwap()
+	}
+	if buffer != window.buffer && window.buffer.GetModified() && !e.isShownElsewhere(window) {
+		return errors.New("No write since last change")
+	}
+	e.rememberCursorPosition(window)
+	e.saveCursorPositions()
+	window.buffer = buffer
+	window.cursor = gott.Point{}
+	window.offset = gott.Size{}
+	window.selecting = false
+	e.addRecentFile(path)
+	e.restoreCursorPosition(window)
+	e.fileOpened(path)
 	return nil
 }
 
@@ -171,7 +396,21 @@ func (e *Editor) AppendBytes(b []byte) {
 	e.GetActiveWindow().GetBuffer().AppendBytes(b)
 }
 
+// checkWrite returns an error if the focused buffer can't be written to a path.
+func (e *Editor) checkWrite(path string) error {
+	if path == "" {
+		return errors.New("No file name")
+	}
+	if e.focusedWindow.GetBuffer().GetBinary() {
+		return errors.New("Hex dumps of binary files can't be written")
+	}
+	return nil
+}
+
 func (e *Editor) WriteFile(path string) error {
+	if err := e.checkWrite(path); err != nil {
+		return err
+	}
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -188,9 +427,61 @@ func (e *Editor) WriteFile(path string) error {
 	} else {
 		f.Write(b)
 	}
+	// writing a copy to another file doesn't save the buffer
+	buffer := e.focusedWindow.(*Window).buffer
+	if path == buffer.GetFileName() {
+		buffer.SetModified(false)
+		buffer.setSavedBytes(b)
+		buffer.RemoveSwap()
+		e.rememberCursorPosition(e.focusedWindow.(*Window))
+		e.saveCursorPositions()
+	}
+	e.fileSaved(path)
+	return nil
+}
+
+// AppendFile writes the contents of the focused buffer to the end of a file.
+func (e *Editor) AppendFile(path string) error {
+	if err := e.checkWrite(path); err != nil {
+		return err
+	}
+	if err := appendToFile(path, e.Bytes()); err != nil {
+		return err
+	}
+	e.fileSaved(path)
+	return nil
+}
+
+// WriteLines writes a range of lines in the focused buffer to a file.
+// Lines are numbered from 1 and the range includes both ends.
+func (e *Editor) WriteLines(path string, start, end int, appending bool) error {
+	if err := e.checkWrite(path); err != nil {
+		return err
+	}
+	b := e.focusedWindow.GetBuffer().BytesForRange(start, end)
+	var err error
+	if appending {
+		err = appendToFile(path, b)
+	} else {
+		err = ioutil.WriteFile(path, b, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	e.fileSaved(path)
 	return nil
 }
 
+func appendToFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(b)
+	return err
+}
+
 func (e *Editor) GetFileName() string {
 	return e.GetActiveWindow().GetBuffer().GetFileName()
 }
@@ -205,29 +496,81 @@ func (e *Editor) Perform(op gott.Operation, multiplier int) {
 	// save the operation for repeats
 	e.previous = op
 	// save the inverse of the operation for undo
+	e.recordChange(inverse)
+}
+
+// A change is an operation in the undo or redo history
+// along with the time of the edit that it undoes or redoes.
+type change struct {
+	operation gott.Operation
+	time      time.Time
+}
+
+// recordChange saves the inverse of a new edit for undo.
+// New edits can't be combined with undone ones, so the redo history is cleared.
+// Each buffer has its own history, so edits are only undone in the buffer that they were made in.
+func (e *Editor) recordChange(inverse gott.Operation) {
 	if inverse != nil {
-		e.undo = append(e.undo, inverse)
+		buffer := e.focusedWindow.(*Window).buffer
+		buffer.undo = append(buffer.undo, change{operation: inverse, time: time.Now()})
+		buffer.redo = nil
 	}
 }
 
-func (e *Editor) Repeat() {
+// Repeat performs the previous operation again.
+// A nonzero multiplier replaces the one that the operation was performed with.
+func (e *Editor) Repeat(multiplier int) {
 	if e.previous != nil {
-		inverse := e.previous.Perform(e, 0)
-		if inverse != nil {
-			e.undo = append(e.undo, inverse)
+		if multiplier > 0 {
+			e.previous.SetMultiplier(multiplier)
 		}
+		e.recordChange(e.previous.Perform(e, 0))
 	}
 }
 
 func (e *Editor) PerformUndo() {
-	if len(e.undo) > 0 {
-		last := len(e.undo) - 1
-		undo := e.undo[last]
-		e.undo = e.undo[0:last]
-		undo.Perform(e, 0)
+	buffer := e.focusedWindow.(*Window).buffer
+	if len(buffer.undo) > 0 {
+		last := len(buffer.undo) - 1
+		undo := buffer.undo[last]
+		buffer.undo = buffer.undo[0:last]
+		if inverse := undo.operation.Perform(e, 0); inverse != nil {
+			buffer.redo = append(buffer.redo, change{operation: inverse, time: undo.time})
+		}
+	}
+}
+
+// PerformRedo performs the most recently undone operation again.
+func (e *Editor) PerformRedo() {
+	buffer := e.focusedWindow.(*Window).buffer
+	if len(buffer.redo) > 0 {
+		last := len(buffer.redo) - 1
+		redo := buffer.redo[last]
+		buffer.redo = buffer.redo[0:last]
+		if inverse := redo.operation.Perform(e, 0); inverse != nil {
+			buffer.undo = append(buffer.undo, change{operation: inverse, time: redo.time})
+		}
 	}
 }
 
+// GetUndoTime returns the time of the edit that would be undone next, or false if there is none.
+func (e *Editor) GetUndoTime() (time.Time, bool) {
+	buffer := e.focusedWindow.(*Window).buffer
+	if len(buffer.undo) == 0 {
+		return time.Time{}, false
+	}
+	return buffer.undo[len(buffer.undo)-1].time, true
+}
+
+// GetRedoTime returns the time of the edit that would be redone next, or false if there is none.
+func (e *Editor) GetRedoTime() (time.Time, bool) {
+	buffer := e.focusedWindow.(*Window).buffer
+	if len(buffer.redo) == 0 {
+		return time.Time{}, false
+	}
+	return buffer.redo[len(buffer.redo)-1].time, true
+}
+
 func (e *Editor) PerformSearchForward(text string) {
 	e.focusedWindow.PerformSearchForward(text)
 }
@@ -236,10 +579,26 @@ func (e *Editor) PerformSearchBackward(text string) {
 	e.focusedWindow.PerformSearchBackward(text)
 }
 
+func (e *Editor) PerformWholeWordSearchForward(text string) {
+	e.focusedWindow.PerformWholeWordSearchForward(text)
+}
+
+func (e *Editor) PerformWholeWordSearchBackward(text string) {
+	e.focusedWindow.PerformWholeWordSearchBackward(text)
+}
+
+func (e *Editor) GetWordAtCursor() string {
+	return e.focusedWindow.GetWordAtCursor()
+}
+
 func (e *Editor) MoveCursor(direction int, multiplier int) {
 	e.focusedWindow.MoveCursor(direction, multiplier)
 }
 
+func (e *Editor) MoveCursorDisplayLine(direction int, multiplier int) {
+	e.focusedWindow.MoveCursorDisplayLine(direction, multiplier)
+}
+
 func (e *Editor) MoveCursorForward() int {
 	return e.focusedWindow.MoveCursorForward()
 }
@@ -249,7 +608,7 @@ func (e *Editor) MoveCursorBackward() int {
 }
 
 func isSpace(c rune) bool {
-	return c == ' ' || c == rune(0)
+	return c == ' ' || c == '\t' || c == rune(0)
 }
 
 func isAlphaNumeric(c rune) bool {
@@ -257,7 +616,7 @@ func isAlphaNumeric(c rune) bool {
 }
 
 func isNonAlphaNumeric(c rune) bool {
-	return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != ' ' && c != rune(0)
+	return !unicode.IsLetter(c) && !unicode.IsDigit(c) && !isSpace(c)
 }
 
 func (e *Editor) MoveCursorToNextWord(multiplier int) {
@@ -284,8 +643,56 @@ func (e *Editor) MoveCursorToPreviousWord(multiplier int) {
 	e.focusedWindow.MoveCursorToPreviousWord(multiplier)
 }
 
+func (e *Editor) MoveToNextParagraph(multiplier int) {
+	e.focusedWindow.MoveToNextParagraph(multiplier)
+}
+
+func (e *Editor) MoveToPreviousParagraph(multiplier int) {
+	e.focusedWindow.MoveToPreviousParagraph(multiplier)
+}
+
+func (e *Editor) MoveToNextSentence(multiplier int) {
+	e.focusedWindow.MoveToNextSentence(multiplier)
+}
+
+func (e *Editor) MoveToPreviousSentence(multiplier int) {
+	e.focusedWindow.MoveToPreviousSentence(multiplier)
+}
+
+func (e *Editor) ParagraphObjectRange(around bool) (start, end gott.Point) {
+	return e.focusedWindow.ParagraphObjectRange(around)
+}
+
+func (e *Editor) WordObjectRange(around bool) (start, end gott.Point) {
+	return e.focusedWindow.WordObjectRange(around)
+}
+
+func (e *Editor) DelimiterRange(open, close rune) (start, end gott.Point, ok bool) {
+	return e.focusedWindow.DelimiterRange(open, close)
+}
+
+func (e *Editor) MoveCursorToMatchingBracket() {
+	e.focusedWindow.MoveCursorToMatchingBracket()
+}
+
+func (e *Editor) MoveToNextMisspelling(multiplier int) {
+	e.focusedWindow.MoveToNextMisspelling(multiplier)
+}
+
+func (e *Editor) MoveToPreviousMisspelling(multiplier int) {
+	e.focusedWindow.MoveToPreviousMisspelling(multiplier)
+}
+
+func (e *Editor) MoveToNextDiagnostic(multiplier int) {
+	e.focusedWindow.MoveToNextDiagnostic(multiplier)
+}
+
+func (e *Editor) MoveToPreviousDiagnostic(multiplier int) {
+	e.focusedWindow.MoveToPreviousDiagnostic(multiplier)
+}
+
 func (e *Editor) MoveCursorToLine(line int) {
-	newRow := line-1
+	newRow := line - 1
 	if newRow > e.GetActiveWindow().GetBuffer().GetRowCount()-1 {
 		newRow = e.GetActiveWindow().GetBuffer().GetRowCount() - 1
 	}
@@ -334,6 +741,35 @@ func (e *Editor) MoveCursorToStartOfLineBelowCursor() {
 
 // editable
 
+// MoveCursorToPosition moves the cursor to a screen position in the focused window.
+func (e *Editor) MoveCursorToPosition(p gott.Point) {
+	e.focusedWindow.MoveCursorToPosition(p)
+}
+
+func (e *Editor) StartSelection(anchor gott.Point) {
+	e.focusedWindow.StartSelection(anchor)
+}
+
+func (e *Editor) ClearSelection() {
+	e.focusedWindow.ClearSelection()
+}
+
+func (e *Editor) ReselectSelection() bool {
+	return e.focusedWindow.ReselectSelection()
+}
+
+func (e *Editor) GetSelection() (start, end gott.Point, ok bool) {
+	return e.focusedWindow.GetSelection()
+}
+
+func (e *Editor) SwapSelectionEnds() {
+	e.focusedWindow.SwapSelectionEnds()
+}
+
+func (e *Editor) YankSelection() {
+	e.focusedWindow.YankSelection()
+}
+
 func (e *Editor) GetCursor() gott.Point {
 	return e.focusedWindow.GetCursor()
 }
@@ -346,6 +782,10 @@ func (e *Editor) ReplaceCharacterAtCursor(cursor gott.Point, c rune) rune {
 	return e.focusedWindow.ReplaceCharacterAtCursor(cursor, c)
 }
 
+func (e *Editor) ReplaceRow(row int, text string) string {
+	return e.focusedWindow.ReplaceRow(row, text)
+}
+
 func (e *Editor) DeleteRowsAtCursor(multiplier int) string {
 	return e.focusedWindow.DeleteRowsAtCursor(multiplier)
 }
@@ -353,6 +793,42 @@ func (e *Editor) DeleteRowsAtCursor(multiplier int) string {
 func (e *Editor) SetPasteBoard(text string, mode int) {
 	e.pasteText = text
 	e.pasteMode = mode
+	e.addKill(text)
+}
+
+// The kill ring keeps this many of the most recent pasteboard texts.
+const killRingSize = 16
+
+func (e *Editor) addKill(text string) {
+	if text == "" {
+		return
+	}
+	e.killRing = append(e.killRing, text)
+	if len(e.killRing) > killRingSize {
+		e.killRing = e.killRing[len(e.killRing)-killRingSize:]
+	}
+	e.killIndex = len(e.killRing) - 1
+}
+
+// GetKillRing returns the texts in the kill ring, oldest first.
+func (e *Editor) GetKillRing() []string {
+	return e.killRing
+}
+
+// GetKill returns the text in the kill ring that would be yanked, or an empty string if the ring is empty.
+func (e *Editor) GetKill() string {
+	if len(e.killRing) == 0 {
+		return ""
+	}
+	return e.killRing[e.killIndex]
+}
+
+// RotateKillRing makes the next older text the one to yank, wrapping around to the newest.
+func (e *Editor) RotateKillRing() {
+	if len(e.killRing) == 0 {
+		return
+	}
+	e.killIndex = (e.killIndex + len(e.killRing) - 1) % len(e.killRing)
 }
 
 func (e *Editor) DeleteWordsAtCursor(multiplier int) string {
@@ -367,7 +843,15 @@ func (e *Editor) ChangeWordAtCursor(multiplier int, text string) (string, int) {
 	return e.focusedWindow.ChangeWordAtCursor(multiplier, text)
 }
 
-func (e *Editor) InsertText(text string, position int) (gott.Point, int) {
+func (e *Editor) DeleteRange(start, end gott.Point, finallyDeleteRow bool) string {
+	return e.focusedWindow.DeleteRange(start, end, finallyDeleteRow)
+}
+
+func (e *Editor) ChangeRange(start, end gott.Point, text string) (deletedText string, insertEnd gott.Point, mode int) {
+	return e.focusedWindow.ChangeRange(start, end, text)
+}
+
+func (e *Editor) InsertText(text string, position int) (start, end gott.Point, mode int) {
 	return e.focusedWindow.InsertText(text, position)
 }
 
@@ -407,13 +891,192 @@ func (e *Editor) HalfPageDown(multiplier int) {
 	e.focusedWindow.HalfPageDown(multiplier)
 }
 
+func (e *Editor) CenterCursor() {
+	e.focusedWindow.CenterCursor()
+}
+
 func (e *Editor) SetSize(s gott.Size) {
 	e.size = s
 }
 
+// SetTabWidth sets the editor's tab width, which is also used by the focused buffer
+// instead of any indentation that was found in it.
+func (e *Editor) SetTabWidth(width int) {
+	if width > 0 {
+		e.tabWidth = width
+		e.ignoreFoundIndentation()
+	}
+}
+
+// GetTabWidth returns the tab width of the focused buffer, if it has one, or the editor's tab width.
+func (e *Editor) GetTabWidth() int {
+	w, _ := e.focusedWindow.(*Window)
+	if w == nil {
+		return e.tabWidth
+	}
+	return e.tabWidthOf(w.buffer)
+}
+
+func (e *Editor) SetTextWidth(width int) {
+	if width > 0 {
+		e.textWidth = width
+	}
+}
+
+func (e *Editor) GetTextWidth() int {
+	return e.textWidth
+}
+
+func (e *Editor) SetSearchCenter(center bool) {
+	e.searchCenter = center
+}
+
+func (e *Editor) GetSearchCenter() bool {
+	return e.searchCenter
+}
+
+func (e *Editor) SetVirtualEdit(virtual bool) {
+	e.virtualEdit = virtual
+}
+
+func (e *Editor) GetVirtualEdit() bool {
+	return e.virtualEdit
+}
+
+func (e *Editor) SetShowByteOffset(show bool) {
+	e.byteOffset = show
+}
+
+func (e *Editor) GetShowByteOffset() bool {
+	return e.byteOffset
+}
+
+func (e *Editor) SetAutoWrap(wrap bool) {
+	e.autoWrap = wrap
+}
+
+func (e *Editor) GetAutoWrap() bool {
+	return e.autoWrap
+}
+
+// SetLiteralTabs sets whether the editor inserts tab characters, which also applies to the
+// focused buffer instead of any indentation that was found in it.
+func (e *Editor) SetLiteralTabs(literal bool) {
+	e.literalTabs = literal
+	e.ignoreFoundIndentation()
+}
+
+// GetLiteralTabs returns true if the focused buffer is indented with tabs,
+// or if it has no indentation of its own, if the editor inserts tab characters.
+func (e *Editor) GetLiteralTabs() bool {
+	if useTabs, _, ok := e.bufferIndentation(); ok {
+		return useTabs
+	}
+	return e.literalTabs
+}
+
+func (e *Editor) SetStatusLine(format string) {
+	e.statusLine = format
+}
+
+func (e *Editor) GetStatusLine() string {
+	return e.statusLine
+}
+
+func (e *Editor) SetTheme(name string) error {
+	theme, err := findTheme(name)
+	if err != nil {
+		return err
+	}
+	e.theme = theme
+	// rehighlight all buffers with the new theme
+	for _, w := range e.documentWindows {
+		if b := w.(*Window).buffer; b != nil {
+			b.Highlighted = false
+		}
+	}
+	return nil
+}
+
+func (e *Editor) GetTheme() *gott.Theme {
+	return e.theme
+}
+
+func (e *Editor) SetDimInactive(dim bool) {
+	e.dimInactive = dim
+}
+
+func (e *Editor) GetDimInactive() bool {
+	return e.dimInactive
+}
+
+func (e *Editor) SetMinimap(minimap bool) {
+	e.minimap = minimap
+}
+
+func (e *Editor) GetMinimap() bool {
+	return e.minimap
+}
+
+func (e *Editor) SetChangeSigns(changeSigns bool) {
+	e.changeSigns = changeSigns
+}
+
+func (e *Editor) GetChangeSigns() bool {
+	return e.changeSigns
+}
+
+func (e *Editor) SetIgnoreCase(ignore bool) {
+	e.ignoreCase = ignore
+}
+
+func (e *Editor) GetIgnoreCase() bool {
+	return e.ignoreCase
+}
+
+func (e *Editor) SetSmartCase(smart bool) {
+	e.smartCase = smart
+}
+
+func (e *Editor) GetSmartCase() bool {
+	return e.smartCase
+}
+
+func (e *Editor) SetEscTimeout(milliseconds int) {
+	if milliseconds >= 0 {
+		e.escTimeout = milliseconds
+	}
+}
+
+func (e *Editor) GetEscTimeout() int {
+	return e.escTimeout
+}
+
+func (e *Editor) SetIdleTimeout(milliseconds int) {
+	if milliseconds >= 0 {
+		e.idleTimeout = milliseconds
+	}
+}
+
+func (e *Editor) GetIdleTimeout() int {
+	return e.idleTimeout
+}
+
+func (e *Editor) SetSwapInterval(milliseconds int) {
+	if milliseconds >= 0 {
+		e.swapInterval = milliseconds
+	}
+}
+
+func (e *Editor) GetSwapInterval() int {
+	return e.swapInterval
+}
+
 func (e *Editor) CloseInsert() {
-	e.insert.Close()
+	// clear the insert operation first so that closing doesn't collect more text
+	insert := e.insert
 	e.insert = nil
+	insert.Close(e)
 }
 
 func (e *Editor) MoveToBeginningOfLine() {
@@ -438,7 +1101,7 @@ func (e *Editor) LayoutWindows() {
 
 func (e *Editor) RenderWindows(d gott.Display) {
 	// render the visible windows
-	e.rootWindow.Render(d)
+	e.rootWindow.Render(d, e.focusedWindow)
 	// the focused window should set the cursor
 	e.focusedWindow.SetCursorForDisplay(d)
 }
@@ -459,7 +1122,14 @@ func (e *Editor) SplitWindowHorizontally() {
 
 func (e *Editor) CloseActiveWindow() {
 	removedWindow := e.focusedWindow.(*Window)
+	e.rememberCursorPosition(removedWindow)
+	e.saveCursorPositions()
+	parent := removedWindow.parent
 	e.focusedWindow = e.focusedWindow.Close()
+	// the parent may have replaced the sibling of the closed window
+	if parent != nil && parent.buffer != nil {
+		e.documentWindows[parent.number] = parent
+	}
 	e.PurgeIfOffscreenDuplicate(removedWindow)
 }
 
@@ -40,6 +40,8 @@ func (e *Editor) Gofmt(filename string, inputBytes []byte) (outputBytes []byte,
 
 	outputBytes, _ = ioutil.ReadAll(output)
 	errors, _ := ioutil.ReadAll(cmderr)
+	// gofmt checks the focused buffer, so its errors are shown there
+	e.focusedWindow.GetBuffer().SetDiagnostics(ParseGofmtErrors(string(errors)))
 	if len(errors) > 0 {
 		errors := strings.Replace(string(errors), "<standard input>", filename, -1)
 		log.Printf("Syntax errors in code:\n%s", errors)
@@ -0,0 +1,80 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+// IsBinary returns true for data that contains null bytes, which text files don't have.
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// HexDump formats data as lines of an offset, sixteen bytes in hex, and the same bytes as ASCII.
+func HexDump(data []byte) string {
+	return hex.Dump(data)
+}
+
+// GetBinary returns true if the buffer shows a hex dump of binary data.
+func (b *Buffer) GetBinary() bool {
+	return b.binary
+}
+
+// loadFileBytes loads the contents of a file into the buffer.
+// Binary files are shown as a read-only hex dump.
+func (b *Buffer) loadFileBytes(data []byte) {
+	if IsBinary(data) {
+		b.loadHexDump(data)
+		return
+	}
+	if b.binary {
+		// the file was binary when it was read before
+		b.binary = false
+		b.ReadOnly = false
+	}
+	b.LoadBytes(data)
+	b.SetModified(false)
+	b.setSavedBytes(data)
+}
+
+// loadHexDump replaces the contents of the buffer with a read-only hex dump of data.
+func (b *Buffer) loadHexDump(data []byte) {
+	dump := []byte(HexDump(data))
+	b.binary = true
+	b.ReadOnly = true
+	b.LoadBytes(dump)
+	b.encoding = "binary"
+	b.SetModified(false)
+	b.setSavedBytes(dump)
+}
+
+// ViewBinary shows the contents of the focused buffer as a read-only hex dump.
+func (e *Editor) ViewBinary() error {
+	buffer := e.focusedWindow.(*Window).buffer
+	if buffer.binary {
+		return nil
+	}
+	if buffer.GetModified() {
+		return errors.New("No write since last change")
+	}
+	buffer.loadHexDump(buffer.GetBytes())
+	// the hex dump can't be edited, so earlier edits can't be undone
+	buffer.undo = nil
+	buffer.redo = nil
+	return nil
+}
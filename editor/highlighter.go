@@ -23,6 +23,7 @@ import (
 
 // The GoHighlighter highlights Go code.
 type GoHighlighter struct {
+	theme               *gott.Theme
 	hexPattern          *regexp.Regexp
 	punctuationPattern  *regexp.Regexp
 	commentPattern      *regexp.Regexp
@@ -31,8 +32,8 @@ type GoHighlighter struct {
 	numberPattern       *regexp.Regexp
 }
 
-func NewGoHighlighter() *GoHighlighter {
-	h := &GoHighlighter{}
+func NewGoHighlighter(theme *gott.Theme) *GoHighlighter {
+	h := &GoHighlighter{theme: theme}
 
 	h.hexPattern, _ = regexp.Compile("0x[0-9|a-f][0-9|a-f]")
 	h.punctuationPattern, _ = regexp.Compile("\\(|\\)|,|:|=|\\[|\\]|\\{|\\}|\\+|-|\\*|<|>|;")
@@ -62,7 +63,7 @@ func (h *GoHighlighter) Highlight(b *Buffer) {
 				// if there's an alphanumeric character on either side, skip this
 				if !checkalphanum(line, match[0], match[1]) {
 					for k := match[0]; k < match[1]; k++ {
-						colors[k] = 0x70
+						colors[k] = h.theme.Keyword
 					}
 				}
 			}
@@ -74,7 +75,7 @@ func (h *GoHighlighter) Highlight(b *Buffer) {
 				// if there's an alphanumeric character on either side, skip this
 				if !checkalphanum(line, match[0], match[1]) {
 					for k := match[0]; k < match[1]; k++ {
-						colors[k] = 0x83
+						colors[k] = h.theme.Number
 					}
 				}
 			}
@@ -84,7 +85,7 @@ func (h *GoHighlighter) Highlight(b *Buffer) {
 		if matches != nil {
 			for _, match := range matches {
 				for k := match[0]; k < match[1]; k++ {
-					colors[k] = 0x71
+					colors[k] = h.theme.Punctuation
 				}
 			}
 		}
@@ -102,7 +103,7 @@ func (h *GoHighlighter) Highlight(b *Buffer) {
 		if matches != nil {
 			for _, match := range matches {
 				for k := match[0]; k < match[1]; k++ {
-					colors[k] = 0xe0
+					colors[k] = h.theme.String
 				}
 			}
 		}
@@ -110,7 +111,7 @@ func (h *GoHighlighter) Highlight(b *Buffer) {
 		if matches != nil {
 			for _, match := range matches {
 				for k := match[0]; k < match[1]; k++ {
-					colors[k] = 0xf8
+					colors[k] = h.theme.Comment
 				}
 			}
 		}
@@ -0,0 +1,119 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+// DetectIndentation guesses how lines of text are indented.
+// It returns true if more lines are indented with tabs than with spaces.
+// Otherwise it returns the most common increase in indentation between
+// consecutive lines, or 0 if no lines are indented.
+func DetectIndentation(lines []string) (useTabs bool, width int) {
+	tabbed, spaced := 0, 0
+	increases := make(map[int]int)
+	previous := 0
+	for _, line := range lines {
+		if len(line) > 0 && line[0] == '\t' {
+			tabbed++
+			continue
+		}
+		indent := 0
+		for indent < len(line) && line[indent] == ' ' {
+			indent++
+		}
+		if indent == len(line) || line[indent] == '\t' {
+			// blank lines and mixed indentation don't show the indentation width
+			continue
+		}
+		if indent > 0 {
+			spaced++
+		}
+		// single spaces usually align text, like the stars in block comments
+		if increase := indent - previous; increase >= 2 && increase <= 8 {
+			increases[increase]++
+		}
+		previous = indent
+	}
+	if tabbed > spaced {
+		return true, 0
+	}
+	for increase := 2; increase <= 8; increase++ {
+		if increases[increase] > increases[width] {
+			width = increase
+		}
+	}
+	return false, width
+}
+
+// GetIndentation returns the indentation found in or set for the buffer.
+// It returns false if the buffer's indentation is unknown.
+// A width of zero means that tabs are as wide as the editor's tab width.
+func (b *Buffer) GetIndentation() (useTabs bool, width int, ok bool) {
+	return b.indentTabs, b.indentWidth, b.indentFound || b.indentSet
+}
+
+// SetIndentation sets the buffer's indentation, overriding any that was found when it was loaded.
+func (b *Buffer) SetIndentation(useTabs bool, width int) {
+	b.indentTabs = useTabs
+	b.indentWidth = width
+	b.indentSet = true
+}
+
+// detectIndentation finds the indentation of text that is loaded into the buffer.
+func (b *Buffer) detectIndentation(lines []string) {
+	if b.indentSet {
+		return
+	}
+	b.indentTabs, b.indentWidth = DetectIndentation(lines)
+	b.indentFound = b.indentTabs || b.indentWidth > 0
+}
+
+// bufferIndentation returns the indentation of the focused buffer if it overrides the editor's.
+func (e *Editor) bufferIndentation() (useTabs bool, width int, ok bool) {
+	w, _ := e.focusedWindow.(*Window)
+	if w == nil {
+		return false, 0, false
+	}
+	return e.indentationOf(w.buffer)
+}
+
+// indentationOf returns the indentation of a buffer if it overrides the editor's.
+func (e *Editor) indentationOf(b *Buffer) (useTabs bool, width int, ok bool) {
+	if b == nil || (!b.indentSet && !(b.indentFound && e.detectIndent)) {
+		return false, 0, false
+	}
+	return b.indentTabs, b.indentWidth, true
+}
+
+// tabWidthOf returns the tab width of a buffer, if it has one, or the editor's tab width.
+func (e *Editor) tabWidthOf(b *Buffer) int {
+	if _, width, ok := e.indentationOf(b); ok && width > 0 {
+		return width
+	}
+	return e.tabWidth
+}
+
+// ignoreFoundIndentation makes the focused buffer use the editor's indentation settings.
+func (e *Editor) ignoreFoundIndentation() {
+	if w, _ := e.focusedWindow.(*Window); w != nil && w.buffer != nil {
+		w.buffer.indentFound = false
+	}
+}
+
+func (e *Editor) SetDetectIndentation(detect bool) {
+	e.detectIndent = detect
+}
+
+func (e *Editor) GetDetectIndentation() bool {
+	return e.detectIndent
+}
@@ -0,0 +1,100 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// The minimap is an overview of a whole buffer drawn at the right edge of a window.
+// Each minimap row summarizes a range of buffer rows, and each minimap column
+// summarizes a range of text columns.
+const (
+	minimapWidth       = 4  // columns used by the minimap
+	minimapColumnWidth = 20 // text columns summarized by each minimap column
+)
+
+// MinimapRows maps buffer rows to the rows of a minimap with the given height.
+// It returns the first buffer row summarized by each minimap row; each minimap
+// row summarizes the rows up to the first row of the next one. Buffers that fit
+// in the minimap have one minimap row per buffer row.
+func MinimapRows(rowCount, height int) []int {
+	if rowCount <= height {
+		height = rowCount
+	}
+	rows := make([]int, height)
+	for i := range rows {
+		rows[i] = i * rowCount / height
+	}
+	return rows
+}
+
+// textWidth returns the number of columns available for text.
+func (w *Window) textWidth() int {
+	width := w.size.Cols - w.gutterWidth()
+	if w.hasMinimap() {
+		width -= minimapWidth
+	}
+	return width
+}
+
+// Minimaps are only drawn in windows that are much wider than they are.
+func (w *Window) hasMinimap() bool {
+	return w.editor.GetMinimap() && w.size.Cols > 4*minimapWidth
+}
+
+// renderMinimap draws the minimap, reversing the rows that summarize the visible text.
+func (w *Window) renderMinimap(display gott.Display, setCell func(j int, i int, c rune, color gott.Color)) {
+	b := w.buffer
+	textRows := w.size.Rows - 1
+	left := w.textLeft() + w.textWidth()
+	rows := MinimapRows(len(b.rows), textRows)
+	for i, first := range rows {
+		last := len(b.rows)
+		if i+1 < len(rows) {
+			last = rows[i+1]
+		}
+		visible := first < w.offset.Rows+textRows && last > w.offset.Rows
+		for j := 0; j < minimapWidth; j++ {
+			c := minimapCell(b.rows[first:last], j)
+			if visible {
+				display.SetCellReversed(left+j, w.origin.Row+i, c, gott.ColorWhite)
+			} else {
+				setCell(left+j, w.origin.Row+i, c, gott.ColorWhite)
+			}
+		}
+	}
+}
+
+// minimapCell returns a character that shows how much text the rows have in a minimap column.
+func minimapCell(rows []*Row, column int) rune {
+	count := 0
+	for _, row := range rows {
+		text := row.GetText()
+		for k := column * minimapColumnWidth; k < (column+1)*minimapColumnWidth && k < len(text); k++ {
+			if text[k] != ' ' && text[k] != '\t' {
+				count++
+			}
+		}
+	}
+	switch density := count * 2 / (len(rows) * minimapColumnWidth); {
+	case count == 0:
+		return ' '
+	case density == 0:
+		return '.'
+	default:
+		return ':'
+	}
+}
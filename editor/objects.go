@@ -0,0 +1,194 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// WordObjectRange returns the first and last positions of the word under the cursor.
+// Words are runs of letters and digits, runs of punctuation, or runs of spaces.
+// If around is true, the range includes the spaces that follow the word,
+// or if there are none, the spaces that precede it.
+func (w *Window) WordObjectRange(around bool) (start, end gott.Point) {
+	start, end = w.cursor, w.cursor
+	if w.cursor.Row >= w.buffer.GetRowCount() {
+		return start, end
+	}
+	text := w.buffer.rows[w.cursor.Row].GetText()
+	if w.cursor.Col >= len(text) {
+		return start, end
+	}
+	kind := kindOfWord(text[w.cursor.Col])
+	for start.Col > 0 && kindOfWord(text[start.Col-1]) == kind {
+		start.Col--
+	}
+	for end.Col < len(text)-1 && kindOfWord(text[end.Col+1]) == kind {
+		end.Col++
+	}
+	if around && kind != gott.WordSpace {
+		if end.Col < len(text)-1 && kindOfWord(text[end.Col+1]) == gott.WordSpace {
+			for end.Col < len(text)-1 && kindOfWord(text[end.Col+1]) == gott.WordSpace {
+				end.Col++
+			}
+		} else {
+			for start.Col > 0 && kindOfWord(text[start.Col-1]) == gott.WordSpace {
+				start.Col--
+			}
+		}
+	}
+	return start, end
+}
+
+// DelimiterRange returns the positions of a pair of delimiters around the cursor
+// in the cursor row. If open and close are the same, as they are for quotes, the
+// delimiters are paired from the start of the row and the first pair that doesn't
+// end before the cursor is used. Brackets may be nested.
+func (w *Window) DelimiterRange(open, close rune) (start, end gott.Point, ok bool) {
+	start, end = w.cursor, w.cursor
+	if w.cursor.Row >= w.buffer.GetRowCount() {
+		return start, end, false
+	}
+	text := w.buffer.rows[w.cursor.Row].GetText()
+	col := w.cursor.Col
+	if col >= len(text) {
+		return start, end, false
+	}
+	left, right := -1, -1
+	if open == close {
+		// quotes are paired from the start of the row
+		var quotes []int
+		for i, c := range text {
+			if c == open {
+				quotes = append(quotes, i)
+			}
+		}
+		for i := 0; i+1 < len(quotes) && right < 0; i += 2 {
+			if quotes[i+1] >= col {
+				left, right = quotes[i], quotes[i+1]
+			}
+		}
+	} else {
+		i := col
+		if text[col] == close {
+			// the cursor is on the closing delimiter
+			i--
+		}
+		for depth := 0; i >= 0 && left < 0; i-- {
+			switch text[i] {
+			case close:
+				depth++
+			case open:
+				if depth == 0 {
+					left = i
+				}
+				depth--
+			}
+		}
+		for i, depth := left+1, 0; left >= 0 && i < len(text) && right < 0; i++ {
+			switch text[i] {
+			case open:
+				depth++
+			case close:
+				if depth == 0 {
+					right = i
+				}
+				depth--
+			}
+		}
+	}
+	if left < 0 || right < 0 {
+		return start, end, false
+	}
+	start.Col, end.Col = left, right
+	return start, end, true
+}
+
+// The brackets that can be matched, each paired with its partner.
+var brackets = map[rune]rune{'(': ')', '[': ']', '{': '}', ')': '(', ']': '[', '}': '{'}
+
+// MatchingBracket returns the position of the bracket that matches the one at a position.
+// Nested brackets are skipped, and matches can be on other rows.
+// It returns false if there is no bracket at the position or it has no match.
+func (w *Window) MatchingBracket(p gott.Point) (gott.Point, bool) {
+	b := w.buffer
+	c := b.GetCharacterAtCursor(p)
+	partner, ok := brackets[c]
+	if !ok {
+		return p, false
+	}
+	forward := c == '(' || c == '[' || c == '{'
+	depth := 0
+	row, col := p.Row, p.Col
+	for {
+		if forward {
+			col++
+			for row < len(b.rows) && col >= b.rows[row].Length() {
+				row++
+				col = 0
+			}
+			if row >= len(b.rows) {
+				return p, false
+			}
+		} else {
+			col--
+			for row >= 0 && col < 0 {
+				row--
+				if row >= 0 {
+					col = b.rows[row].Length() - 1
+				}
+			}
+			if row < 0 {
+				return p, false
+			}
+		}
+		switch b.rows[row].GetText()[col] {
+		case c:
+			depth++
+		case partner:
+			if depth == 0 {
+				return gott.Point{Row: row, Col: col}, true
+			}
+			depth--
+		}
+	}
+}
+
+// MoveCursorToMatchingBracket moves the cursor to the bracket that matches the one at the cursor.
+func (w *Window) MoveCursorToMatchingBracket() {
+	if match, ok := w.MatchingBracket(w.cursor); ok {
+		w.cursor = match
+	}
+}
+
+// renderMatchingBracket colors the bracket that matches the one at the cursor,
+// or the bracket at the cursor if it has no match.
+func (w *Window) renderMatchingBracket(setCell func(j int, i int, c rune, color gott.Color)) {
+	c := w.buffer.GetCharacterAtCursor(w.cursor)
+	if _, ok := brackets[c]; !ok {
+		return
+	}
+	theme := w.editor.GetTheme()
+	p, color := w.cursor, theme.Error
+	if match, ok := w.MatchingBracket(w.cursor); ok {
+		p, color = match, theme.Match
+		c = w.buffer.GetCharacterAtCursor(match)
+	}
+	row, col := p.Row-w.offset.Rows, w.displayColumn(p)-w.offset.Cols
+	if row < 0 || row >= w.size.Rows-1 || col < 0 || col >= w.textWidth() {
+		return
+	}
+	setCell(col+w.textLeft(), row+w.origin.Row, c, color)
+}
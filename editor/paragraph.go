@@ -0,0 +1,112 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"strings"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// Paragraphs are separated by rows that are empty or contain only whitespace.
+func (w *Window) isBlankRow(row int) bool {
+	return strings.TrimSpace(w.buffer.rows[row].GetString()) == ""
+}
+
+// MoveToNextParagraph moves the cursor to the blank row that ends the paragraph,
+// or to the end of the last row if there is no following blank row.
+func (w *Window) MoveToNextParagraph(multiplier int) {
+	count := w.buffer.GetRowCount()
+	if count == 0 {
+		return
+	}
+	row := w.cursor.Row
+	for i := 0; i < multiplier && row < count-1; i++ {
+		// skip blank rows between paragraphs, then the rows of the paragraph
+		for row < count-1 && w.isBlankRow(row) {
+			row++
+		}
+		for row < count-1 && !w.isBlankRow(row) {
+			row++
+		}
+	}
+	w.cursor.Row = row
+	if row == count-1 && !w.isBlankRow(row) {
+		w.MoveToEndOfLine()
+	} else {
+		w.cursor.Col = 0
+	}
+}
+
+// MoveToPreviousParagraph moves the cursor to the blank row that starts the paragraph,
+// or to the start of the first row if there is no preceding blank row.
+func (w *Window) MoveToPreviousParagraph(multiplier int) {
+	if w.buffer.GetRowCount() == 0 {
+		return
+	}
+	row := w.cursor.Row
+	for i := 0; i < multiplier && row > 0; i++ {
+		for row > 0 && w.isBlankRow(row) {
+			row--
+		}
+		for row > 0 && !w.isBlankRow(row) {
+			row--
+		}
+	}
+	w.cursor.Row = row
+	w.cursor.Col = 0
+}
+
+// ParagraphObjectRange returns the first and last positions of the paragraph
+// around the cursor. If around is true, the range includes the blank rows that
+// follow the paragraph, or if there are none, the blank rows that precede it.
+// On a blank row, the paragraph is the run of blank rows around the cursor.
+func (w *Window) ParagraphObjectRange(around bool) (start, end gott.Point) {
+	last := w.buffer.GetRowCount() - 1
+	// an empty last row is just the end of the final line
+	if last > 0 && w.buffer.rows[last].Length() == 0 {
+		last--
+	}
+	if last < 0 {
+		return start, end
+	}
+	row := clipToRange(w.cursor.Row, 0, last)
+	blank := w.isBlankRow(row)
+	first, final := row, row
+	for first > 0 && w.isBlankRow(first-1) == blank {
+		first--
+	}
+	for final < last && w.isBlankRow(final+1) == blank {
+		final++
+	}
+	if around {
+		extended := final
+		for extended < last && w.isBlankRow(extended+1) != blank {
+			extended++
+		}
+		if extended == final && !blank {
+			for first > 0 && w.isBlankRow(first-1) {
+				first--
+			}
+		}
+		final = extended
+	}
+	start = gott.Point{Row: first, Col: 0}
+	end = gott.Point{Row: final, Col: w.buffer.rows[final].Length() - 1}
+	if end.Col < 0 {
+		end.Col = 0
+	}
+	return start, end
+}
@@ -0,0 +1,128 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// DefaultCursorPositions is the file that stores the last cursor position in each file.
+var DefaultCursorPositions = os.Getenv("HOME") + "/.gott-positions"
+
+// maxCursorPositions is the number of files whose cursor positions are remembered.
+const maxCursorPositions = 100
+
+// A cursorPosition is the last cursor position in a file.
+type cursorPosition struct {
+	path   string
+	cursor gott.Point
+}
+
+// LoadCursorPositions reads the last cursor positions in files, most recent first.
+// Each line of the file has a row, a column, and an absolute path.
+// Files that no longer exist are forgotten, and positions that are remembered
+// later are saved to the same path.
+func (e *Editor) LoadCursorPositions(path string) error {
+	e.positionsPath = path
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// the positions are saved when the first file is closed or written
+		return nil
+	} else if err != nil {
+		return err
+	}
+	e.cursorPositions = nil
+	for _, line := range strings.Split(string(bytes), "\n") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 || len(e.cursorPositions) == maxCursorPositions {
+			continue
+		}
+		row, err1 := strconv.Atoi(fields[0])
+		col, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if _, err := os.Stat(fields[2]); err != nil {
+			continue
+		}
+		e.cursorPositions = append(e.cursorPositions, cursorPosition{path: fields[2], cursor: gott.Point{Row: row, Col: col}})
+	}
+	return nil
+}
+
+// SaveCursorPositions remembers the cursor positions in the files of all windows and saves them.
+func (e *Editor) SaveCursorPositions() {
+	for _, w := range e.documentWindows {
+		e.rememberCursorPosition(w.(*Window))
+	}
+	e.saveCursorPositions()
+}
+
+// rememberCursorPosition moves the position of a window's cursor to the front of the remembered positions.
+func (e *Editor) rememberCursorPosition(w *Window) {
+	if w.buffer == nil || w.buffer.GetFileName() == "" {
+		return
+	}
+	path := w.buffer.GetFileName()
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+	positions := []cursorPosition{{path: path, cursor: w.cursor}}
+	for _, position := range e.cursorPositions {
+		if position.path != path && len(positions) < maxCursorPositions {
+			positions = append(positions, position)
+		}
+	}
+	e.cursorPositions = positions
+}
+
+// saveCursorPositions writes the remembered positions to the file that they were loaded from.
+func (e *Editor) saveCursorPositions() {
+	if e.positionsPath == "" {
+		return
+	}
+	var s string
+	for _, position := range e.cursorPositions {
+		s += fmt.Sprintf("%d %d %s\n", position.cursor.Row, position.cursor.Col, position.path)
+	}
+	if err := ioutil.WriteFile(e.positionsPath, []byte(s), 0644); err != nil {
+		log.Printf("%+v", err)
+	}
+}
+
+// restoreCursorPosition moves a window's cursor to the remembered position in its file.
+func (e *Editor) restoreCursorPosition(w *Window) {
+	path := w.buffer.GetFileName()
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+	for _, position := range e.cursorPositions {
+		if position.path == path {
+			// the file may have changed since the position was saved
+			w.cursor = position.cursor
+			w.cursor.Row = clipToRange(w.cursor.Row, 0, w.buffer.GetRowCount()-1)
+			w.KeepCursorInRow()
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRecentFiles is the file that lists recently opened files.
+var DefaultRecentFiles = os.Getenv("HOME") + "/.gott-recent"
+
+// maxRecentFiles is the number of recently opened files that are remembered.
+const maxRecentFiles = 20
+
+// LoadRecentFiles reads a list of recently opened files, most recent first.
+// Files that are opened later are added to the list and it is saved to the same path.
+func (e *Editor) LoadRecentFiles(path string) error {
+	e.recentFilesPath = path
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// the list is created when the first file is opened
+		return nil
+	} else if err != nil {
+		return err
+	}
+	e.recentFiles = nil
+	for _, line := range strings.Split(string(bytes), "\n") {
+		if line != "" && len(e.recentFiles) < maxRecentFiles {
+			e.recentFiles = append(e.recentFiles, line)
+		}
+	}
+	return nil
+}
+
+// GetRecentFiles returns the absolute paths of recently opened files, most recent first.
+func (e *Editor) GetRecentFiles() []string {
+	return e.recentFiles
+}
+
+// addRecentFile moves a file to the front of the recent files and saves the list.
+func (e *Editor) addRecentFile(path string) {
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+	files := []string{path}
+	for _, file := range e.recentFiles {
+		if file != path && len(files) < maxRecentFiles {
+			files = append(files, file)
+		}
+	}
+	e.recentFiles = files
+	if e.recentFilesPath == "" {
+		return
+	}
+	if err := ioutil.WriteFile(e.recentFilesPath, []byte(strings.Join(files, "\n")+"\n"), 0644); err != nil {
+		log.Printf("%+v", err)
+	}
+}
@@ -15,8 +15,6 @@
 package editor
 
 import (
-	"strings"
-
 	gott "github.com/timburks/gott/types"
 )
 
@@ -27,10 +25,10 @@ type Row struct {
 	colors []gott.Color
 }
 
-// Upon creation, we replace any tabs with spaces
+// Tabs are kept in rows and displayed at tab stops.
 func NewRow(text string) *Row {
 	r := &Row{}
-	r.SetText([]rune(strings.Replace(text, "\t", "        ", -1)))
+	r.SetText([]rune(text))
 	return r
 }
 
@@ -125,10 +123,10 @@ func (r *Row) TextFromColumn(col int) string {
 	}
 }
 
-func (r *Row) FirstPositionAfterCol(col int, text string) int {
+func (r *Row) FirstPositionAfterCol(col int, s *search) int {
 	searchposition := col+1
 	searchtext := r.TextFromColumn(searchposition)
-	i := strings.Index(searchtext, text)
+	i := s.index(searchtext)
 	if i == -1 {
 		return -1
 	} else {
@@ -136,12 +134,12 @@ func (r *Row) FirstPositionAfterCol(col int, text string) int {
 	}
 }
 
-func (r *Row) LastPositionBeforeCol(col int, text string) int {
+func (r *Row) LastPositionBeforeCol(col int, s *search) int {
 	foundposition := -1
 	searchposition := 0
 	searchtext := r.TextFromColumn(searchposition)
 	for {
-		i := strings.Index(searchtext, text)
+		i := s.index(searchtext)
 		if i == -1 {
 			return foundposition
 		} else {
@@ -155,6 +153,5 @@ func (r *Row) LastPositionBeforeCol(col int, text string) int {
 			}
 		}
 	}
-	return foundposition
 }
 
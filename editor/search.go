@@ -0,0 +1,102 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// A search describes text to find in rows.
+type search struct {
+	text       string
+	ignoreCase bool
+	wholeWord  bool // true to only match text that isn't part of a longer word
+}
+
+// Return the index of the first match in a line, or -1 if there is none.
+func (s *search) index(line string) int {
+	text := s.text
+	if s.ignoreCase {
+		line = strings.ToLower(line)
+		text = strings.ToLower(text)
+	}
+	offset := 0
+	for {
+		i := strings.Index(line[offset:], text)
+		if i == -1 {
+			return -1
+		}
+		i += offset
+		if !s.wholeWord || isWordBoundary(line, i, i+len(text)) {
+			return i
+		}
+		offset = i + 1
+	}
+}
+
+// Return true if the text between start and end isn't adjacent to other word characters.
+func isWordBoundary(line string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(line[0:start]); start > 0 && isAlphaNumeric(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isAlphaNumeric(after) {
+		return false
+	}
+	return true
+}
+
+// SetHighlightPattern sets a regular expression whose matches are highlighted in every window.
+// An empty pattern removes the highlighting. Invalid patterns return an error and leave it unchanged.
+func (e *Editor) SetHighlightPattern(pattern string) error {
+	if pattern == "" {
+		e.highlight = nil
+		return nil
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	e.highlight = r
+	return nil
+}
+
+// GetHighlightPattern returns the pattern whose matches are highlighted, or an empty string if there is none.
+func (e *Editor) GetHighlightPattern() string {
+	if e.highlight == nil {
+		return ""
+	}
+	return e.highlight.String()
+}
+
+// highlightedRanges returns the byte ranges of a line that match the highlight pattern.
+func (w *Window) highlightedRanges(line string) [][]int {
+	e, ok := w.editor.(*Editor)
+	if !ok || e.highlight == nil {
+		return nil
+	}
+	return e.highlight.FindAllStringIndex(line, -1)
+}
+
+// inRanges returns true if a position is in one of a list of ranges.
+func inRanges(ranges [][]int, position int) bool {
+	for _, r := range ranges {
+		if position >= r[0] && position < r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"strings"
+	"unicode"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// MoveToNextSentence moves the cursor to the start of the next sentence,
+// or to the end of the last row if there are no more sentences.
+func (w *Window) MoveToNextSentence(multiplier int) {
+	if w.buffer.GetRowCount() == 0 {
+		return
+	}
+	for i := 0; i < multiplier; i++ {
+		p, ok := w.nextPosition(w.cursor)
+		for ok && !w.isSentenceStart(p) {
+			p, ok = w.nextPosition(p)
+		}
+		if !ok {
+			w.cursor.Row = w.buffer.GetRowCount() - 1
+			w.MoveToEndOfLine()
+			return
+		}
+		w.cursor = p
+	}
+}
+
+// MoveToPreviousSentence moves the cursor to the start of the current sentence,
+// or if it is already there, to the start of the previous one.
+func (w *Window) MoveToPreviousSentence(multiplier int) {
+	if w.buffer.GetRowCount() == 0 {
+		return
+	}
+	for i := 0; i < multiplier; i++ {
+		p, ok := w.previousPosition(w.cursor)
+		for ok && !w.isSentenceStart(p) {
+			p, ok = w.previousPosition(p)
+		}
+		w.cursor = p
+		if !ok {
+			return
+		}
+	}
+}
+
+// Sentences start with the first non-space character after a '.', '!', or '?'
+// that is followed by whitespace, or after the start of a paragraph.
+// Line breaks count as whitespace.
+func (w *Window) isSentenceStart(p gott.Point) bool {
+	text := w.buffer.rows[p.Row].GetText()
+	if p.Col >= len(text) || unicode.IsSpace(text[p.Col]) {
+		return false
+	}
+	spaced := false
+	row, col := p.Row, p.Col-1
+	for {
+		if col < 0 {
+			if row == 0 || w.isBlankRow(row-1) {
+				return true
+			}
+			row--
+			col = w.buffer.rows[row].Length() - 1
+			spaced = true
+			continue
+		}
+		c := w.buffer.rows[row].GetText()[col]
+		if !unicode.IsSpace(c) {
+			return spaced && strings.ContainsRune(".!?", c)
+		}
+		spaced = true
+		col--
+	}
+}
+
+// Return the position after p, which may be on the next row.
+// Empty rows have a single position.
+func (w *Window) nextPosition(p gott.Point) (gott.Point, bool) {
+	if p.Col+1 < w.buffer.rows[p.Row].Length() {
+		return gott.Point{Row: p.Row, Col: p.Col + 1}, true
+	}
+	if p.Row+1 < w.buffer.GetRowCount() {
+		return gott.Point{Row: p.Row + 1, Col: 0}, true
+	}
+	return p, false
+}
+
+// Return the position before p, which may be on the previous row.
+func (w *Window) previousPosition(p gott.Point) (gott.Point, bool) {
+	if p.Col > 0 {
+		return gott.Point{Row: p.Row, Col: p.Col - 1}, true
+	}
+	if p.Row > 0 {
+		col := w.buffer.rows[p.Row-1].Length() - 1
+		if col < 0 {
+			col = 0
+		}
+		return gott.Point{Row: p.Row - 1, Col: col}, true
+	}
+	return p, false
+}
@@ -0,0 +1,61 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SessionScript returns a lisp script that reopens the visible files,
+// recreates the window layout, and restores cursor positions.
+// The script expects to be run in an editor with a single window.
+func (e *Editor) SessionScript() string {
+	s := ";; gott session\n"
+	focused := 0
+	count := 0
+	var visit func(w *Window)
+	visit = func(w *Window) {
+		if w.buffer == nil {
+			if w.horizontal {
+				s += "(hsplit)\n"
+			} else {
+				s += "(vsplit)\n"
+			}
+			visit(w.child1)
+			// the next window after the last one in child1 is the first one in child2
+			s += "(next-window)\n"
+			visit(w.child2)
+			return
+		}
+		if w == e.focusedWindow {
+			focused = count
+		}
+		count++
+		if w.buffer.GetFileName() != "" {
+			s += fmt.Sprintf("(edit-file %s)\n", strconv.Quote(w.buffer.GetFileName()))
+		}
+		s += fmt.Sprintf("(goto-line %d)\n", w.cursor.Row+1)
+		if w.cursor.Col > 0 {
+			s += fmt.Sprintf("(right %d)\n", w.cursor.Col)
+		}
+	}
+	visit(e.rootWindow.(*Window))
+	// moving forward from the last window wraps around to the first one
+	for i := 0; i <= focused && count > 1; i++ {
+		s += "(next-window)\n"
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// DefaultDictionary is the word list that is loaded when spell checking is first turned on.
+const DefaultDictionary = "/usr/share/dict/words"
+
+// UserDictionary is the file that holds words added with AddSpellingWord.
+// Its words are accepted along with the ones in the default dictionary.
+var UserDictionary = os.Getenv("HOME") + "/.gott-spell"
+
+// A SpellChecker finds words that aren't in a dictionary.
+type SpellChecker struct {
+	words map[string]bool
+}
+
+// NewSpellChecker creates a spell checker that accepts the specified words, ignoring case.
+func NewSpellChecker(words []string) *SpellChecker {
+	s := &SpellChecker{words: make(map[string]bool)}
+	for _, word := range words {
+		s.Add(word)
+	}
+	return s
+}
+
+// LoadSpellChecker creates a spell checker from a file that lists one word per line.
+func LoadSpellChecker(path string) (*SpellChecker, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewSpellChecker(strings.Split(string(bytes), "\n")), nil
+}
+
+// Load adds the words in a file that lists one word per line.
+func (s *SpellChecker) Load(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, word := range strings.Split(string(bytes), "\n") {
+		s.Add(word)
+	}
+	return nil
+}
+
+// Add accepts a word in future checks.
+func (s *SpellChecker) Add(word string) {
+	if word = strings.TrimSpace(word); word != "" {
+		s.words[strings.ToLower(word)] = true
+	}
+}
+
+// Check returns true if a word is in the dictionary.
+func (s *SpellChecker) Check(word string) bool {
+	return s.words[strings.ToLower(word)]
+}
+
+// Misspellings returns the start and end columns of the misspelled words in some text.
+// Words are runs of letters, and apostrophes within them, as in "don't".
+func (s *SpellChecker) Misspellings(text []rune) [][2]int {
+	var misspellings [][2]int
+	for i := 0; i < len(text); {
+		if !unicode.IsLetter(text[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(text) && (unicode.IsLetter(text[i]) ||
+			(text[i] == '\'' && i+1 < len(text) && unicode.IsLetter(text[i+1]))) {
+			i++
+		}
+		if !s.Check(string(text[start:i])) {
+			misspellings = append(misspellings, [2]int{start, i})
+		}
+	}
+	return misspellings
+}
+
+// SetSpell turns spell checking of text buffers on or off.
+// The default and user dictionaries are loaded if no dictionary has been set.
+func (e *Editor) SetSpell(spell bool) error {
+	if spell && e.spellChecker == nil {
+		checker, err := LoadSpellChecker(DefaultDictionary)
+		if err != nil {
+			return err
+		}
+		// the user dictionary doesn't exist until a word is added
+		if err := checker.Load(UserDictionary); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		e.spellChecker = checker
+	}
+	e.spell = spell
+	return nil
+}
+
+func (e *Editor) GetSpell() bool {
+	return e.spell
+}
+
+// SetSpellChecker sets the spell checker used when spell checking is on.
+func (e *Editor) SetSpellChecker(s *SpellChecker) {
+	e.spellChecker = s
+}
+
+// AddSpellingWord accepts a word in spell checks and appends it to the user dictionary.
+func (e *Editor) AddSpellingWord(word string) error {
+	if word == "" {
+		return errors.New("No word to add")
+	}
+	if e.spellChecker != nil {
+		e.spellChecker.Add(word)
+	}
+	f, err := os.OpenFile(UserDictionary, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(word + "\n")
+	return err
+}
+
+// spellChecker returns the spell checker for a window's buffer, or nil if it shouldn't be checked.
+// Only prose is checked.
+func (w *Window) spellChecker() *SpellChecker {
+	e, ok := w.editor.(*Editor)
+	if !ok || !e.spell || e.spellChecker == nil {
+		return nil
+	}
+	if mode := w.buffer.languageMode; mode != "txt" && mode != "md" {
+		return nil
+	}
+	return e.spellChecker
+}
+
+// spellColors returns the colors for a row with misspelled words in the theme's error color.
+func (w *Window) spellColors(s *SpellChecker, row *Row) []gott.Color {
+	colors := row.GetColors()
+	misspellings := s.Misspellings(row.GetText())
+	if len(misspellings) == 0 {
+		return colors
+	}
+	colors = append([]gott.Color{}, colors...)
+	for _, m := range misspellings {
+		for j := m[0]; j < m[1]; j++ {
+			colors[j] = w.editor.GetTheme().Error
+		}
+	}
+	return colors
+}
+
+// MoveToNextMisspelling moves the cursor to the next misspelled word, wrapping around the buffer.
+func (w *Window) MoveToNextMisspelling(multiplier int) {
+	s := w.spellChecker()
+	if s == nil || len(w.buffer.rows) == 0 {
+		return
+	}
+	for m := 0; m < multiplier; m++ {
+		count := len(w.buffer.rows)
+		for i := 0; i <= count; i++ {
+			row := (w.cursor.Row + i) % count
+			found := false
+			for _, misspelling := range s.Misspellings(w.buffer.rows[row].GetText()) {
+				if i > 0 || misspelling[0] > w.cursor.Col {
+					w.cursor = gott.Point{Row: row, Col: misspelling[0]}
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+	}
+}
+
+// MoveToPreviousMisspelling moves the cursor to the previous misspelled word, wrapping around the buffer.
+func (w *Window) MoveToPreviousMisspelling(multiplier int) {
+	s := w.spellChecker()
+	if s == nil || len(w.buffer.rows) == 0 {
+		return
+	}
+	for m := 0; m < multiplier; m++ {
+		count := len(w.buffer.rows)
+		for i := 0; i <= count; i++ {
+			row := (w.cursor.Row - i + count) % count
+			misspellings := s.Misspellings(w.buffer.rows[row].GetText())
+			found := false
+			for k := len(misspellings) - 1; k >= 0; k-- {
+				if i > 0 || misspellings[k][0] < w.cursor.Col {
+					w.cursor = gott.Point{Row: row, Col: misspellings[k][0]}
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+	}
+}
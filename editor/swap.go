@@ -0,0 +1,109 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Swap files hold unsaved changes so they can be recovered after a crash.
+// The swap file for a file named "name" is ".name.gott.swp" in the same directory.
+
+// GetSwapFileName returns the name of the buffer's swap file, or an empty string if the buffer has no file.
+func (b *Buffer) GetSwapFileName() string {
+	if b.fileName == "" {
+		return ""
+	}
+	dir, name := filepath.Split(b.fileName)
+	return filepath.Join(dir, "."+name+".gott.swp")
+}
+
+// WriteSwap saves the contents of a modified buffer to its swap file.
+func (b *Buffer) WriteSwap() error {
+	swap := b.GetSwapFileName()
+	if swap == "" || !b.modified {
+		return nil
+	}
+	return ioutil.WriteFile(swap, b.GetBytes(), 0600)
+}
+
+// RemoveSwap deletes the buffer's swap file if it exists.
+func (b *Buffer) RemoveSwap() {
+	if swap := b.GetSwapFileName(); swap != "" {
+		os.Remove(swap)
+	}
+	b.recovered = false
+}
+
+// checkSwap recovers from the buffer's swap file if it is newer than the buffer's file.
+func (b *Buffer) checkSwap() {
+	b.recovered = false
+	swap, err := os.Stat(b.GetSwapFileName())
+	if err != nil {
+		return
+	}
+	if file, err := os.Stat(b.fileName); err == nil && !swap.ModTime().After(file.ModTime()) {
+		return
+	}
+	b.recoverFromSwap()
+}
+
+// recoverFromSwap loads the contents of the buffer's swap file, leaving the buffer modified.
+func (b *Buffer) recoverFromSwap() {
+	bytes, err := ioutil.ReadFile(b.GetSwapFileName())
+	if err != nil {
+		return
+	}
+	b.LoadBytes(bytes)
+	b.recovered = true
+}
+
+// GetRecovered returns true if the buffer was loaded from its swap file and hasn't been saved or reloaded since.
+func (b *Buffer) GetRecovered() bool {
+	return b.recovered
+}
+
+// buffers returns the buffers of all document windows.
+func (e *Editor) buffers() []*Buffer {
+	var buffers []*Buffer
+	seen := make(map[*Buffer]bool)
+	for _, w := range e.documentWindows {
+		if b := w.(*Window).buffer; b != nil && !seen[b] {
+			seen[b] = true
+			buffers = append(buffers, b)
+		}
+	}
+	return buffers
+}
+
+// WriteSwapFiles saves all modified buffers to their swap files.
+func (e *Editor) WriteSwapFiles() error {
+	var firstErr error
+	for _, b := range e.buffers() {
+		if err := b.WriteSwap(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// RemoveSwapFiles deletes the swap files of all buffers.
+func (e *Editor) RemoveSwapFiles() {
+	for _, b := range e.buffers() {
+		b.RemoveSwap()
+	}
+}
@@ -0,0 +1,68 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package editor
+
+import (
+	"errors"
+	"fmt"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// DefaultTheme is the name of the theme that is initially used for highlighting.
+const DefaultTheme = "default"
+
+// These are the built-in themes.
+var themes = map[string]*gott.Theme{
+	"default": {
+		Name:        "default",
+		Keyword:     0x70,
+		String:      0xe0,
+		Comment:     0xf8,
+		Number:      0x83,
+		Punctuation: 0x71,
+		Match:       0x0c,
+		Error:       0x0a,
+	},
+	"solarized": {
+		Name:        "solarized",
+		Keyword:     gott.RGB(0x85, 0x99, 0x00),
+		String:      gott.RGB(0x2a, 0xa1, 0x98),
+		Comment:     gott.RGB(0x58, 0x6e, 0x75),
+		Number:      gott.RGB(0xd3, 0x36, 0x82),
+		Punctuation: gott.RGB(0x93, 0xa1, 0xa1),
+		Match:       gott.RGB(0xb5, 0x89, 0x00),
+		Error:       gott.RGB(0xdc, 0x32, 0x2f),
+	},
+	"monochrome": {
+		Name:        "monochrome",
+		Keyword:     0xff,
+		String:      0xf9,
+		Comment:     0xf1,
+		Number:      0xff,
+		Punctuation: 0xf5,
+		Match:       0x100,
+		Error:       0xf1,
+	},
+}
+
+// Find a built-in theme by name.
+func findTheme(name string) (*gott.Theme, error) {
+	theme := themes[name]
+	if theme == nil {
+		return nil, errors.New(fmt.Sprintf("No theme exists named %s", name))
+	}
+	return theme, nil
+}
@@ -16,7 +16,9 @@ package editor
 
 import (
 	"fmt"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	gott "github.com/timburks/gott/types"
 )
@@ -24,6 +26,14 @@ import (
 // This is the number of the last window created. Use it to uniquely number windows.
 var lastWindowNumber = -1
 
+// Windows that contain other windows have this number, which never matches a window lookup.
+const containerWindowNumber = -1
+
+func nextWindowNumber() int {
+	lastWindowNumber++
+	return lastWindowNumber
+}
+
 // A Window instance manages a rectangular area onscreen.
 // A window can be a view of a buffer or a container for two other windows.
 // When a window contains text, it also has an associated cursor position.
@@ -40,13 +50,21 @@ type Window struct {
 	child1     *Window    // left/top child
 	child2     *Window    // right/bottom child
 	horizontal bool       // true if split is horizontal
+	selecting  bool       // true if the window has a selection
+	anchor     gott.Point // selection anchor; the cursor is the other end
+	lastAnchor gott.Point // ends of the most recently cleared selection
+	lastCursor gott.Point
+	hasLast    bool         // true if a selection has been cleared
+	goalColumn int          // display column that vertical motions try to return to
+	goalCursor gott.Point   // cursor after the last vertical motion
+	goalAt     int          // buffer version after the last vertical motion
+	cursors    []gott.Point // secondary cursors that receive the characters inserted at the cursor
 }
 
 func NewWindow(e gott.Editor) *Window {
-	lastWindowNumber++
 	w := &Window{}
 	w.editor = e
-	w.number = lastWindowNumber
+	w.number = nextWindowNumber()
 	w.buffer = NewBuffer()
 	return w
 }
@@ -90,6 +108,8 @@ func (w *Window) Layout(r gott.Rect) {
 	w.size = r.Size
 
 	if w.buffer != nil {
+		// keep the cursor visible when the window size changes
+		w.EnsureCursorVisible()
 		return
 	}
 	// adjust window sizes
@@ -118,10 +138,9 @@ func (w *Window) SplitVertically() (gott.Window, gott.Window) {
 	w1 := w.Copy()
 	w2 := w.Copy()
 
-	// update the window numbers
-	w.number = -1
-	lastWindowNumber++
-	w2.number = lastWindowNumber
+	// the first window keeps this window's number and the second gets a new one
+	w.number = containerWindowNumber
+	w2.number = nextWindowNumber()
 
 	w1.parent = w
 	w2.parent = w
@@ -143,10 +162,9 @@ func (w *Window) SplitHorizontally() (gott.Window, gott.Window) {
 	w1 := w.Copy()
 	w2 := w.Copy()
 
-	// update the window numbers
-	w.number = -1
-	lastWindowNumber++
-	w2.number = lastWindowNumber
+	// the first window keeps this window's number and the second gets a new one
+	w.number = containerWindowNumber
+	w2.number = nextWindowNumber()
 
 	w1.parent = w
 	w2.parent = w
@@ -195,8 +213,11 @@ func (w *Window) Close() gott.Window {
 }
 
 func (w *Window) FindWindow(number int) gott.Window {
-	if w.number == number {
-		return w
+	if w.buffer != nil {
+		if w.number == number {
+			return w
+		}
+		return nil
 	}
 	if w.child1 != nil {
 		child := w.child1.FindWindow(number)
@@ -213,6 +234,21 @@ func (w *Window) FindWindow(number int) gott.Window {
 	return nil
 }
 
+// FindWindowAtPosition returns the buffer window that contains a screen position.
+func (w *Window) FindWindowAtPosition(p gott.Point) gott.Window {
+	if p.Row < w.origin.Row || p.Row >= w.origin.Row+w.size.Rows ||
+		p.Col < w.origin.Col || p.Col >= w.origin.Col+w.size.Cols {
+		return nil
+	}
+	if w.buffer != nil {
+		return w
+	}
+	if child := w.child1.FindWindowAtPosition(p); child != nil {
+		return child
+	}
+	return w.child2.FindWindowAtPosition(p)
+}
+
 func (w *Window) GetWindowNext() gott.Window {
 	parent := w.parent
 	if parent == nil {
@@ -256,15 +292,15 @@ func (w *Window) GetChildPrevious() gott.Window {
 }
 
 // draw text in an area defined by origin and size with a specified offset into the buffer
-func (w *Window) Render(display gott.Display) {
+func (w *Window) Render(display gott.Display, focused gott.Window) {
 	if w.buffer != nil {
-		w.RenderBuffer(display)
+		w.RenderBuffer(display, focused == gott.Window(w))
 	} else {
 		if w.child1 != nil {
-			w.child1.Render(display)
+			w.child1.Render(display, focused)
 		}
 		if w.child2 != nil {
-			w.child2.Render(display)
+			w.child2.Render(display, focused)
 			if w.horizontal {
 				// Draw a vertical dividing bar
 				col := w.child2.origin.Col - 1
@@ -276,71 +312,251 @@ func (w *Window) Render(display gott.Display) {
 	}
 }
 
-func (w *Window) RenderBuffer(display gott.Display) {
+func (w *Window) RenderBuffer(display gott.Display, focused bool) {
 	w.adjustDisplayOffsetForScrolling()
 
+	// optionally dim windows that don't have focus
+	setCell := display.SetCell
+	setInfoBarCell := display.SetCell
+	if w.editor.GetDimInactive() {
+		if focused {
+			setInfoBarCell = display.SetCellReversed
+		} else {
+			setCell = display.SetCellDimmed
+			setInfoBarCell = display.SetCellDimmed
+		}
+	}
+
 	b := w.buffer
 	if !b.Highlighted {
 		switch b.languageMode {
 		case "go":
-			h := NewGoHighlighter()
+			h := NewGoHighlighter(w.editor.GetTheme())
 			h.Highlight(b)
 		}
 		b.Highlighted = true
 	}
 
+	spellChecker := w.spellChecker()
+	var diffSigns []rune
+	if e, ok := w.editor.(*Editor); ok {
+		diffSigns = e.diffSigns(b)
+	}
+	width := w.textWidth()
+	tabWidth := w.tabWidth()
 	for i := 0; i < w.size.Rows-1; i++ {
-		var line string
-		var colors []gott.Color
-		if (i + w.offset.Rows) < len(b.rows) {
-			line = b.rows[i+w.offset.Rows].GetString()
-			colors = b.rows[i+w.offset.Rows].GetColors()
-			if w.offset.Cols < len(line) {
-				line = line[w.offset.Cols:]
-				colors = colors[w.offset.Cols:]
-			} else {
-				line = ""
+		row := i + w.offset.Rows
+		if row >= len(b.rows) {
+			if width > 0 {
+				setCell(w.textLeft(), i+w.origin.Row, '~', gott.ColorWhite)
 			}
-		} else {
-			line = "~"
-			colors = make([]gott.Color, 1, 1)
-			colors[0] = gott.ColorWhite
+			continue
 		}
-		// truncate line to fit screen
-		if len(line) > w.size.Cols {
-			line = line[0:w.size.Cols]
-			colors = colors[0:w.size.Cols]
+		text := b.rows[row].GetText()
+		highlighted := w.highlightedRanges(string(text))
+		colors := b.rows[row].GetColors()
+		if spellChecker != nil {
+			colors = w.spellColors(spellChecker, b.rows[row])
 		}
-		for j, c := range line {
+		if diffSigns != nil {
+			colors = w.diffColors(diffSigns, row, colors)
+		}
+		// tabs extend to the next tab stop, and highlighted ranges are measured in bytes
+		column, offset := 0, 0
+		for col, c := range text {
+			next := column + 1
+			if c == '\t' {
+				next = column + tabWidth - column%tabWidth
+			}
 			var color gott.Color = gott.ColorWhite
-			if j < len(colors) {
-				color = colors[j]
+			if col < len(colors) {
+				color = colors[col]
+			}
+			reversed := w.inSelection(row, col) || inRanges(highlighted, offset) || w.atSecondaryCursor(row, col)
+			for ; column < next && column-w.offset.Cols < width; column++ {
+				x := column - w.offset.Cols
+				if x < 0 {
+					continue
+				}
+				ch := c
+				if c == '\t' {
+					ch = ' '
+				}
+				if reversed {
+					display.SetCellReversed(x+w.textLeft(), i+w.origin.Row, ch, color)
+				} else {
+					setCell(x+w.textLeft(), i+w.origin.Row, ch, color)
+				}
 			}
-			display.SetCell(j+w.origin.Col, i+w.origin.Row, rune(c), color)
+			if column-w.offset.Cols >= width {
+				break
+			}
+			offset += utf8.RuneLen(c)
 		}
 	}
 
+	w.renderGutter(setCell)
+
+	if focused {
+		w.renderMatchingBracket(setCell)
+	}
+
+	if w.hasMinimap() {
+		w.renderMinimap(display, setCell)
+	}
+
+	// Welcome the user until text is entered.
+	if focused && len(b.rows) == 0 && b.GetFileName() == "" {
+		w.renderSplash(setCell)
+	}
+
 	// Draw the info bar as a single line at the bottom of the buffer window.
 	infoText := w.computeInfoBarText(w.size.Cols)
 	infoRow := w.origin.Row + w.size.Rows - 1
 	for x, ch := range infoText {
-		display.SetCell(x+w.origin.Col, infoRow, rune(ch), gott.ColorWhite)
+		setInfoBarCell(x+w.origin.Col, infoRow, rune(ch), gott.ColorWhite)
+	}
+}
+
+// The splash is centered in a window that shows an empty buffer.
+var splash = []string{
+	"gott " + Version,
+	"a little text editor",
+	"",
+	"type :r filename to read a file",
+	"type :q to quit",
+}
+
+func (w *Window) renderSplash(setCell func(j int, i int, c rune, color gott.Color)) {
+	top := (w.size.Rows - 1 - len(splash)) / 2
+	for i, line := range splash {
+		left := (w.size.Cols - len(line)) / 2
+		if top+i < 0 || top+i >= w.size.Rows-1 || left < 1 {
+			// leave room for the tildes that mark empty rows
+			continue
+		}
+		for j, c := range line {
+			setCell(left+j+w.origin.Col, top+i+w.origin.Row, c, gott.ColorWhite)
+		}
 	}
 }
 
-// Compute the text to display on the info bar.
+// DefaultStatusLine is the initial format of the info bar.
+// Text before %= is left-aligned, text after it is right-aligned,
+// and the space between is filled with dots.
+const DefaultStatusLine = "%n> %f %r%= %l/%L %o %e %t "
+
+// GetInfoBarText returns the text to display on the window's info bar.
+func (w *Window) GetInfoBarText(length int) string {
+	return w.computeInfoBarText(length)
+}
+
+// Compute the text to display on the info bar by expanding the editor's
+// status line format. These placeholders are supported:
+//
+//	%f  buffer name
+//	%l  cursor line
+//	%L  number of lines
+//	%c  cursor column
+//	%p  cursor line as a percentage of the number of lines
+//	%m  "[+]" if the buffer is modified
+//	%r  "(read-only) " if the buffer is read-only
+//	%y  file type
+//	%n  window number
+//	%e  encoding
+//	%t  line ending style
+//	%o  byte offset of the cursor, if the editor shows byte offsets
+//	%=  separator between left- and right-aligned text
+//	%%  a percent sign
+//
+// The byte offset, encoding, and line ending are left out, along with a space
+// after each, if the text doesn't fit otherwise.
 func (w *Window) computeInfoBarText(length int) string {
+	format := []rune(w.editor.GetStatusLine())
+	left, right := w.expandStatusLine(format, true)
+	if utf8.RuneCountInString(left)+utf8.RuneCountInString(right) > length {
+		left, right = w.expandStatusLine(format, false)
+	}
+	// fill the space between the left and right text
+	fill := length - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if fill > 0 {
+		left += strings.Repeat(".", fill)
+	}
+	line := []rune(left + right)
+	if len(line) > length {
+		line = line[0:length]
+	}
+	return string(line)
+}
+
+// expandStatusLine returns the left- and right-aligned text of a status line format.
+func (w *Window) expandStatusLine(format []rune, details bool) (left, right string) {
 	b := w.buffer
-	finalText := fmt.Sprintf(" %d/%d ", w.cursor.Row+1, b.GetRowCount())
-	text := fmt.Sprintf("%d> %s ", w.GetIndex(), b.GetName())
-	if b.GetReadOnly() {
-		text = text + "(read-only) "
+	text := &left
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			*text += string(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'e', 't', 'o':
+			if !details || (format[i] == 'o' && !w.editor.GetShowByteOffset()) {
+				// skip the space after a detail that is left out
+				if i+1 < len(format) && format[i+1] == ' ' {
+					i++
+				}
+			} else if format[i] == 'e' {
+				*text += b.GetEncoding()
+			} else if format[i] == 't' {
+				*text += b.GetLineEnding()
+			} else {
+				*text += fmt.Sprintf("@%d", b.ByteOffset(w.cursor))
+			}
+		case 'f':
+			*text += b.GetName()
+		case 'l':
+			*text += fmt.Sprintf("%d", w.cursor.Row+1)
+		case 'L':
+			*text += fmt.Sprintf("%d", b.GetRowCount())
+		case 'c':
+			*text += fmt.Sprintf("%d", w.cursor.Col+1)
+		case 'p':
+			percent := 100
+			if b.GetRowCount() > 0 {
+				percent = (w.cursor.Row + 1) * 100 / b.GetRowCount()
+			}
+			*text += fmt.Sprintf("%d", percent)
+		case 'm':
+			if b.GetModified() {
+				*text += "[+]"
+			}
+		case 'r':
+			if b.GetReadOnly() {
+				*text += "(read-only) "
+			}
+		case 'y':
+			*text += b.GetLanguageMode()
+		case 'n':
+			*text += fmt.Sprintf("%d", w.GetIndex())
+		case '=':
+			text = &right
+		case '%':
+			*text += "%"
+		default:
+			*text += "%" + string(format[i])
+		}
 	}
-	for len(text) <= length-len(finalText)-1 {
-		text = text + "."
+	return left, right
+}
+
+// EnsureCursorVisible scrolls the window so that the cursor is onscreen.
+func (w *Window) EnsureCursorVisible() {
+	// windows without room for text can't show the cursor
+	if w.size.Rows < 2 || w.size.Cols < 1 {
+		return
 	}
-	text += finalText
-	return text
+	w.adjustDisplayOffsetForScrolling()
 }
 
 // Recompute the display offset to keep the cursor onscreen.
@@ -355,16 +571,66 @@ func (w *Window) adjustDisplayOffsetForScrolling() {
 		// scroll down
 		w.offset.Rows = w.cursor.Row - textRows + 1
 	}
-	if w.cursor.Col < w.offset.Cols {
+	column := w.displayColumn(w.cursor)
+	if column < w.offset.Cols {
 		// scroll left
-		w.offset.Cols = w.cursor.Col
+		w.offset.Cols = column
 	}
-	if w.cursor.Col-w.offset.Cols >= w.size.Cols {
+	if width := w.textWidth(); column-w.offset.Cols >= width {
 		// scroll right
-		w.offset.Cols = w.cursor.Col - w.size.Cols + 1
+		w.offset.Cols = column - width + 1
 	}
 }
 
+// tabWidth returns the distance between the tab stops that the window's buffer is displayed with.
+func (w *Window) tabWidth() int {
+	if e, ok := w.editor.(*Editor); ok {
+		return e.tabWidthOf(w.buffer)
+	}
+	return w.editor.GetTabWidth()
+}
+
+// displayColumn returns the column where the character at a position is displayed.
+// Tabs extend to the next tab stop, so characters after them are displayed further right.
+func (w *Window) displayColumn(p gott.Point) int {
+	var text []rune
+	if p.Row >= 0 && p.Row < len(w.buffer.rows) {
+		text = w.buffer.rows[p.Row].GetText()
+	}
+	tabWidth := w.tabWidth()
+	column := 0
+	for col := 0; col < p.Col; col++ {
+		if col < len(text) && text[col] == '\t' {
+			column += tabWidth - column%tabWidth
+		} else {
+			column++
+		}
+	}
+	return column
+}
+
+// columnAtDisplay returns the column of the character in a row that is displayed at a display column.
+// Columns past the end of the row are counted as if the row were extended with spaces.
+func (w *Window) columnAtDisplay(row, column int) int {
+	var text []rune
+	if row >= 0 && row < len(w.buffer.rows) {
+		text = w.buffer.rows[row].GetText()
+	}
+	tabWidth := w.tabWidth()
+	col, x := 0, 0
+	for ; col < len(text); col++ {
+		next := x + 1
+		if text[col] == '\t' {
+			next = x + tabWidth - x%tabWidth
+		}
+		if next > column {
+			return col
+		}
+		x = next
+	}
+	return col + column - x
+}
+
 func (w *Window) GetCursor() gott.Point {
 	return w.cursor
 }
@@ -373,21 +639,198 @@ func (w *Window) SetCursor(cursor gott.Point) {
 	w.cursor = cursor
 }
 
+// GetCursorDisplayColumn returns the column where the cursor is displayed in its row.
+func (w *Window) GetCursorDisplayColumn() int {
+	return w.displayColumn(w.cursor)
+}
+
 func (w *Window) SetCursorForDisplay(d gott.Display) {
 	d.SetCursor(gott.Point{
-		Col: w.cursor.Col - w.offset.Cols + w.origin.Col,
+		Col: w.displayColumn(w.cursor) - w.offset.Cols + w.textLeft(),
 		Row: w.cursor.Row - w.offset.Rows + w.origin.Row,
 	})
 }
 
+// MoveCursorToPosition moves the cursor to the text displayed at a screen position.
+// Positions outside the window are clipped to its text area.
+func (w *Window) MoveCursorToPosition(p gott.Point) {
+	w.cursor.Row = clipToRange(p.Row-w.origin.Row, 0, w.size.Rows-2) + w.offset.Rows
+	column := clipToRange(p.Col-w.textLeft(), 0, w.textWidth()-1) + w.offset.Cols
+	w.cursor.Col = w.columnAtDisplay(w.cursor.Row, column)
+	w.KeepCursorInRow()
+}
+
+// StartSelection starts a selection that extends from an anchor to the cursor.
+func (w *Window) StartSelection(anchor gott.Point) {
+	w.selecting = true
+	w.anchor = anchor
+}
+
+// ClearSelection ends a selection and remembers it so that it can be reselected.
+func (w *Window) ClearSelection() {
+	if w.selecting {
+		w.lastAnchor, w.lastCursor = w.anchor, w.cursor
+		w.hasLast = true
+	}
+	w.selecting = false
+}
+
+// ReselectSelection restores the most recently cleared selection.
+// It returns false if there is no selection to restore.
+func (w *Window) ReselectSelection() bool {
+	if !w.hasLast {
+		return false
+	}
+	// the buffer may have changed since the selection was made
+	w.cursor = w.lastCursor
+	w.KeepCursorInRow()
+	anchor := w.lastAnchor
+	if w.buffer.GetRowCount() == 0 {
+		anchor = gott.Point{}
+	} else {
+		anchor.Row = clipToRange(anchor.Row, 0, w.buffer.GetRowCount()-1)
+		anchor.Col = clipToRange(anchor.Col, 0, w.buffer.rows[anchor.Row].Length()-1)
+	}
+	w.StartSelection(anchor)
+	return true
+}
+
+// GetSelection returns the ordered ends of the selection, which includes both of them.
+func (w *Window) GetSelection() (start, end gott.Point, ok bool) {
+	if !w.selecting {
+		return start, end, false
+	}
+	start, end = w.anchor, w.cursor
+	if end.Row < start.Row || (end.Row == start.Row && end.Col < start.Col) {
+		start, end = end, start
+	}
+	return start, end, true
+}
+
+// SwapSelectionEnds moves the cursor to the anchor and the anchor to the cursor.
+func (w *Window) SwapSelectionEnds() {
+	if w.selecting {
+		w.anchor, w.cursor = w.cursor, w.anchor
+	}
+}
+
+// SetSecondaryCursors sets cursors that insert and delete characters along with the cursor.
+// Secondary cursors are ordered from top to bottom, each on its own row.
+func (w *Window) SetSecondaryCursors(cursors []gott.Point) {
+	w.cursors = append([]gott.Point(nil), cursors...)
+}
+
+// GetSecondaryCursors returns the cursors that insert and delete characters along with the cursor.
+func (w *Window) GetSecondaryCursors() []gott.Point {
+	return w.cursors
+}
+
+func (w *Window) atSecondaryCursor(row, col int) bool {
+	for _, cursor := range w.cursors {
+		if cursor.Row == row && cursor.Col == col {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Window) inSelection(row, col int) bool {
+	start, end, ok := w.GetSelection()
+	if !ok || row < start.Row || row > end.Row {
+		return false
+	}
+	if row == start.Row && col < start.Col {
+		return false
+	}
+	if row == end.Row && col > end.Col {
+		return false
+	}
+	return true
+}
+
+// YankSelection copies the selected text to the pasteboard.
+func (w *Window) YankSelection() {
+	start, end, ok := w.GetSelection()
+	if !ok {
+		return
+	}
+	pasteText := ""
+	for row := start.Row; row <= end.Row && row < w.buffer.GetRowCount(); row++ {
+		text := w.buffer.rows[row].GetText()
+		first := 0
+		if row == start.Row {
+			first = clipToRange(start.Col, 0, len(text))
+		}
+		last := len(text)
+		if row == end.Row {
+			last = clipToRange(end.Col+1, first, len(text))
+		}
+		if row > start.Row {
+			pasteText += "\n"
+		}
+		pasteText += string(text[first:last])
+	}
+	w.editor.SetPasteBoard(pasteText, gott.PasteAtCursor)
+}
+
+// Searches ignore case if the ignorecase option is set,
+// unless the smartcase option is also set and the text contains uppercase letters.
+func (w *Window) newSearch(text string) *search {
+	ignoreCase := w.editor.GetIgnoreCase()
+	if ignoreCase && w.editor.GetSmartCase() && strings.IndexFunc(text, unicode.IsUpper) != -1 {
+		ignoreCase = false
+	}
+	return &search{text: text, ignoreCase: ignoreCase}
+}
+
 func (w *Window) PerformSearchForward(text string) {
+	w.performSearchForward(w.newSearch(text))
+}
+
+func (w *Window) PerformSearchBackward(text string) {
+	w.performSearchBackward(w.newSearch(text))
+}
+
+func (w *Window) PerformWholeWordSearchForward(text string) {
+	s := w.newSearch(text)
+	s.wholeWord = true
+	w.performSearchForward(s)
+}
+
+func (w *Window) PerformWholeWordSearchBackward(text string) {
+	s := w.newSearch(text)
+	s.wholeWord = true
+	w.performSearchBackward(s)
+}
+
+// GetWordAtCursor returns the word that contains the cursor, or an empty string if the cursor isn't on a word.
+func (w *Window) GetWordAtCursor() string {
+	if w.cursor.Row >= w.buffer.GetRowCount() {
+		return ""
+	}
+	text := w.buffer.rows[w.cursor.Row].GetText()
+	if w.cursor.Col >= len(text) || !isAlphaNumeric(text[w.cursor.Col]) {
+		return ""
+	}
+	start := w.cursor.Col
+	for start > 0 && isAlphaNumeric(text[start-1]) {
+		start--
+	}
+	end := w.cursor.Col
+	for end < len(text) && isAlphaNumeric(text[end]) {
+		end++
+	}
+	return string(text[start:end])
+}
+
+func (w *Window) performSearchForward(s *search) {
 	if w.buffer.GetRowCount() == 0 {
 		return
 	}
 	row := w.cursor.Row
 	col := w.cursor.Col
 	for {
-		position := w.buffer.FirstPositionInRowAfterCol(row, col, text)
+		position := w.buffer.FirstPositionInRowAfterCol(row, col, s)
 		if position != -1 {
 			// found it
 			w.cursor.Row = row
@@ -406,14 +849,14 @@ func (w *Window) PerformSearchForward(text string) {
 	}
 }
 
-func (w *Window) PerformSearchBackward(text string) {
+func (w *Window) performSearchBackward(s *search) {
 	if w.buffer.GetRowCount() == 0 {
 		return
 	}
 	row := w.cursor.Row
 	col := w.cursor.Col
 	for {
-		position := w.buffer.LastPositionInRowBeforeCol(row, col, text)
+		position := w.buffer.LastPositionInRowBeforeCol(row, col, s)
 		if position != -1 {
 			// found it
 			w.cursor.Row = row
@@ -437,6 +880,12 @@ func (w *Window) PerformSearchBackward(text string) {
 }
 
 func (w *Window) MoveCursor(direction int, multiplier int) {
+	// vertical motions return to the column where they started,
+	// until the cursor is moved another way or the buffer changes
+	vertical := direction == gott.MoveUp || direction == gott.MoveDown
+	if vertical && (w.cursor != w.goalCursor || w.buffer.version != w.goalAt) {
+		w.goalColumn = w.displayColumn(w.cursor)
+	}
 	for i := 0; i < multiplier; i++ {
 		switch direction {
 		case gott.MoveLeft:
@@ -445,8 +894,7 @@ func (w *Window) MoveCursor(direction int, multiplier int) {
 			}
 		case gott.MoveRight:
 			if w.cursor.Row < w.buffer.GetRowCount() {
-				rowLength := w.buffer.GetRowLength(w.cursor.Row)
-				if w.cursor.Col < rowLength-1 {
+				if w.cursor.Col < w.lastColumn(w.cursor.Row) {
 					w.cursor.Col++
 				}
 			}
@@ -459,17 +907,39 @@ func (w *Window) MoveCursor(direction int, multiplier int) {
 				w.cursor.Row++
 			}
 		}
+		if vertical {
+			w.cursor.Col = w.columnAtDisplay(w.cursor.Row, w.goalColumn)
+		}
 		// don't go past the end of the current line
 		if w.cursor.Row < w.buffer.GetRowCount() {
-			rowLength := w.buffer.GetRowLength(w.cursor.Row)
-			if w.cursor.Col > rowLength-1 {
-				w.cursor.Col = rowLength - 1
-				if w.cursor.Col < 0 {
-					w.cursor.Col = 0
-				}
+			if last := w.lastColumn(w.cursor.Row); w.cursor.Col > last {
+				w.cursor.Col = last
 			}
 		}
 	}
+	if vertical {
+		w.goalCursor = w.cursor
+		w.goalAt = w.buffer.version
+	}
+}
+
+// lastColumn returns the last column that the cursor can be on in a row in edit mode.
+// With virtual edit, that is just past the last character.
+func (w *Window) lastColumn(row int) int {
+	last := w.buffer.GetRowLength(row) - 1
+	if w.editor.GetVirtualEdit() {
+		last++
+	}
+	if last < 0 {
+		last = 0
+	}
+	return last
+}
+
+// MoveCursorDisplayLine moves the cursor up or down by lines as they are displayed.
+// Rows aren't wrapped, so each row is displayed on a single line.
+func (w *Window) MoveCursorDisplayLine(direction int, multiplier int) {
+	w.MoveCursor(direction, multiplier)
 }
 
 func (w *Window) MoveCursorForward() int {
@@ -522,10 +992,7 @@ func (w *Window) MoveToBeginningOfLine() {
 func (w *Window) MoveToEndOfLine() {
 	w.cursor.Col = 0
 	if w.cursor.Row < w.buffer.GetRowCount() {
-		w.cursor.Col = w.buffer.GetRowLength(w.cursor.Row) - 1
-		if w.cursor.Col < 0 {
-			w.cursor.Col = 0
-		}
+		w.cursor.Col = w.lastColumn(w.cursor.Row)
 	}
 }
 
@@ -584,8 +1051,8 @@ func (w *Window) moveCursorToNextWord() {
 
 func (w *Window) MoveForwardToFirstNonSpace() {
 	c := w.buffer.GetCharacterAtCursor(w.cursor)
-	if c == ' ' { // if we're on a space, move to first non-space
-		for c == ' ' {
+	if c == ' ' || c == '\t' { // if we're on a space, move to first non-space
+		for c == ' ' || c == '\t' {
 			if w.MoveCursorForward() != gott.AtNextCharacter {
 				return
 			}
@@ -698,6 +1165,11 @@ func (w *Window) HalfPageUp(multiplier int) {
 	}
 }
 
+// CenterCursor scrolls the window so that the cursor row is in the middle of it, if possible.
+func (w *Window) CenterCursor() {
+	w.offset.Rows = clipToRange(w.cursor.Row-(w.size.Rows-1)/2, 0, w.cursor.Row)
+}
+
 func (w *Window) HalfPageDown(multiplier int) {
 	// move to the bottom of the screen
 	w.cursor.Row = min(
@@ -713,7 +1185,7 @@ func (w *Window) ReverseCaseCharactersAtCursor(multiplier int) {
 	if w.buffer.GetRowCount() == 0 {
 		return
 	}
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	row := w.buffer.rows[w.cursor.Row]
 	for i := 0; i < multiplier; i++ {
 		c := row.GetText()[w.cursor.Col]
@@ -735,6 +1207,8 @@ func (w *Window) InsertChar(c rune) {
 		insert.AddCharacter(c)
 	}
 	if c == '\n' {
+		// secondary cursors only edit their own rows
+		w.cursors = nil
 		w.InsertRow()
 		w.cursor.Row++
 		w.cursor.Col = 0
@@ -746,10 +1220,15 @@ func (w *Window) InsertChar(c rune) {
 	}
 	w.buffer.InsertCharacter(w.cursor.Row, w.cursor.Col, c)
 	w.cursor.Col += 1
+	// work from the bottom up so that the positions of other cursors stay valid
+	for i := len(w.cursors) - 1; i >= 0; i-- {
+		w.buffer.InsertCharacter(w.cursors[i].Row, w.cursors[i].Col, c)
+		w.cursors[i].Col++
+	}
 }
 
 func (w *Window) InsertRow() {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	if w.cursor.Row >= w.buffer.GetRowCount() {
 		// we should never get here
 		w.AppendBlankRow()
@@ -774,11 +1253,17 @@ func (w *Window) BackspaceChar() rune {
 	if insert.Length() == 0 {
 		return rune(0)
 	}
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	insert.DeleteCharacter()
 	if w.cursor.Col > 0 {
 		c := w.buffer.rows[w.cursor.Row].DeleteChar(w.cursor.Col - 1)
 		w.cursor.Col--
+		for i := len(w.cursors) - 1; i >= 0; i-- {
+			if w.cursors[i].Col > 0 {
+				w.buffer.rows[w.cursors[i].Row].DeleteChar(w.cursors[i].Col - 1)
+				w.cursors[i].Col--
+			}
+		}
 		return c
 	} else if w.cursor.Row > 0 {
 		// remove the current row and join it with the previous one
@@ -795,14 +1280,17 @@ func (w *Window) BackspaceChar() rune {
 	}
 }
 
+// JoinRow joins up to multiplier following rows with the cursor row,
+// stopping at the end of the buffer. It returns the position of each join
+// so that the joins can be undone by inserting newlines.
 func (w *Window) JoinRow(multiplier int) []gott.Point {
-	if w.buffer.GetRowCount() == 0 {
+	if w.cursor.Row+1 >= w.buffer.GetRowCount() {
 		return nil
 	}
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	// remove the next row and join it with this one
 	insertions := make([]gott.Point, 0)
-	for i := 0; i < multiplier; i++ {
+	for i := 0; i < multiplier && w.cursor.Row+1 < len(w.buffer.rows); i++ {
 		oldRowText := w.buffer.rows[w.cursor.Row+1].GetText()
 		var newCursor gott.Point
 		newCursor.Col = len(w.buffer.rows[w.cursor.Row].GetText())
@@ -840,9 +1328,8 @@ func (w *Window) KeepCursorInRow() {
 		if w.cursor.Row < 0 {
 			w.cursor.Row = 0
 		}
-		lastIndexInRow := w.buffer.rows[w.cursor.Row].Length() - 1
-		if w.cursor.Col > lastIndexInRow {
-			w.cursor.Col = lastIndexInRow
+		if last := w.lastColumn(w.cursor.Row); w.cursor.Col > last {
+			w.cursor.Col = last
 		}
 		if w.cursor.Col < 0 {
 			w.cursor.Col = 0
@@ -855,7 +1342,7 @@ func (w *Window) AppendBlankRow() {
 }
 
 func (w *Window) InsertLineAboveCursor() {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	w.AppendBlankRow()
 	copy(w.buffer.rows[w.cursor.Row+1:], w.buffer.rows[w.cursor.Row:])
 	w.buffer.rows[w.cursor.Row] = NewRow("")
@@ -863,7 +1350,7 @@ func (w *Window) InsertLineAboveCursor() {
 }
 
 func (w *Window) InsertLineBelowCursor() {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	w.AppendBlankRow()
 	copy(w.buffer.rows[w.cursor.Row+2:], w.buffer.rows[w.cursor.Row+1:])
 	w.buffer.rows[w.cursor.Row+1] = NewRow("")
@@ -881,12 +1368,23 @@ func (w *Window) MoveCursorToStartOfLineBelowCursor() {
 }
 
 func (w *Window) ReplaceCharacterAtCursor(cursor gott.Point, c rune) rune {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	return w.buffer.rows[cursor.Row].ReplaceChar(cursor.Col, c)
 }
 
+// ReplaceRow replaces the text of a row and returns the previous text.
+func (w *Window) ReplaceRow(row int, text string) string {
+	if row < 0 || row >= w.buffer.GetRowCount() {
+		return ""
+	}
+	defer w.buffer.markModified()
+	old := string(w.buffer.rows[row].GetText())
+	w.buffer.rows[row].SetText([]rune(text))
+	return old
+}
+
 func (w *Window) DeleteRowsAtCursor(multiplier int) string {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	deletedText := ""
 	for i := 0; i < multiplier; i++ {
 		row := w.cursor.Row
@@ -905,7 +1403,7 @@ func (w *Window) DeleteRowsAtCursor(multiplier int) string {
 }
 
 func kindOfWord(c rune) int {
-	if c == ' ' {
+	if c == ' ' || c == '\t' {
 		return gott.WordSpace
 	} else if isAlphaNumeric(c) {
 		return gott.WordAlphaNumeric
@@ -915,7 +1413,7 @@ func kindOfWord(c rune) int {
 }
 
 func (w *Window) DeleteWordsAtCursor(multiplier int) string {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	deletedText := ""
 	for i := 0; i < multiplier; i++ {
 		if w.buffer.GetRowCount() == 0 {
@@ -938,7 +1436,7 @@ func (w *Window) DeleteWordsAtCursor(multiplier int) string {
 				if w.cursor.Col > w.buffer.rows[w.cursor.Row].Length()-1 {
 					break
 				}
- 				if c == ' ' {
+				if c == ' ' || c == '\t' {
 					break
 				}
 				c = w.buffer.rows[w.cursor.Row].DeleteChar(w.cursor.Col)
@@ -956,7 +1454,7 @@ func (w *Window) DeleteWordsAtCursor(multiplier int) string {
 }
 
 func (w *Window) DeleteCharactersAtCursor(multiplier int, undo bool, finallyDeleteRow bool) string {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	deletedText := w.buffer.DeleteCharacters(w.cursor.Row, w.cursor.Col, multiplier, undo)
 	if w.cursor.Col > w.buffer.rows[w.cursor.Row].Length()-1 {
 		w.cursor.Col--
@@ -970,8 +1468,15 @@ func (w *Window) DeleteCharactersAtCursor(multiplier int, undo bool, finallyDele
 	return deletedText
 }
 
+// DeleteRange deletes the text that starts at start and ends just before end.
+// Line breaks in the range are deleted, joining the rows they separate.
+func (w *Window) DeleteRange(start, end gott.Point, finallyDeleteRow bool) string {
+	w.cursor = start
+	return w.DeleteCharactersAtCursor(w.buffer.characterCount(start, end), true, finallyDeleteRow)
+}
+
 func (w *Window) ChangeWordAtCursor(multiplier int, text string) (string, int) {
-	w.buffer.Highlighted = false
+	defer w.buffer.markModified()
 	// delete the next N words and enter insert mode.
 	deletedText := w.DeleteWordsAtCursor(multiplier)
 
@@ -992,8 +1497,24 @@ func (w *Window) ChangeWordAtCursor(multiplier int, text string) (string, int) {
 	return deletedText, mode
 }
 
-func (w *Window) InsertText(text string, position int) (gott.Point, int) {
-	w.buffer.Highlighted = false
+// ChangeRange deletes the text from start up to end and enters insert mode.
+// If text is not empty, it is inserted instead and the editor stays in edit mode.
+// It returns the deleted text and the end of any inserted text.
+func (w *Window) ChangeRange(start, end gott.Point, text string) (deletedText string, insertEnd gott.Point, mode int) {
+	deletedText = w.DeleteRange(start, end, false)
+	// unlike other deletions, the cursor stays where the text was deleted
+	w.cursor = start
+	if text != "" {
+		_, insertEnd, _ = w.InsertText(text, gott.InsertAtCursor)
+		return deletedText, insertEnd, gott.ModeEdit
+	}
+	return deletedText, start, gott.ModeInsert
+}
+
+// InsertText inserts text at a position relative to the cursor.
+// It returns the start and end of the inserted text and the mode that should follow.
+func (w *Window) InsertText(text string, position int) (start, end gott.Point, mode int) {
+	defer w.buffer.markModified()
 	if w.buffer.GetRowCount() == 0 {
 		w.AppendBlankRow()
 	}
@@ -1012,20 +1533,19 @@ func (w *Window) InsertText(text string, position int) (gott.Point, int) {
 	case gott.InsertAtNewLineAboveCursor:
 		w.InsertLineAboveCursor()
 	}
-	var mode int
+	start = w.cursor
 	if text != "" {
-		r := w.cursor.Row
-		c := w.cursor.Col
 		for _, c := range text {
 			w.InsertChar(c)
 		}
-		w.cursor.Row = r
-		w.cursor.Col = c
+		end = w.cursor
+		w.cursor = start
 		mode = gott.ModeEdit
 	} else {
+		end = start
 		mode = gott.ModeInsert
 	}
-	return w.cursor, mode
+	return start, end, mode
 }
 
 func min(a, b int) int {
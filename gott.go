@@ -22,12 +22,15 @@ import (
 	"github.com/timburks/gott/commander"
 	"github.com/timburks/gott/editor"
 	"github.com/timburks/gott/screen"
+	gott "github.com/timburks/gott/types"
 )
 
 func main() {
 
 	filenames := make([]string, 0)
 	var script string
+	var allowShell bool
+	useTcell := os.Getenv("GOTT_DISPLAY") == "tcell"
 
 	for i := 1; i < len(os.Args); i++ {
 		argi := os.Args[i]
@@ -40,6 +43,10 @@ func main() {
 				log.Output(1, "No file specified for --eval option")
 				return
 			}
+		case "--tcell": // display with tcell instead of termbox
+			useTcell = true
+		case "--allow-shell": // allow scripts to run shell commands
+			allowShell = true
 		default:
 			// If a file was specified on the command line, read it.
 			filenames = append(filenames, os.Args[i])
@@ -48,9 +55,16 @@ func main() {
 
 	// The editor manages all text manipulation.
 	e := editor.NewEditor()
+	if err := e.LoadRecentFiles(editor.DefaultRecentFiles); err != nil {
+		log.Output(1, err.Error())
+	}
+	if err := e.LoadCursorPositions(editor.DefaultCursorPositions); err != nil {
+		log.Output(1, err.Error())
+	}
 
 	// The commander converts user inputs into commands for the editor.
 	c := commander.NewCommander(e)
+	c.SetAllowShell(allowShell)
 
 	if len(filenames) == 0 {
 		// todo: create an empty buffer
@@ -82,8 +96,19 @@ func main() {
 		c.ParseEvalFile(script)
 	} else {
 		// Create a screen to manage display.
-		s := screen.NewScreen(e)
+		var s gott.Display
+		if useTcell {
+			t, err := screen.NewTcellScreen(e)
+			if err != nil {
+				log.Output(1, err.Error())
+				return
+			}
+			s = t
+		} else {
+			s = screen.NewScreen(e)
+		}
 		defer s.Close()
+		c.SetDisplay(s)
 
 		// Open a log file.
 		f, err := os.OpenFile(
@@ -99,10 +124,17 @@ func main() {
 		// Run the main event loop.
 		for c.IsRunning() {
 			s.Render(e, c)
-			err = c.ProcessEvent(s.GetNextEvent())
+			event := c.NextEvent(s)
+			if event == nil {
+				// swap files were written or idle hooks were run while input paused
+				continue
+			}
+			err = c.ProcessEvent(event)
 			if err != nil {
 				log.Output(1, err.Error())
 			}
 		}
+		e.SaveCursorPositions()
+		e.RemoveSwapFiles()
 	}
 }
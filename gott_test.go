@@ -15,10 +15,18 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/steelseries/golisp"
+	"github.com/timburks/gott/commander"
+	"github.com/timburks/gott/display"
 	"github.com/timburks/gott/editor"
 	"github.com/timburks/gott/operations"
 	gott "github.com/timburks/gott/types"
@@ -45,6 +53,18 @@ func final(t *testing.T, e gott.Editor) {
 	}
 }
 
+// send characters to a commander as key events
+func typeKeys(c *commander.Commander, text string) {
+	for _, ch := range text {
+		c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ch})
+	}
+}
+
+// send a special key to a commander
+func pressKey(c *commander.Commander, key gott.Key) {
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Key: key})
+}
+
 // read and write a file without changing it
 func TestReadWriteInvariance(t *testing.T) {
 	e := setup(t)
@@ -151,6 +171,86 @@ func TestInsert(t *testing.T) {
 	final(t, e)
 }
 
+func TestInsertMultipleLines(t *testing.T) {
+	e := setup(t)
+	b := e.GetActiveWindow().GetBuffer()
+	originalRowCount := b.GetRowCount()
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	// the inserted text spans rows and contains multibyte characters
+	e.Perform(&operations.Insert{Position: gott.InsertAtCursor, Text: "très\nnouvelle\n—"}, 1)
+	if rowCount := b.GetRowCount(); rowCount != originalRowCount+2 {
+		t.Errorf("Invalid row count after insertion: %d", rowCount)
+	}
+	if text := b.TextFromPosition(5, 0); !strings.HasPrefix(text, "—score and seven") {
+		t.Errorf("Unexpected text after insertion: '%s'", text)
+	}
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestUndoMultibyteChanges(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	for _, sample := range []struct {
+		Text   string
+		Cursor gott.Point
+		Keys   string
+	}{
+		{"abc def ghi\n", gott.Point{Row: 0, Col: 4}, "cwhéllo"},
+		{"héé\nxyz\nlast\n", gott.Point{Row: 0, Col: 0}, "yyp"},
+		{"héé\nxyz\nlast\n", gott.Point{Row: 0, Col: 0}, "yy2p"},
+	} {
+		e.LoadBytes([]byte(sample.Text))
+		e.SetCursor(sample.Cursor)
+		typeKeys(c, sample.Keys)
+		pressKey(c, gott.KeyEsc)
+		typeKeys(c, "u")
+		if text := string(e.Bytes()); text != sample.Text {
+			t.Errorf("Unexpected text after %s and undo: %q", sample.Keys, text)
+		}
+	}
+}
+
+func TestInsertWithMultiplier(t *testing.T) {
+	e := setup(t)
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	// insert interactively with a multiplier, as "3i" would
+	e.Perform(&operations.Insert{Position: gott.InsertAtCursor}, 3)
+	for _, c := range "ab " {
+		e.InsertChar(c)
+	}
+	e.CloseInsert()
+	expected := "ab ab ab Four score and seven years ago our fathers brought forth on this"
+	if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(3, 0); sample != expected {
+		t.Errorf("Unexpected sample after insertion: '%s'", sample)
+	}
+	// a single undo removes all copies
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestInsertNewLinesWithMultiplier(t *testing.T) {
+	e := setup(t)
+	originalRowCount := e.GetActiveWindow().GetBuffer().GetRowCount()
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	// open new lines interactively with a multiplier, as "3o" would
+	e.Perform(&operations.Insert{Position: gott.InsertAtNewLineBelowCursor}, 3)
+	for _, c := range "xyz" {
+		e.InsertChar(c)
+	}
+	e.CloseInsert()
+	if rowCount := e.GetActiveWindow().GetBuffer().GetRowCount(); rowCount != originalRowCount+3 {
+		t.Errorf("Invalid row count after insertion: %d", rowCount)
+	}
+	for row := 4; row < 7; row++ {
+		if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(row, 0); sample != "xyz" {
+			t.Errorf("Unexpected sample after insertion in row %d: '%s'", row, sample)
+		}
+	}
+	e.PerformUndo()
+	final(t, e)
+}
+
 func TestReverseCase(t *testing.T) {
 	e := setup(t)
 	e.SetCursor(gott.Point{Row: 0, Col: 1})
@@ -225,6 +325,28 @@ func TestJoinRow(t *testing.T) {
 	final(t, e)
 }
 
+func TestJoinRowPastEnd(t *testing.T) {
+	e := setup(t)
+	buffer := e.GetActiveWindow().GetBuffer()
+	rowCount := buffer.GetRowCount()
+	e.SetCursor(gott.Point{Row: rowCount - 4, Col: 0})
+	// join more rows than remain below the cursor
+	e.Perform(&operations.JoinLine{}, 10)
+	if count := buffer.GetRowCount(); count != rowCount-3 {
+		t.Errorf("Unexpected row count after join: %d", count)
+	}
+	// joining the last row does nothing
+	e.Perform(&operations.JoinLine{}, 1)
+	if count := buffer.GetRowCount(); count != rowCount-3 {
+		t.Errorf("Unexpected row count after joining the last row: %d", count)
+	}
+	e.PerformUndo()
+	if count := buffer.GetRowCount(); count != rowCount {
+		t.Errorf("Unexpected row count after undo: %d", count)
+	}
+	final(t, e)
+}
+
 func TestChangeWord(t *testing.T) {
 	e := setup(t)
 	e.SetCursor(gott.Point{Row: 3, Col: 0})
@@ -289,3 +411,3702 @@ func TestSearchBackward(t *testing.T) {
 	}
 	final(t, e)
 }
+
+func TestInsertTab(t *testing.T) {
+	original := "Four score and seven years ago our fathers brought forth on this"
+	cases := []struct {
+		TabWidth int
+		Col      int
+		Spaces   int
+	}{
+		{8, 0, 8},
+		{8, 3, 5},
+		{8, 8, 8},
+		{4, 0, 4},
+		{4, 5, 3},
+		{4, 7, 1},
+	}
+	for _, tc := range cases {
+		e := setup(t)
+		e.SetTabWidth(tc.TabWidth)
+		e.SetCursor(gott.Point{Row: 3, Col: tc.Col})
+		c := commander.NewCommander(e)
+		typeKeys(c, "i")
+		pressKey(c, gott.KeyTab)
+		pressKey(c, gott.KeyEsc)
+		expected := original[0:tc.Col] + strings.Repeat(" ", tc.Spaces) + original[tc.Col:]
+		if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(3, 0); sample != expected {
+			t.Errorf("Unexpected sample after tab with width %d at column %d: '%s'",
+				tc.TabWidth, tc.Col, sample)
+		}
+		e.PerformUndo()
+		final(t, e)
+	}
+}
+
+func TestInsertLiteralTab(t *testing.T) {
+	e := setup(t)
+	e.SetLiteralTabs(true)
+	e.SetCursor(gott.Point{Row: 3, Col: 4})
+	c := commander.NewCommander(e)
+	typeKeys(c, "i")
+	pressKey(c, gott.KeyTab)
+	pressKey(c, gott.KeyEsc)
+	expected := "Four\t score and seven years ago our fathers brought forth on this"
+	if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(3, 0); sample != expected {
+		t.Errorf("Unexpected sample after literal tab: '%s'", sample)
+	}
+	// the cursor is displayed after the tab, and vertical motions keep its display column
+	if column := e.GetActiveWindow().GetCursorDisplayColumn(); column != 8 {
+		t.Errorf("Unexpected display column after a literal tab: %d", column)
+	}
+	typeKeys(c, "j")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 4, Col: 8}) {
+		t.Errorf("Unexpected cursor after moving down from a tab: %+v", cursor)
+	}
+	typeKeys(c, "k")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 5}) {
+		t.Errorf("Unexpected cursor after moving up to a tab: %+v", cursor)
+	}
+	// tabs are displayed up to the next tab stop, and clicks find the character that is displayed
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	d.Render(e, c)
+	if text := d.GetRowText(3); text != "Four     score and seven years ago our f" {
+		t.Errorf("Unexpected display of a literal tab: %q", text)
+	}
+	e.GetActiveWindow().MoveCursorToPosition(gott.Point{Row: 3, Col: 6})
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 4}) {
+		t.Errorf("Unexpected cursor after clicking a tab: %+v", cursor)
+	}
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	// spaces inserted after a tab extend to the next tab stop
+	e.SetLiteralTabs(false)
+	typeKeys(c, "i")
+	pressKey(c, gott.KeyTab)
+	pressKey(c, gott.KeyEsc)
+	expected = "Four\t         score and seven years ago our fathers brought forth on this"
+	if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(3, 0); sample != expected {
+		t.Errorf("Unexpected sample after a tab following a literal tab: '%s'", sample)
+	}
+	e.PerformUndo()
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestInsertBacktab(t *testing.T) {
+	e := setup(t)
+	e.SetTabWidth(4)
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	c := commander.NewCommander(e)
+	// open a line, over-indent it, and then dedent
+	typeKeys(c, "o")
+	pressKey(c, gott.KeyTab)
+	pressKey(c, gott.KeyTab)
+	pressKey(c, gott.KeyTab)
+	typeKeys(c, "  ")
+	pressKey(c, gott.KeyBacktab)
+	typeKeys(c, "x")
+	expected := "            x"
+	if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(4, 0); sample != expected {
+		t.Errorf("Unexpected sample after first backtab: '%s'", sample)
+	}
+	pressKey(c, gott.KeyBackspace2)
+	pressKey(c, gott.KeyBacktab)
+	pressKey(c, gott.KeyBacktab)
+	pressKey(c, gott.KeyBacktab)
+	pressKey(c, gott.KeyBacktab)
+	typeKeys(c, "y")
+	pressKey(c, gott.KeyEsc)
+	expected = "y"
+	if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(4, 0); sample != expected {
+		t.Errorf("Unexpected sample after more backtabs: '%s'", sample)
+	}
+	e.PerformUndo()
+	final(t, e)
+
+	// tab stops are found from display columns, so tabs earlier in a row are counted in full
+	e = setup(t)
+	e.SetTabWidth(8)
+	e.SetLiteralTabs(true)
+	e.SetCursor(gott.Point{Row: 3, Col: 4})
+	c = commander.NewCommander(e)
+	typeKeys(c, "i")
+	pressKey(c, gott.KeyTab)
+	typeKeys(c, strings.Repeat(" ", 10))
+	pressKey(c, gott.KeyBacktab)
+	typeKeys(c, "x")
+	pressKey(c, gott.KeyEsc)
+	expected = "Four\t        x score and seven years ago our fathers brought forth on this"
+	if sample := e.GetActiveWindow().GetBuffer().TextFromPosition(3, 0); sample != expected {
+		t.Errorf("Unexpected sample after a backtab following a tab: '%s'", sample)
+	}
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestWriteCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatalf("Temp directory creation failed: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notes.txt")
+	ioutil.WriteFile(path, []byte("one\ntwo\n"), 0644)
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(path); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	typeKeys(c, "dd:w "+filepath.Join(dir, "copy.txt"))
+	pressKey(c, gott.KeyEnter)
+	// writing a copy leaves the buffer modified
+	if !b.GetModified() {
+		t.Errorf("Expected the buffer to be modified after writing a copy")
+	}
+	typeKeys(c, ":w")
+	pressKey(c, gott.KeyEnter)
+	if b.GetModified() {
+		t.Errorf("Expected the buffer to be saved after writing its file")
+	}
+}
+
+func TestInfoBarText(t *testing.T) {
+	e := setup(t)
+	e.SetCursor(gott.Point{Row: 18, Col: 4})
+	w := e.GetActiveWindow()
+	prefix := fmt.Sprintf("%d> %s ", w.GetNumber(), source)
+	cases := []struct {
+		Format   string
+		Length   int
+		Expected string
+	}{
+		{editor.DefaultStatusLine, 50, prefix + strings.Repeat(".", 50-len(prefix)-16) + " 19/38 UTF-8 LF "},
+		{editor.DefaultStatusLine, 42, prefix + strings.Repeat(".", 42-len(prefix)-7) + " 19/38 "},
+		{"%f:%l:%c", 40, source + ":19:5........"},
+		{"%l/%L %p%% %y%m", 20, "19/38 50% txt......."},
+		{"[%c]%=%L", 12, "[5].......38"},
+		{"%f%=%l", 10, "test/getty"},
+	}
+	for _, tc := range cases {
+		e.SetStatusLine(tc.Format)
+		if text := w.GetInfoBarText(tc.Length); text != tc.Expected {
+			t.Errorf("Unexpected info bar text for '%s': '%s' expected '%s'", tc.Format, text, tc.Expected)
+		}
+	}
+	// modifications are reported
+	e.SetStatusLine("%m")
+	e.Perform(&operations.DeleteCharacter{}, 1)
+	if text := w.GetInfoBarText(5); text != "[+].." {
+		t.Errorf("Unexpected info bar text for modified buffer: '%s'", text)
+	}
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestColorRGB(t *testing.T) {
+	c := gott.RGB(0x12, 0x34, 0x56)
+	if !c.IsRGB() {
+		t.Errorf("RGB color not recognized: %x", c)
+	}
+	if r, g, b := c.RGB(); r != 0x12 || g != 0x34 || b != 0x56 {
+		t.Errorf("Unexpected components of RGB color: %x %x %x", r, g, b)
+	}
+	if r, g, b := gott.Color(gott.ColorWhite).RGB(); r != 192 || g != 192 || b != 192 {
+		t.Errorf("Unexpected components of palette color: %d %d %d", r, g, b)
+	}
+	// RGB colors fall back to the closest palette color
+	if indexed := gott.RGB(0xff, 0x00, 0x00).Indexed(); indexed != 197 {
+		t.Errorf("Unexpected palette color for red: %d", indexed)
+	}
+	if indexed := gott.RGB(0x30, 0x30, 0x31).Indexed(); indexed != 237 {
+		t.Errorf("Unexpected palette color for gray: %d", indexed)
+	}
+	// palette colors round-trip through RGB
+	for i := 17; i <= 256; i++ {
+		c := gott.Color(i)
+		if indexed := gott.RGB(c.RGB()).Indexed(); indexed != c {
+			t.Errorf("Palette color %d converted to %d", c, indexed)
+		}
+	}
+}
+
+func TestThemes(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.go")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("package main\n\nfunc main() {\n}\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Errorf("Read failed: %+v", err)
+	}
+	e.SetSize(gott.Size{Rows: 10, Cols: 40})
+	e.LayoutWindows()
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	keyword := gott.Point{Row: 2, Col: 0}
+
+	e.RenderWindows(d)
+	if color := d.GetCell(keyword).Color; color != e.GetTheme().Keyword || e.GetTheme().Name != "default" {
+		t.Errorf("Unexpected keyword color for default theme: %x", color)
+	}
+	defaultColor := d.GetCell(keyword).Color
+
+	if err := e.SetTheme("solarized"); err != nil {
+		t.Errorf("Theme selection failed: %+v", err)
+	}
+	e.RenderWindows(d)
+	if color := d.GetCell(keyword).Color; color != e.GetTheme().Keyword || color == defaultColor {
+		t.Errorf("Unexpected keyword color for solarized theme: %x", color)
+	}
+
+	if err := e.SetTheme("unknown"); err == nil {
+		t.Errorf("Selecting an unknown theme didn't fail")
+	}
+}
+
+func TestDimInactiveWindows(t *testing.T) {
+	e := setup(t)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+	e.SplitWindowVertically()
+	e.SplitWindowHorizontally()
+	// the focused window is the top left one
+	top := gott.Point{Row: 0, Col: 0}
+	topInfoBar := gott.Point{Row: 9, Col: 0}
+	right := gott.Point{Row: 0, Col: 41}
+	bottom := gott.Point{Row: 10, Col: 0}
+
+	d := display.NewDisplay(gott.Size{Rows: 20, Cols: 80})
+	e.RenderWindows(d)
+	for _, p := range []gott.Point{top, topInfoBar, right, bottom} {
+		if d.GetCell(p).Dimmed || d.GetCell(p).Reversed {
+			t.Errorf("Unexpected styling at %+v when dimming is off", p)
+		}
+	}
+
+	e.SetDimInactive(true)
+	e.RenderWindows(d)
+	if d.GetCell(top).Dimmed || !d.GetCell(topInfoBar).Reversed {
+		t.Errorf("Focused window wasn't rendered with focus")
+	}
+	if !d.GetCell(right).Dimmed || !d.GetCell(bottom).Dimmed {
+		t.Errorf("Unfocused windows weren't dimmed")
+	}
+
+	// moving focus moves the highlighting
+	e.SelectWindowNext()
+	e.RenderWindows(d)
+	if !d.GetCell(top).Dimmed || d.GetCell(right).Dimmed || !d.GetCell(bottom).Dimmed {
+		t.Errorf("Focus change wasn't rendered")
+	}
+}
+
+func TestRender(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	d.Render(e, c)
+	if text := d.GetRowText(0); text != "THE GETTYSBURG ADDRESS:" {
+		t.Errorf("Unexpected text in first row: %q", text)
+	}
+	// rows are truncated to the width of the display
+	if text := d.GetRowText(3); text != "Four score and seven years ago our fathe" {
+		t.Errorf("Unexpected text in fourth row: %q", text)
+	}
+	if cursor := d.GetCursor(); cursor != (gott.Point{Row: 3, Col: 5}) {
+		t.Errorf("Unexpected cursor position: %+v", cursor)
+	}
+	// the info bar is just above the message bar
+	if text := d.GetRowText(8); !strings.HasSuffix(text, "> "+source+" .. 4/38") {
+		t.Errorf("Unexpected info bar text: %q", text)
+	}
+}
+
+// send a mouse event to a commander
+func mouse(c *commander.Commander, key gott.Key, row, col int) {
+	c.ProcessEvent(&gott.Event{Type: gott.EventMouse, Key: key, Position: gott.Point{Row: row, Col: col}})
+}
+
+func TestMouseSelection(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+
+	// a click moves the cursor without selecting
+	mouse(c, gott.KeyMouseLeft, 3, 5)
+	mouse(c, gott.KeyMouseRelease, 3, 5)
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 5}) {
+		t.Errorf("Unexpected cursor position after click: %+v", cursor)
+	}
+	if _, _, ok := e.GetSelection(); ok {
+		t.Errorf("Click created a selection")
+	}
+
+	// a drag selects text and leaves the editor in visual mode
+	mouse(c, gott.KeyMouseLeft, 3, 15)
+	mouse(c, gott.KeyMouseLeft, 4, 20)
+	mouse(c, gott.KeyMouseLeft, 3, 10)
+	mouse(c, gott.KeyMouseRelease, 3, 10)
+	start, end, ok := e.GetSelection()
+	if !ok || start != (gott.Point{Row: 3, Col: 10}) || end != (gott.Point{Row: 3, Col: 15}) {
+		t.Errorf("Unexpected selection %+v to %+v", start, end)
+	}
+	typeKeys(c, "y")
+	if text := e.GetPasteText(); text != " and s" {
+		t.Errorf("Unexpected yanked text: %q", text)
+	}
+	if _, _, ok := e.GetSelection(); ok {
+		t.Errorf("Yank didn't clear the selection")
+	}
+
+	// drags are clipped to the window where they started
+	e.SplitWindowHorizontally()
+	e.LayoutWindows()
+	mouse(c, gott.KeyMouseLeft, 5, 2)
+	mouse(c, gott.KeyMouseLeft, 6, 60)
+	mouse(c, gott.KeyMouseRelease, 6, 60)
+	start, end, ok = e.GetSelection()
+	if !ok || start != (gott.Point{Row: 5, Col: 2}) || end != (gott.Point{Row: 6, Col: 39}) {
+		t.Errorf("Unexpected clipped selection %+v to %+v", start, end)
+	}
+	pressKey(c, gott.KeyEsc)
+	if _, _, ok := e.GetSelection(); ok {
+		t.Errorf("Escape didn't clear the selection")
+	}
+}
+
+func TestResizeKeepsCursorVisible(t *testing.T) {
+	e := setup(t)
+	e.SetSize(gott.Size{Rows: 10, Cols: 80})
+	e.LayoutWindows()
+	e.SetCursor(gott.Point{Row: 7, Col: 0})
+	d := display.NewDisplay(gott.Size{Rows: 11, Cols: 80})
+	e.RenderWindows(d)
+	if cursor := d.GetCursor(); cursor.Row != 7 {
+		t.Errorf("Unexpected cursor row before resize: %d", cursor.Row)
+	}
+
+	// shrinking leaves room for three rows of text above the info bar
+	e.SetSize(gott.Size{Rows: 4, Cols: 80})
+	e.LayoutWindows()
+	e.GetActiveWindow().SetCursorForDisplay(d)
+	if cursor := d.GetCursor(); cursor.Row != 2 {
+		t.Errorf("Unexpected cursor row after resize: %d", cursor.Row)
+	}
+}
+
+func TestSplitWithFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("hello\n"))
+	f.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+	original := e.GetActiveWindow().GetBuffer()
+
+	typeKeys(c, ":split "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	top := e.GetActiveWindow()
+	if top.GetBuffer() == original || top.GetBuffer().GetFileName() != f.Name() {
+		t.Errorf("Split didn't open %s", f.Name())
+	}
+	if string(top.GetBuffer().GetBytes()) != "hello\n" {
+		t.Errorf("Unexpected contents in split: %q", string(top.GetBuffer().GetBytes()))
+	}
+	e.SelectWindowNext()
+	if e.GetActiveWindow().GetBuffer() != original {
+		t.Errorf("Split changed the buffer of the other window")
+	}
+
+	// files that are already open share their buffers
+	typeKeys(c, ":vsplit "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	if e.GetActiveWindow().GetBuffer() != top.GetBuffer() {
+		t.Errorf("Split didn't reuse the buffer for %s", f.Name())
+	}
+
+	// files that can't be read leave the new window's buffer alone
+	missing := f.Name() + ".missing"
+	typeKeys(c, ":split "+missing)
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(200); !strings.Contains(message, "no such file") {
+		t.Errorf("Unexpected message after splitting a missing file: %q", message)
+	}
+	if e.GetActiveWindow().GetBuffer() != top.GetBuffer() {
+		t.Errorf("Unexpected buffer after splitting a missing file: %s", e.GetActiveWindow().GetBuffer().GetName())
+	}
+}
+
+func TestEditFileWithUnsavedChanges(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("hello\n"))
+	f.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+	original := e.GetActiveWindow().GetBuffer()
+	first := original.GetRowString(0)
+
+	// edits that would be hidden prevent another file from replacing them
+	typeKeys(c, "dd")
+	typeKeys(c, fmt.Sprintf("(edit-file %q)", f.Name()))
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(200); !strings.Contains(message, "No write since last change") {
+		t.Errorf("Unexpected message after editing another file: %q", message)
+	}
+	if e.GetActiveWindow().GetBuffer() != original || !original.GetModified() {
+		t.Errorf("Editing another file discarded unsaved changes")
+	}
+
+	// edits that are shown in another window can be replaced, but each buffer keeps its own undo history
+	typeKeys(c, ":vsplit "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "u")
+	if text := string(e.GetActiveWindow().GetBuffer().GetBytes()); text != "hello\n" {
+		t.Errorf("Undo changed a buffer that wasn't edited: %q", text)
+	}
+	e.SelectWindowNext()
+	typeKeys(c, "u")
+	if row := original.GetRowString(0); row != first {
+		t.Errorf("Undo didn't restore the edited buffer: %q", row)
+	}
+}
+
+func TestSplitWindowNumbers(t *testing.T) {
+	e := setup(t)
+	e.SetSize(gott.Size{Rows: 40, Cols: 80})
+	e.LayoutWindows()
+	e.SplitWindowVertically()
+	e.SplitWindowHorizontally()
+	e.SelectWindowNext()
+	e.SplitWindowVertically()
+	e.CloseActiveWindow()
+	e.SelectWindowNext()
+	e.SplitWindowHorizontally()
+	e.SelectWindowPrevious()
+	e.CloseActiveWindow()
+
+	// each visible window has a unique number that finds it
+	numbers := make(map[int]bool)
+	first := e.GetActiveWindow()
+	w := first
+	for {
+		number := w.GetNumber()
+		if numbers[number] || number < 0 {
+			t.Errorf("Unexpected window number %d", number)
+		}
+		numbers[number] = true
+		e.SelectWindowNext()
+		w = e.GetActiveWindow()
+		if w == first {
+			break
+		}
+	}
+	if len(numbers) != 3 {
+		t.Errorf("Unexpected number of windows: %d", len(numbers))
+	}
+	for number := range numbers {
+		if err := e.SelectWindow(number); err != nil || e.GetActiveWindow().GetNumber() != number {
+			t.Errorf("Window %d wasn't found", number)
+		}
+	}
+	if err := e.SelectWindow(-1); err == nil {
+		t.Errorf("A container window was selected")
+	}
+}
+
+func TestListWindows(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+	number := e.GetActiveWindow().GetNumber()
+	typeKeys(c, "x")
+
+	e.ListWindows()
+	listing := strings.Split(string(e.Bytes()), "\n")
+	expected := []string{
+		// the output window is created just before the file window
+		fmt.Sprintf(" [%d] h   *output*", number-1),
+		fmt.Sprintf(" [%d] a+  %s", number, source),
+	}
+	for i, line := range expected {
+		if i >= len(listing) || listing[i] != line {
+			t.Errorf("Unexpected window listing: %q", listing)
+			break
+		}
+	}
+}
+
+func TestScratchBuffer(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+
+	typeKeys(c, ":new")
+	pressKey(c, gott.KeyEnter)
+	b := e.GetActiveWindow().GetBuffer()
+	if b.GetName() != "*scratch*" || b.GetFileName() != "" || b.GetReadOnly() {
+		t.Errorf("Unexpected scratch buffer %q for file %q", b.GetName(), b.GetFileName())
+	}
+	typeKeys(c, "inotes")
+	pressKey(c, gott.KeyEsc)
+	if text := string(e.Bytes()); text != "notes" {
+		t.Errorf("Unexpected scratch buffer contents: %q", text)
+	}
+
+	// scratch buffers can only be written to named files
+	typeKeys(c, ":w")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); message != "No file name" {
+		t.Errorf("Unexpected message after writing scratch buffer: %q", message)
+	}
+}
+
+func TestSnapshotBuffer(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+	original := e.GetActiveWindow()
+	text := string(e.Bytes())
+
+	typeKeys(c, ":snapshot")
+	pressKey(c, gott.KeyEnter)
+	snapshot := e.GetActiveWindow()
+	b := snapshot.GetBuffer()
+	if b.GetName() != original.GetBuffer().GetName()+" (snapshot)" || !b.GetReadOnly() || b.GetModified() {
+		t.Errorf("Unexpected snapshot buffer %q", b.GetName())
+	}
+	if string(b.GetBytes()) != text {
+		t.Errorf("Unexpected snapshot contents: %q", b.GetBytes())
+	}
+	// snapshots can't be edited
+	typeKeys(c, "dd")
+	if string(b.GetBytes()) != text {
+		t.Errorf("Unexpected snapshot contents after edit: %q", b.GetBytes())
+	}
+	// edits to the original don't change the snapshot
+	e.SelectWindow(original.GetNumber())
+	typeKeys(c, "dd")
+	if string(e.Bytes()) == text {
+		t.Errorf("Expected the original buffer to change")
+	}
+	if string(b.GetBytes()) != text {
+		t.Errorf("Unexpected snapshot contents after editing the original: %q", b.GetBytes())
+	}
+}
+
+func TestGotoFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatalf("Temp directory creation failed: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "notes"), 0755)
+	target := filepath.Join(dir, "notes", "to-do_list.txt")
+	ioutil.WriteFile(target, []byte("buy milk\n"), 0644)
+	path := filepath.Join(dir, "index.txt")
+	ioutil.WriteFile(path, []byte("see notes/to-do_list.txt, or notes/missing.txt\n"), 0644)
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(path); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "10l:echo (file-at-cursor)")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(200); message != "\""+target+"\"" {
+		t.Errorf("Unexpected path under the cursor: %s", message)
+	}
+	// missing files are reported
+	pressKey(c, gott.KeyCtrlE)
+	typeKeys(c, "gf")
+	if message := c.GetMessageBarText(200); !strings.HasPrefix(message, "Can't find file") {
+		t.Errorf("Unexpected message for a missing file: %s", message)
+	}
+	pressKey(c, gott.KeyCtrlA)
+	typeKeys(c, "wgf")
+	if name := e.GetActiveWindow().GetBuffer().GetFileName(); name != target {
+		t.Errorf("Unexpected file after gf: %s", name)
+	}
+	if text := string(e.Bytes()); text != "buy milk\n" {
+		t.Errorf("Unexpected contents after gf: %q", text)
+	}
+
+	// files that are open under relative names aren't read again
+	e.ReadFile(path)
+	wd, _ := os.Getwd()
+	relative, err := filepath.Rel(wd, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeKeys(c, ":split "+relative)
+	pressKey(c, gott.KeyEnter)
+	shown := e.GetActiveWindow().GetBuffer()
+	e.SelectWindowNext()
+	typeKeys(c, "wgf")
+	if e.GetActiveWindow().GetBuffer() != shown {
+		t.Errorf("gf didn't reuse the buffer for %s", relative)
+	}
+}
+
+func TestOpenExternally(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("docs (https://example.com/a/b?q=1&r=2#top), then none.\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	var opened []string
+	defer func(start func(string, ...string) error) {
+		commander.StartOpener = start
+	}(commander.StartOpener)
+	commander.StartOpener = func(name string, args ...string) error {
+		opened = append([]string{name}, args...)
+		return nil
+	}
+	url := "https://example.com/a/b?q=1&r=2#top"
+	typeKeys(c, "10l:echo (url-at-cursor)")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(200); message != "\""+url+"\"" {
+		t.Errorf("Unexpected URL under the cursor: %s", message)
+	}
+	typeKeys(c, "gx")
+	if len(opened) == 0 || opened[len(opened)-1] != url {
+		t.Errorf("Unexpected command to open a URL: %q", opened)
+	}
+	if opener := opened[0]; opener != "open" && opener != "xdg-open" && opener != "cmd" {
+		t.Errorf("Unexpected opener: %s", opener)
+	}
+	// words that aren't URLs or files aren't opened
+	opened = nil
+	pressKey(c, gott.KeyCtrlE)
+	typeKeys(c, "hgx")
+	if opened != nil || !strings.HasPrefix(c.GetMessageBarText(200), "Can't find file") {
+		t.Errorf("Unexpected command to open a missing file: %q", opened)
+	}
+}
+
+func TestCursorPositions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatalf("Temp directory creation failed: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	text, err := ioutil.ReadFile(source)
+	if err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	path := filepath.Join(dir, "address.txt")
+	ioutil.WriteFile(path, text, 0644)
+	positions := filepath.Join(dir, "positions")
+	// positions in files that no longer exist are forgotten
+	ioutil.WriteFile(positions, []byte("7 2 "+filepath.Join(dir, "missing.txt")+"\n"), 0644)
+
+	e := editor.NewEditor()
+	if err := e.LoadCursorPositions(positions); err != nil {
+		t.Fatalf("Load failed: %+v", err)
+	}
+	if err := e.ReadFile(path); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "jjjlllll:w")
+	pressKey(c, gott.KeyEnter)
+	if saved, _ := ioutil.ReadFile(positions); string(saved) != "3 5 "+path+"\n" {
+		t.Errorf("Unexpected saved positions: %q", saved)
+	}
+
+	// the position is restored when the file is opened again
+	e = editor.NewEditor()
+	if err := e.LoadCursorPositions(positions); err != nil {
+		t.Fatalf("Load failed: %+v", err)
+	}
+	if err := e.ReadFile(path); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	if cursor := e.GetCursor(); cursor.Row != 3 || cursor.Col != 5 {
+		t.Errorf("Unexpected cursor after reopening: %+v", cursor)
+	}
+}
+
+func TestBinaryFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.bin")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	data := []byte("GOTT\x00\x01\x02\nbinary data\xff")
+	f.Write(data)
+	f.Close()
+
+	if !editor.IsBinary(data) || editor.IsBinary([]byte("text\n")) {
+		t.Errorf("Unexpected binary detection")
+	}
+	first := "00000000  47 4f 54 54 00 01 02 0a  62 69 6e 61 72 79 20 64  |GOTT....binary d|"
+	if line := strings.Split(editor.HexDump(data), "\n")[0]; line != first {
+		t.Errorf("Unexpected first line of hex dump: %q", line)
+	}
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	if !b.GetBinary() || !b.GetReadOnly() || b.GetRowString(0) != first {
+		t.Errorf("Unexpected buffer for binary file: %q", b.GetRowString(0))
+	}
+	// the hex dump can't be edited or written
+	typeKeys(c, "dd:w")
+	pressKey(c, gott.KeyEnter)
+	if b.GetRowString(0) != first {
+		t.Errorf("Unexpected edit to hex dump: %q", b.GetRowString(0))
+	}
+	if written, _ := ioutil.ReadFile(f.Name()); string(written) != string(data) {
+		t.Errorf("Unexpected write of hex dump: %q", written)
+	}
+	for _, command := range []string{":1,$w!", ":1,$w " + f.Name(), ":w >> " + f.Name()} {
+		typeKeys(c, command)
+		pressKey(c, gott.KeyEnter)
+		if message := c.GetMessageBarText(80); !strings.Contains(message, "can't be written") {
+			t.Errorf("Unexpected message after %s: %q", command, message)
+		}
+		if written, _ := ioutil.ReadFile(f.Name()); string(written) != string(data) {
+			t.Errorf("Unexpected write of hex dump with %s: %q", command, written)
+		}
+	}
+
+	// text buffers can be shown as hex dumps
+	e2 := setup(t)
+	c = commander.NewCommander(e2)
+	typeKeys(c, ":set binary")
+	pressKey(c, gott.KeyEnter)
+	b = e2.GetActiveWindow().GetBuffer()
+	if !b.GetBinary() || !strings.HasPrefix(b.GetRowString(0), "00000000  54 48 45 20") {
+		t.Errorf("Unexpected hex dump of text buffer: %q", b.GetRowString(0))
+	}
+}
+
+func TestAppendToFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("first\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	c := commander.NewCommander(e)
+	e.LoadBytes([]byte("more\n"))
+	typeKeys(c, ":w >> "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, ":w >> "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("Read failed: %+v", err)
+	}
+	if string(b) != "first\nmore\nmore\n" {
+		t.Errorf("Unexpected file contents after appending: %q", string(b))
+	}
+}
+
+func TestWriteLineRange(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	b, err := ioutil.ReadFile(source)
+	if err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, ":4,6w "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	// ranges are clipped to the buffer
+	typeKeys(c, ":30,1000w >> "+f.Name())
+	pressKey(c, gott.KeyEnter)
+
+	expected := strings.Join(lines[3:6], "") + strings.Join(lines[29:], "")
+	b, err = ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("Read failed: %+v", err)
+	}
+	if string(b) != expected {
+		t.Errorf("Unexpected file contents after writing ranges: %q", string(b))
+	}
+
+	// a range isn't written over the buffer's own file unless that is forced
+	e = editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c = commander.NewCommander(e)
+	typeKeys(c, ":1,2w")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); !strings.HasPrefix(message, "Use w!") {
+		t.Errorf("Unexpected message after writing a range without a path: %q", message)
+	}
+	if b, _ := ioutil.ReadFile(f.Name()); string(b) != expected {
+		t.Errorf("Writing a range without a path changed the file: %q", string(b))
+	}
+	typeKeys(c, ":1,2w!")
+	pressKey(c, gott.KeyEnter)
+	if b, _ := ioutil.ReadFile(f.Name()); string(b) != strings.Join(lines[3:5], "") {
+		t.Errorf("Unexpected file contents after forcing a range write: %q", string(b))
+	}
+}
+
+func TestSession(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("one\ntwo\nthree\n"))
+	f.Close()
+	session, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	session.Close()
+	defer os.Remove(session.Name())
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 40, Cols: 80})
+	e.LayoutWindows()
+	e.SetCursor(gott.Point{Row: 20, Col: 4})
+	typeKeys(c, ":vsplit "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	e.SetCursor(gott.Point{Row: 2, Col: 0})
+
+	script := e.SessionScript()
+	for _, command := range []string{
+		"(vsplit)",
+		fmt.Sprintf("(edit-file %q)", f.Name()),
+		"(goto-line 3)",
+		fmt.Sprintf("(edit-file %q)", source),
+		"(goto-line 21)",
+		"(right 4)",
+	} {
+		if !strings.Contains(script, command+"\n") {
+			t.Errorf("Session script doesn't contain %s:\n%s", command, script)
+		}
+	}
+
+	// restore the session in a new editor
+	typeKeys(c, ":mksession "+session.Name())
+	pressKey(c, gott.KeyEnter)
+	e2 := editor.NewEditor()
+	c2 := commander.NewCommander(e2)
+	e2.SetSize(gott.Size{Rows: 40, Cols: 80})
+	e2.LayoutWindows()
+	typeKeys(c2, ":source "+session.Name())
+	pressKey(c2, gott.KeyEnter)
+	if name := e2.GetFileName(); name != f.Name() || e2.GetCursor() != (gott.Point{Row: 2, Col: 0}) {
+		t.Errorf("Unexpected focused window after restoring session: %s %+v", name, e2.GetCursor())
+	}
+	e2.SelectWindowNext()
+	if name := e2.GetFileName(); name != source || e2.GetCursor() != (gott.Point{Row: 20, Col: 4}) {
+		t.Errorf("Unexpected second window after restoring session: %s %+v", name, e2.GetCursor())
+	}
+}
+
+func TestQuitFromLisp(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "(quit)")
+	pressKey(c, gott.KeyEnter)
+	if c.IsRunning() {
+		t.Errorf("(quit) didn't stop the commander")
+	}
+
+	// unsaved changes prevent quitting unless they are discarded
+	c = commander.NewCommander(e)
+	typeKeys(c, "x")
+	typeKeys(c, "(quit)")
+	pressKey(c, gott.KeyEnter)
+	if !c.IsRunning() {
+		t.Errorf("(quit) stopped the commander with unsaved changes")
+	}
+	typeKeys(c, "(quit-all)")
+	pressKey(c, gott.KeyEnter)
+	if c.IsRunning() {
+		t.Errorf("(quit-all) didn't stop the commander")
+	}
+}
+
+func TestMessageFromLisp(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, `(message "hello there")`)
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); message != "hello there" {
+		t.Errorf("Unexpected message: %q", message)
+	}
+}
+
+func TestAskFromLisp(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	c.SetDisplay(d)
+	// backspace removes a whole character
+	for _, ch := range "Bobé" {
+		d.AddEvent(&gott.Event{Type: gott.EventKey, Ch: ch})
+	}
+	d.AddEvent(&gott.Event{Type: gott.EventKey, Key: gott.KeyBackspace2})
+	d.AddEvent(&gott.Event{Type: gott.EventKey, Key: gott.KeySpace})
+	d.AddEvent(&gott.Event{Type: gott.EventKey, Ch: 'J'})
+	d.AddEvent(&gott.Event{Type: gott.EventKey, Key: gott.KeyEnter})
+
+	typeKeys(c, `(message (ask "Name? "))`)
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(40); message != "Bob J" {
+		t.Errorf("Unexpected answer: %q", message)
+	}
+	// the prompt is shown while the answer is typed
+	if text := d.GetRowText(9); text != "Name? Bob J" {
+		t.Errorf("Unexpected prompt: %q", text)
+	}
+}
+
+func TestCancelEditKeys(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	original := string(e.Bytes())
+
+	// Esc cancels a pending command
+	typeKeys(c, "d")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, "j")
+	if cursor := e.GetCursor(); cursor.Row != 1 {
+		t.Errorf("Motion after cancelled command was lost: %+v", cursor)
+	}
+
+	// unmapped keys cancel a pending command and its multiplier
+	typeKeys(c, "3dz")
+	if string(e.Bytes()) != original {
+		t.Errorf("Cancelled command changed the buffer")
+	}
+	typeKeys(c, "j")
+	if cursor := e.GetCursor(); cursor.Row != 2 {
+		t.Errorf("Motion after cancelled command was lost: %+v", cursor)
+	}
+	typeKeys(c, "kkx")
+	if text := string(e.Bytes()); text != original[1:] {
+		t.Errorf("Unexpected change after cancelled command: %q", text[0:20])
+	}
+}
+
+func TestSearchAndJumpWithMultiplier(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "/the")
+	pressKey(c, gott.KeyEnter)
+	first := e.GetCursor()
+	typeKeys(c, "nnn")
+	expected := e.GetCursor()
+	typeKeys(c, "3N")
+	if cursor := e.GetCursor(); cursor != first {
+		t.Errorf("Unexpected cursor position after 3N: %+v expected %+v", cursor, first)
+	}
+	typeKeys(c, "3n")
+	if cursor := e.GetCursor(); cursor != expected {
+		t.Errorf("Unexpected cursor position after 3n: %+v expected %+v", cursor, expected)
+	}
+
+	for _, tc := range []struct {
+		Keys string
+		Row  int
+	}{
+		{"5G", 4},
+		{"G", e.GetActiveWindow().GetBuffer().GetRowCount() - 1},
+		{"gg", 0},
+		{"12gg", 11},
+	} {
+		typeKeys(c, tc.Keys)
+		if cursor := e.GetCursor(); cursor.Row != tc.Row {
+			t.Errorf("Unexpected row after %s: %d expected %d", tc.Keys, cursor.Row, tc.Row)
+		}
+	}
+}
+
+func TestMotionWithMultiplier(t *testing.T) {
+	for _, tc := range []struct {
+		Keys     string
+		Expected gott.Point
+	}{
+		{"5j", gott.Point{Row: 8, Col: 0}},
+		{"3w", gott.Point{Row: 3, Col: 15}},
+		{"10l", gott.Point{Row: 3, Col: 10}},
+		{"2w3h", gott.Point{Row: 3, Col: 8}},
+		{"2j4k", gott.Point{Row: 1, Col: 0}},
+	} {
+		e := setup(t)
+		c := commander.NewCommander(e)
+		e.SetCursor(gott.Point{Row: 3, Col: 0})
+		typeKeys(c, tc.Keys)
+		if cursor := e.GetCursor(); cursor != tc.Expected {
+			t.Errorf("Unexpected cursor position after %s: %+v expected %+v", tc.Keys, cursor, tc.Expected)
+		}
+	}
+
+	// arrow keys use multipliers too
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "5")
+	pressKey(c, gott.KeyArrowDown)
+	typeKeys(c, "2")
+	pressKey(c, gott.KeyArrowRight)
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 5, Col: 2}) {
+		t.Errorf("Unexpected cursor position after arrows: %+v", cursor)
+	}
+
+	// multipliers aren't kept for later commands
+	typeKeys(c, "3:")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, "j")
+	if cursor := e.GetCursor(); cursor.Row != 6 {
+		t.Errorf("Unused multiplier was applied to a later command: %+v", cursor)
+	}
+}
+
+func TestDisplayLineMotion(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 20})
+	e.LayoutWindows()
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	// rows aren't wrapped, so display lines are rows
+	for _, tc := range []struct {
+		Keys string
+		Row  int
+	}{
+		{"gj", 4},
+		{"3gj", 7},
+		{"2gk", 5},
+	} {
+		typeKeys(c, tc.Keys)
+		if cursor := e.GetCursor(); cursor.Row != tc.Row {
+			t.Errorf("Unexpected row after %s: %d expected %d", tc.Keys, cursor.Row, tc.Row)
+		}
+	}
+}
+
+func TestRepeatWithMultiplier(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "x")
+	typeKeys(c, "3.")
+	if text := string(e.Bytes()); !strings.HasPrefix(text, "GETTYSBURG ADDRESS:") {
+		t.Errorf("Unexpected text after 3.: %q", text[0:20])
+	}
+	// the new multiplier is kept for later repeats
+	typeKeys(c, ".")
+	if text := string(e.Bytes()); !strings.HasPrefix(text, "TYSBURG ADDRESS:") {
+		t.Errorf("Unexpected text after .: %q", text[0:20])
+	}
+	typeKeys(c, "uu")
+	if text := string(e.Bytes()); !strings.HasPrefix(text, "HE GETTYSBURG ADDRESS:") {
+		t.Errorf("Unexpected text after undo: %q", text[0:20])
+	}
+}
+
+func TestSwapSelectionEnds(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	typeKeys(c, "vj3l")
+	start, end, _ := e.GetSelection()
+	if cursor := e.GetCursor(); cursor != end {
+		t.Errorf("Unexpected cursor before swap: %+v", cursor)
+	}
+	typeKeys(c, "o")
+	if cursor := e.GetCursor(); cursor != start {
+		t.Errorf("Unexpected cursor after swap: %+v", cursor)
+	}
+	if start2, end2, ok := e.GetSelection(); !ok || start2 != start || end2 != end {
+		t.Errorf("Swap changed the selection to %+v %+v", start2, end2)
+	}
+	// the selection extends from the other end
+	typeKeys(c, "h")
+	if start2, end2, _ := e.GetSelection(); start2 != (gott.Point{Row: 3, Col: 4}) || end2 != end {
+		t.Errorf("Unexpected selection after extending: %+v %+v", start2, end2)
+	}
+}
+
+func TestSubstitution(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	typeKeys(c, ":s/(o)(\\w+)/${2}o/")
+	pressKey(c, gott.KeyEnter)
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "Furo score and seven") {
+		t.Errorf("Unexpected text after substitution: %q", text)
+	}
+
+	// & repeats the substitution on the current line
+	typeKeys(c, "j&")
+	if text := b.TextFromPosition(4, 0); !strings.HasPrefix(text, "cntinento a new nation") {
+		t.Errorf("Unexpected text after repeated substitution: %q", text)
+	}
+	typeKeys(c, "uu")
+	final(t, e)
+
+	// substitutions can apply to ranges with multiple matches
+	typeKeys(c, ":4,5s/ /_/g")
+	pressKey(c, gott.KeyEnter)
+	if text := b.TextFromPosition(4, 0); strings.Contains(text, " ") {
+		t.Errorf("Unexpected text after global substitution: %q", text)
+	}
+	if text := b.TextFromPosition(5, 0); !strings.Contains(text, " ") {
+		t.Errorf("Substitution changed a row outside its range: %q", text)
+	}
+	typeKeys(c, "u")
+	final(t, e)
+}
+
+func TestSmartCaseSearch(t *testing.T) {
+	for _, tc := range []struct {
+		Options string
+		Query   string
+		Row     int
+	}{
+		{"", "national", 0},
+		{"", "National", 31},
+		{"(set-ignorecase #t)", "national", 31},
+		{"(set-ignorecase #t)", "NATIONAL", 31},
+		{"(set-ignorecase #t)(set-smartcase #t)", "national", 31},
+		{"(set-ignorecase #t)(set-smartcase #t)", "National", 31},
+		{"(set-ignorecase #t)(set-smartcase #t)", "NATIONAL", 0},
+		// smartcase has no effect unless case is ignored
+		{"(set-smartcase #t)", "national", 0},
+	} {
+		e := setup(t)
+		c := commander.NewCommander(e)
+		if tc.Options != "" {
+			typeKeys(c, tc.Options)
+			pressKey(c, gott.KeyEnter)
+		}
+		typeKeys(c, "/"+tc.Query)
+		pressKey(c, gott.KeyEnter)
+		if cursor := e.GetCursor(); cursor.Row != tc.Row {
+			t.Errorf("Unexpected row after searching for %s with %s: %d expected %d",
+				tc.Query, tc.Options, cursor.Row, tc.Row)
+		}
+	}
+}
+
+func TestWholeWordSearch(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 58})
+	// * skips words that contain the word under the cursor
+	typeKeys(c, "*")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 7, Col: 55}) {
+		t.Errorf("Unexpected cursor after *: %+v", cursor)
+	}
+	typeKeys(c, "#")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 57}) {
+		t.Errorf("Unexpected cursor after #: %+v", cursor)
+	}
+	// repeated searches also match whole words
+	typeKeys(c, "N")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 7, Col: 55}) {
+		t.Errorf("Unexpected cursor after N: %+v", cursor)
+	}
+}
+
+func TestReselect(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	typeKeys(c, "vj3l")
+	start, end, _ := e.GetSelection()
+	pressKey(c, gott.KeyEsc)
+	if _, _, ok := e.GetSelection(); ok {
+		t.Errorf("Selection was not cleared")
+	}
+	typeKeys(c, "jjw")
+	typeKeys(c, "gv")
+	if start2, end2, ok := e.GetSelection(); !ok || start2 != start || end2 != end {
+		t.Errorf("Unexpected reselection %+v %+v, expected %+v %+v", start2, end2, start, end)
+	}
+	if cursor := e.GetCursor(); cursor != end {
+		t.Errorf("Unexpected cursor after reselection: %+v", cursor)
+	}
+	// gv enters visual mode, so motion extends the selection
+	typeKeys(c, "l")
+	if _, end2, _ := e.GetSelection(); end2.Col != end.Col+1 {
+		t.Errorf("Unexpected selection after extending: %+v", end2)
+	}
+}
+
+func TestAltKey(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	// an Alt key leaves insert mode and is then handled as a command
+	typeKeys(c, "iab")
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: 'x', Mod: gott.ModAlt})
+	b := e.GetActiveWindow().GetBuffer()
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "abour score") {
+		t.Errorf("Unexpected text after Alt key: %q", text)
+	}
+	typeKeys(c, "uu")
+	final(t, e)
+}
+
+func TestCtrlArrowKeys(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Key: gott.KeyArrowRight, Mod: gott.ModCtrl})
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 5}) {
+		t.Errorf("Unexpected cursor after Ctrl-Right: %+v", cursor)
+	}
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Key: gott.KeyArrowLeft, Mod: gott.ModCtrl})
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 0}) {
+		t.Errorf("Unexpected cursor after Ctrl-Left: %+v", cursor)
+	}
+}
+
+func TestParagraphMotion(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	cases := []struct {
+		Keys     string
+		Expected gott.Point
+	}{
+		{"}", gott.Point{Row: 26, Col: 0}},
+		{"2}", gott.Point{Row: 32, Col: 0}},
+		{"{", gott.Point{Row: 28, Col: 0}},
+		{"{", gott.Point{Row: 26, Col: 0}},
+		{"2{", gott.Point{Row: 0, Col: 0}},
+		{"9}", gott.Point{Row: 37, Col: 0}},
+		{"3{", gott.Point{Row: 26, Col: 0}},
+	}
+	for _, tc := range cases {
+		typeKeys(c, tc.Keys)
+		if cursor := e.GetCursor(); cursor != tc.Expected {
+			t.Errorf("Unexpected cursor after %q: %+v expected %+v", tc.Keys, cursor, tc.Expected)
+		}
+	}
+}
+
+func TestSentenceMotion(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	cases := []struct {
+		Keys     string
+		Expected gott.Point
+	}{
+		{")", gott.Point{Row: 5, Col: 44}},
+		{"2)", gott.Point{Row: 8, Col: 25}},
+		{"g(", gott.Point{Row: 7, Col: 44}},
+		{"2g(", gott.Point{Row: 3, Col: 0}},
+		{"g(", gott.Point{Row: 0, Col: 0}},
+		// this sentence ends at the end of a row
+		{"12jll)", gott.Point{Row: 13, Col: 0}},
+		{"g(", gott.Point{Row: 11, Col: 31}},
+		{"lg(", gott.Point{Row: 11, Col: 31}},
+	}
+	for _, tc := range cases {
+		typeKeys(c, tc.Keys)
+		if cursor := e.GetCursor(); cursor != tc.Expected {
+			t.Errorf("Unexpected cursor after %q: %+v expected %+v", tc.Keys, cursor, tc.Expected)
+		}
+	}
+}
+
+func TestParagraphObjects(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	originalRowCount := b.GetRowCount()
+	e.SetCursor(gott.Point{Row: 5, Col: 10})
+	typeKeys(c, "dip")
+	if rowCount := b.GetRowCount(); rowCount != originalRowCount-23 {
+		t.Errorf("Invalid row count after dip: %d", rowCount)
+	}
+	// the blank rows around the paragraph remain
+	if b.TextFromPosition(2, 0) != "" || b.TextFromPosition(3, 0) != "" ||
+		!strings.HasPrefix(b.TextFromPosition(4, 0), "---") {
+		t.Errorf("Unexpected rows after dip: %q", string(b.BytesForRange(1, 5)))
+	}
+	typeKeys(c, "u")
+
+	// around includes the following blank row
+	typeKeys(c, "dap")
+	if rowCount := b.GetRowCount(); rowCount != originalRowCount-24 {
+		t.Errorf("Invalid row count after dap: %d", rowCount)
+	}
+	typeKeys(c, "u")
+
+	// change replaces the paragraph with a single row
+	e.SetCursor(gott.Point{Row: 5, Col: 10})
+	typeKeys(c, "cipnew text")
+	pressKey(c, gott.KeyEsc)
+	if rowCount := b.GetRowCount(); rowCount != originalRowCount-22 {
+		t.Errorf("Invalid row count after cip: %d", rowCount)
+	}
+	if text := b.TextFromPosition(3, 0); text != "new text" {
+		t.Errorf("Unexpected text after cip: %q", text)
+	}
+	typeKeys(c, "u")
+	final(t, e)
+}
+
+func TestSurround(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	e.SetCursor(gott.Point{Row: 3, Col: 7})
+	typeKeys(c, "ysiw)")
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "Four (score) and") {
+		t.Errorf("Unexpected text after surrounding a word: %q", text)
+	}
+	typeKeys(c, "cs)[")
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "Four [ score ] and") {
+		t.Errorf("Unexpected text after changing brackets: %q", text)
+	}
+	typeKeys(c, "ds]")
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "Four  score  and") {
+		t.Errorf("Unexpected text after deleting brackets: %q", text)
+	}
+	typeKeys(c, "uuu")
+
+	// change quotes in a new row
+	typeKeys(c, "osay \"hello\" now")
+	pressKey(c, gott.KeyEsc)
+	e.SetCursor(gott.Point{Row: 4, Col: 6})
+	typeKeys(c, "cs\"'")
+	if text := b.TextFromPosition(4, 0); text != "say 'hello' now" {
+		t.Errorf("Unexpected text after changing quotes: %q", text)
+	}
+	typeKeys(c, "u")
+	if text := b.TextFromPosition(4, 0); text != "say \"hello\" now" {
+		t.Errorf("Unexpected text after undoing quote change: %q", text)
+	}
+	typeKeys(c, "u")
+	final(t, e)
+}
+
+func TestWordObjects(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	e.SetCursor(gott.Point{Row: 3, Col: 7})
+	typeKeys(c, "daw")
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "Four and seven") {
+		t.Errorf("Unexpected text after daw: %q", text)
+	}
+	typeKeys(c, "u")
+	typeKeys(c, "ciwten")
+	pressKey(c, gott.KeyEsc)
+	if text := b.TextFromPosition(3, 0); !strings.HasPrefix(text, "Four ten and seven") {
+		t.Errorf("Unexpected text after ciw: %q", text)
+	}
+	typeKeys(c, "u")
+	final(t, e)
+}
+
+func TestRetab(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	typeKeys(c, "(set-tab-width 4)")
+	pressKey(c, gott.KeyEnter)
+	// rows 34 and 35 are indented with two spaces
+	typeKeys(c, "(spaces-to-tabs)")
+	pressKey(c, gott.KeyEnter)
+	if text := b.TextFromPosition(34, 0); !strings.HasPrefix(text, "  redistribute") {
+		t.Errorf("Unexpected text after converting short indentation: %q", text)
+	}
+	e.SetCursor(gott.Point{Row: 34, Col: 0})
+	typeKeys(c, "i\t\t")
+	pressKey(c, gott.KeyEsc)
+	if text := b.TextFromPosition(34, 0); !strings.HasPrefix(text, "\t\t  redistribute") {
+		t.Errorf("Unexpected text after indenting: %q", text)
+	}
+	typeKeys(c, "(tabs-to-spaces)")
+	pressKey(c, gott.KeyEnter)
+	if text := b.TextFromPosition(34, 0); !strings.HasPrefix(text, "          redistribute") {
+		t.Errorf("Unexpected text after converting tabs to spaces: %q", text)
+	}
+	typeKeys(c, "(spaces-to-tabs)")
+	pressKey(c, gott.KeyEnter)
+	if text := b.TextFromPosition(34, 0); !strings.HasPrefix(text, "\t\t  redistribute") {
+		t.Errorf("Unexpected text after converting spaces to tabs: %q", text)
+	}
+	typeKeys(c, "uuuu")
+	if text := b.TextFromPosition(34, 0); !strings.HasPrefix(text, "  redistribute") {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	final(t, e)
+}
+
+func TestRetabLoadedFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("func main() {\n\tif x {\n\t\ty := 1\n\t}\n}\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	// tabs are kept when files are read and displayed at tab stops
+	if text := b.TextFromPosition(2, 0); text != "\t\ty := 1" {
+		t.Errorf("Unexpected row after reading a file with tabs: %q", text)
+	}
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	d.Render(e, c)
+	if text := d.GetRowText(2); text != "                y := 1" {
+		t.Errorf("Unexpected display of a row with tabs: %q", text)
+	}
+	typeKeys(c, "(set-tab-width 4)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "(tabs-to-spaces)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, ":w")
+	pressKey(c, gott.KeyEnter)
+	if text, _ := ioutil.ReadFile(f.Name()); string(text) != "func main() {\n    if x {\n        y := 1\n    }\n}\n" {
+		t.Errorf("Unexpected file after converting tabs to spaces: %q", text)
+	}
+	typeKeys(c, "(spaces-to-tabs)")
+	pressKey(c, gott.KeyEnter)
+	if text := b.TextFromPosition(2, 0); text != "\t\ty := 1" {
+		t.Errorf("Unexpected row after converting spaces to tabs: %q", text)
+	}
+}
+
+// return true if any row of a display contains some text
+func displayContains(d *display.Display, text string) bool {
+	for i := 0; i < d.GetSize().Rows; i++ {
+		if strings.Contains(d.GetRowText(i), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSplash(t *testing.T) {
+	banner := "gott " + editor.Version
+	e := editor.NewEditor()
+	c := commander.NewCommander(e)
+	d := display.NewDisplay(gott.Size{Rows: 20, Cols: 60})
+	d.Render(e, c)
+	if !displayContains(d, banner) {
+		t.Errorf("The splash wasn't shown for an empty buffer")
+	}
+	// the splash disappears when text is entered
+	typeKeys(c, ":new")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "ihello")
+	d.Render(e, c)
+	if displayContains(d, banner) {
+		t.Errorf("The splash was shown after typing")
+	}
+	// it is never shown for files
+	f := setup(t)
+	d.Render(f, commander.NewCommander(f))
+	if displayContains(d, banner) {
+		t.Errorf("The splash was shown for a file")
+	}
+}
+
+func TestHelp(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, ":help")
+	pressKey(c, gott.KeyEnter)
+	w := e.GetActiveWindow()
+	if w.GetName() != "*help*" || !w.GetBuffer().GetReadOnly() {
+		t.Errorf("Help wasn't shown in a read-only window: %s", w.GetName())
+	}
+	text := string(w.GetBuffer().GetBytes())
+	for _, s := range []string{"dd dw", "search forward or backward", "w [file]", "(delete-row)", "(paste)", "(help)"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("Help doesn't mention %q", s)
+		}
+	}
+	// :q closes help instead of quitting
+	typeKeys(c, ":q")
+	pressKey(c, gott.KeyEnter)
+	if !c.IsRunning() {
+		t.Errorf(":q quit instead of closing help")
+	}
+	if name := e.GetActiveWindow().GetName(); name != source {
+		t.Errorf("Unexpected active window after closing help: %s", name)
+	}
+}
+
+func TestListPrimitives(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, ":commands")
+	pressKey(c, gott.KeyEnter)
+	listing := strings.Split(string(e.Bytes()), "\n")
+	expected := map[string]string{"down": "0|1", "delete-row": "0|1", "paste": "0|1", "edit-file": "1", "help": "0"}
+	for _, line := range listing {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && expected[fields[0]] == fields[1] {
+			delete(expected, fields[0])
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("Primitives missing from listing: %+v", expected)
+	}
+}
+
+func TestRepeatTimes(t *testing.T) {
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	script.Write([]byte("(repeat-times 3 (down))\n"))
+	script.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	if result := c.ParseEvalFile(script.Name()); strings.HasPrefix(result, "ERR") {
+		t.Fatalf("Script failed: %s", result)
+	}
+	if row := e.GetCursor().Row; row != 3 {
+		t.Errorf("Cursor row is %d, expected 3", row)
+	}
+}
+
+func TestWhileSearch(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("a-b-c\nd-e\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, `(while-search "-" (delete-character))`)
+	pressKey(c, gott.KeyEnter)
+	if string(e.Bytes()) != "abc\nde\n" {
+		t.Errorf("Unexpected result:\n%s", e.Bytes())
+	}
+}
+
+func TestBufferVariables(t *testing.T) {
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	script.Write([]byte("(buffer-set 'count 0)\n(repeat-times 3 (buffer-set 'count (+ (buffer-get 'count) 1)))\n(buffer-get 'count)\n"))
+	script.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	if result := c.ParseEvalFile(script.Name()); result != "3" {
+		t.Errorf("Script returned %s, expected 3", result)
+	}
+	e.LoadBytes([]byte("new text\n"))
+	if value := e.GetActiveWindow().GetBuffer().GetVariable("count"); value != nil {
+		t.Errorf("Buffer variable wasn't cleared: %+v", value)
+	}
+}
+
+func TestFilePrimitives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatalf("Temp directory creation failed: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notes.txt")
+	script := filepath.Join(dir, "script.lisp")
+	ioutil.WriteFile(script, []byte(fmt.Sprintf("(write-file %q \"one\\ntwo\\n\")\n(read-file %q)\n", path, path)), 0644)
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	if result := c.ParseEvalFile(script); result != "\"one\ntwo\n\"" {
+		t.Errorf("Script returned %s", result)
+	}
+	if bytes, err := ioutil.ReadFile(path); err != nil || string(bytes) != "one\ntwo\n" {
+		t.Errorf("Unexpected file contents: %q %+v", bytes, err)
+	}
+	// errors are returned to the script
+	ioutil.WriteFile(script, []byte(fmt.Sprintf("(read-file %q)\n", filepath.Join(dir, "missing.txt"))), 0644)
+	if result := c.ParseEvalFile(script); !strings.HasPrefix(result, "ERR") {
+		t.Errorf("Expected an error reading a missing file: %s", result)
+	}
+}
+
+func TestShellPrimitive(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	script.Write([]byte("(shell \"echo hi\")\n"))
+	script.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	// scripts can only run shell commands when they are allowed
+	if result := c.ParseEvalFile(script.Name()); !strings.HasPrefix(result, "ERR") {
+		t.Errorf("Expected an error running a shell command: %s", result)
+	}
+	c.SetAllowShell(true)
+	if result := c.ParseEvalFile(script.Name()); result != "\"hi\n\"" {
+		t.Errorf("Script returned %s", result)
+	}
+	// errors hold the standard error of failed commands
+	ioutil.WriteFile(script.Name(), []byte("(shell \"echo oops >&2; exit 1\")\n"), 0644)
+	if result := c.ParseEvalFile(script.Name()); !strings.Contains(result, "oops") {
+		t.Errorf("Unexpected result from a failed command: %s", result)
+	}
+}
+
+func TestSaveHook(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, `(add-hook "on-save" (lambda (name) (buffer-set 'saved name)))`)
+	pressKey(c, gott.KeyEnter)
+	if value := e.GetActiveWindow().GetBuffer().GetVariable("saved"); value != nil {
+		t.Errorf("Save hook fired before saving")
+	}
+	typeKeys(c, ":w "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	value, ok := e.GetActiveWindow().GetBuffer().GetVariable("saved").(*golisp.Data)
+	if !ok || golisp.StringValue(value) != f.Name() {
+		t.Errorf("Save hook didn't fire")
+	}
+
+	// hooks also run for files that scripts open and save
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	copied := f.Name() + ".copy"
+	defer os.Remove(copied)
+	fmt.Fprintf(script, "(edit-file %q)\n(write-file %q \"copy\n\")\n", f.Name(), copied)
+	script.Close()
+	typeKeys(c, `(add-hook "on-open" (lambda (name) (buffer-set 'opened name)))`)
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, ":source "+script.Name())
+	pressKey(c, gott.KeyEnter)
+	b := e.GetActiveWindow().GetBuffer()
+	if b.GetFileName() != f.Name() {
+		t.Fatalf("The script didn't open %s: %s", f.Name(), c.GetMessageBarText(200))
+	}
+	value, ok = b.GetVariable("opened").(*golisp.Data)
+	if !ok || golisp.StringValue(value) != f.Name() {
+		t.Errorf("Open hook didn't fire for a script")
+	}
+	value, ok = b.GetVariable("saved").(*golisp.Data)
+	if !ok || golisp.StringValue(value) != copied {
+		t.Errorf("Save hook didn't fire for a script")
+	}
+
+	// appending and writing ranges also run the hook
+	for command, path := range map[string]string{":w >> " + copied: copied, ":1,1w " + f.Name(): f.Name()} {
+		b.SetVariable("saved", nil)
+		typeKeys(c, command)
+		pressKey(c, gott.KeyEnter)
+		if value, ok := b.GetVariable("saved").(*golisp.Data); !ok || golisp.StringValue(value) != path {
+			t.Errorf("Save hook didn't fire for %s", command)
+		}
+	}
+}
+
+func TestSwapFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("saved\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "Aunsaved")
+	pressKey(c, gott.KeyEsc)
+	// swap files are written when input pauses, without any other configuration
+	swap := e.GetActiveWindow().GetBuffer().GetSwapFileName()
+	defer os.Remove(swap)
+	if event := c.NextEvent(display.NewDisplay(gott.Size{Rows: 10, Cols: 40})); event != nil {
+		t.Errorf("Unexpected event while input is paused: %+v", event)
+	}
+	if _, err := os.Stat(swap); err != nil {
+		t.Fatalf("Swap file wasn't written while input paused: %+v", err)
+	}
+	// make sure the swap file is newer than the file
+	past := time.Now().Add(-time.Minute)
+	os.Chtimes(f.Name(), past, past)
+
+	// a new editor recovers the changes from the swap file
+	e2 := editor.NewEditor()
+	if err := e2.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c2 := commander.NewCommander(e2)
+	if string(e2.Bytes()) != "savedunsaved\n" {
+		t.Errorf("Unexpected recovered text: %q", e2.Bytes())
+	}
+	if !e2.GetActiveWindow().GetBuffer().GetModified() {
+		t.Errorf("Recovered buffer isn't modified")
+	}
+	if !strings.Contains(c2.GetMessageBarText(200), "Recovered") {
+		t.Errorf("Recovery wasn't reported: %s", c2.GetMessageBarText(200))
+	}
+
+	// saving removes the swap file
+	typeKeys(c2, ":w")
+	pressKey(c2, gott.KeyEnter)
+	if _, err := os.Stat(swap); !os.IsNotExist(err) {
+		t.Errorf("Swap file wasn't removed after saving")
+	}
+	if text, _ := ioutil.ReadFile(f.Name()); string(text) != "savedunsaved\n" {
+		t.Errorf("Unexpected saved text: %q", text)
+	}
+}
+
+func TestReloadActiveBuffer(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("saved\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "Aunsaved")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, ":e")
+	pressKey(c, gott.KeyEnter)
+	if string(e.Bytes()) != "savedunsaved\n" {
+		t.Errorf("Modified file was reloaded without !: %q", e.Bytes())
+	}
+	typeKeys(c, ":e!")
+	pressKey(c, gott.KeyEnter)
+	if string(e.Bytes()) != "saved\n" || e.GetActiveWindow().GetBuffer().GetModified() {
+		t.Errorf("File wasn't reloaded: %q", e.Bytes())
+	}
+	// the edits can't be undone after a reload
+	typeKeys(c, "u")
+	if string(e.Bytes()) != "saved\n" {
+		t.Errorf("Undo changed the reloaded file: %q", e.Bytes())
+	}
+
+	// buffers without files can't be reloaded
+	typeKeys(c, ":new")
+	pressKey(c, gott.KeyEnter)
+	if err := e.ReloadActiveBuffer(); err == nil {
+		t.Errorf("Buffer without a file was reloaded")
+	}
+}
+
+func TestEarlierAndLater(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	original := string(e.Bytes())
+	// delete the first three characters, one at a time
+	typeKeys(c, "xxx")
+	if !strings.HasPrefix(string(e.Bytes()), " GETTYSBURG") {
+		t.Errorf("Unexpected text after edits: %s", e.Bytes()[0:20])
+	}
+	typeKeys(c, ":earlier 2")
+	pressKey(c, gott.KeyEnter)
+	if !strings.HasPrefix(string(e.Bytes()), "HE GETTYSBURG") {
+		t.Errorf("Unexpected text after :earlier 2: %s", e.Bytes()[0:20])
+	}
+	typeKeys(c, ":later 1")
+	pressKey(c, gott.KeyEnter)
+	if !strings.HasPrefix(string(e.Bytes()), "E GETTYSBURG") {
+		t.Errorf("Unexpected text after :later 1: %s", e.Bytes()[0:20])
+	}
+	typeKeys(c, ":earlier 1h")
+	pressKey(c, gott.KeyEnter)
+	if string(e.Bytes()) != original {
+		t.Errorf("Edits remain after :earlier 1h: %s", e.Bytes()[0:20])
+	}
+	pressKey(c, gott.KeyCtrlR)
+	pressKey(c, gott.KeyCtrlR)
+	pressKey(c, gott.KeyCtrlR)
+	if !strings.HasPrefix(string(e.Bytes()), " GETTYSBURG") {
+		t.Errorf("Unexpected text after redoing edits: %s", e.Bytes()[0:20])
+	}
+	// new edits discard undone ones
+	typeKeys(c, "ux")
+	pressKey(c, gott.KeyCtrlR)
+	if !strings.HasPrefix(string(e.Bytes()), " GETTYSBURG") {
+		t.Errorf("Unexpected text after redoing a discarded edit: %s", e.Bytes()[0:20])
+	}
+}
+
+func TestKillRing(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	// THE GETTYSBURG ADDRESS:
+	typeKeys(c, "dwdwdw")
+	if ring := e.GetKillRing(); len(ring) != 3 {
+		t.Fatalf("Unexpected kill ring: %q", ring)
+	}
+	// yank into an empty row, then cycle through older deletions
+	typeKeys(c, "jj")
+	pressKey(c, gott.KeyCtrlY)
+	buffer := e.GetActiveWindow().GetBuffer()
+	if row := buffer.TextFromPosition(2, 0); row != "ADDRESS:" {
+		t.Errorf("Unexpected yanked text: %q", row)
+	}
+	for _, expected := range []string{"GETTYSBURG ", "THE ", "ADDRESS:"} {
+		c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: 'y', Mod: gott.ModAlt})
+		if row := buffer.TextFromPosition(2, 0); row != expected {
+			t.Errorf("Unexpected text after yank-pop: %q expected %q", row, expected)
+		}
+	}
+	// yank-pop only follows a yank
+	typeKeys(c, "j")
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: 'y', Mod: gott.ModAlt})
+	if row := buffer.TextFromPosition(2, 0); row != "ADDRESS:" {
+		t.Errorf("Unexpected text after a late yank-pop: %q", row)
+	}
+}
+
+func TestBufferText(t *testing.T) {
+	e := setup(t)
+	b := e.GetActiveWindow().GetBuffer()
+	if row := b.GetRowString(0); row != "THE GETTYSBURG ADDRESS:" {
+		t.Errorf("Unexpected row 0: %q", row)
+	}
+	if row := b.GetRowString(1); row != "" {
+		t.Errorf("Unexpected row 1: %q", row)
+	}
+	if row := b.GetRowString(1000); row != "" {
+		t.Errorf("Unexpected text for a missing row: %q", row)
+	}
+	if text := b.GetText(gott.Point{Row: 0, Col: 4}, gott.Point{Row: 0, Col: 14}); text != "GETTYSBURG" {
+		t.Errorf("Unexpected text in a row: %q", text)
+	}
+	text := b.GetText(gott.Point{Row: 0, Col: 15}, gott.Point{Row: 3, Col: 4})
+	if text != "ADDRESS:\n\n\nFour" {
+		t.Errorf("Unexpected text in a range of rows: %q", text)
+	}
+}
+
+func TestChangeListener(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	changes := 0
+	e.GetActiveWindow().GetBuffer().AddChangeListener(func(b gott.Buffer) {
+		changes++
+	})
+	typeKeys(c, "jjll")
+	if changes != 0 {
+		t.Errorf("Cursor movement was reported as a change")
+	}
+	typeKeys(c, "ifoo")
+	pressKey(c, gott.KeyEsc)
+	if changes == 0 {
+		t.Errorf("Insert wasn't reported as a change")
+	}
+	changes = 0
+	typeKeys(c, "x")
+	if changes == 0 {
+		t.Errorf("Delete wasn't reported as a change")
+	}
+}
+
+func TestMinimapRows(t *testing.T) {
+	cases := []struct {
+		RowCount int
+		Height   int
+		Expected []int
+	}{
+		{100, 10, []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}},
+		{10, 4, []int{0, 2, 5, 7}},
+		{3, 10, []int{0, 1, 2}},
+		{0, 10, []int{}},
+	}
+	for _, tc := range cases {
+		rows := editor.MinimapRows(tc.RowCount, tc.Height)
+		if fmt.Sprintf("%v", rows) != fmt.Sprintf("%v", tc.Expected) {
+			t.Errorf("Unexpected minimap rows for %d rows in %d: %v expected %v",
+				tc.RowCount, tc.Height, rows, tc.Expected)
+		}
+	}
+}
+
+func TestMinimap(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "(set-minimap #t)")
+	pressKey(c, gott.KeyEnter)
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 80})
+	d.Render(e, c)
+	// the last columns of each text row summarize the buffer
+	for i := 0; i < 8; i++ {
+		row := d.GetRowText(i)
+		if len(row) < 77 || strings.Trim(row[76:], " .:") != "" {
+			t.Errorf("Unexpected minimap in row %d: %q", i, row)
+		}
+	}
+}
+
+func TestMatchingBracket(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("func f() {\n  if (a[0]) {\n  }\n}\nx ( y\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	theme := e.GetTheme()
+
+	// the brace that closes the function is highlighted
+	e.SetCursor(gott.Point{Row: 0, Col: 9})
+	d.Render(e, c)
+	if cell := d.GetCell(gott.Point{Row: 3, Col: 0}); cell.Ch != '}' || cell.Color != theme.Match {
+		t.Errorf("Matching brace wasn't highlighted: %+v", cell)
+	}
+	typeKeys(c, "%")
+	if cursor := e.GetCursor(); cursor.Row != 3 || cursor.Col != 0 {
+		t.Errorf("Unexpected cursor after %%: %+v", cursor)
+	}
+
+	// nested brackets are skipped
+	e.SetCursor(gott.Point{Row: 1, Col: 12})
+	typeKeys(c, "%")
+	if cursor := e.GetCursor(); cursor.Row != 2 || cursor.Col != 2 {
+		t.Errorf("Unexpected cursor after %% on a nested brace: %+v", cursor)
+	}
+	e.SetCursor(gott.Point{Row: 1, Col: 10})
+	typeKeys(c, "%")
+	if cursor := e.GetCursor(); cursor.Row != 1 || cursor.Col != 5 {
+		t.Errorf("Unexpected cursor after %% on a closing parenthesis: %+v", cursor)
+	}
+
+	// unmatched brackets are marked as errors
+	e.SetCursor(gott.Point{Row: 4, Col: 2})
+	d.Render(e, c)
+	if cell := d.GetCell(gott.Point{Row: 4, Col: 2}); cell.Ch != '(' || cell.Color != theme.Error {
+		t.Errorf("Unmatched bracket wasn't marked: %+v", cell)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	output := "<standard input>:3:14: expected ';', found 'IDENT' x\n" +
+		"<standard input>:7:1: expected declaration, found '}'\n"
+	diagnostics := editor.ParseGofmtErrors(output)
+	expected := []gott.Diagnostic{
+		{Row: 2, Col: 13, Message: "expected ';', found 'IDENT' x"},
+		{Row: 6, Col: 0, Message: "expected declaration, found '}'"},
+	}
+	if fmt.Sprintf("%+v", diagnostics) != fmt.Sprintf("%+v", expected) {
+		t.Errorf("Unexpected diagnostics: %+v", diagnostics)
+	}
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.GetActiveWindow().GetBuffer().SetDiagnostics(diagnostics)
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 80})
+	e.SetCursor(gott.Point{Row: 2, Col: 0})
+	d.Render(e, c)
+	if row := d.GetRowText(2); row != ">" {
+		t.Errorf("Unexpected gutter: %q", row)
+	}
+	if row := d.GetRowText(3); row != "  Four score and seven years ago our fathers brought forth on this" {
+		t.Errorf("Unexpected text beside the gutter: %q", row)
+	}
+	if row := d.GetRowText(9); row != "3:14: expected ';', found 'IDENT' x" {
+		t.Errorf("Unexpected message: %q", row)
+	}
+}
+
+func TestDiagnosticMotion(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.GetActiveWindow().GetBuffer().SetDiagnostics([]gott.Diagnostic{
+		{Row: 20, Col: 4, Message: "third"},
+		{Row: 3, Col: 10, Message: "first"},
+		{Row: 20, Col: 0, Message: "second"},
+	})
+	e.SetCursor(gott.Point{Row: 5, Col: 0})
+	for _, expected := range []gott.Point{{Row: 20, Col: 0}, {Row: 20, Col: 4}, {Row: 3, Col: 10}, {Row: 20, Col: 0}} {
+		typeKeys(c, "]d")
+		if cursor := e.GetCursor(); cursor != expected {
+			t.Errorf("Unexpected cursor after ]d: %+v expected %+v", cursor, expected)
+		}
+	}
+	for _, expected := range []gott.Point{{Row: 3, Col: 10}, {Row: 20, Col: 4}} {
+		typeKeys(c, "[d")
+		if cursor := e.GetCursor(); cursor != expected {
+			t.Errorf("Unexpected cursor after [d: %+v expected %+v", cursor, expected)
+		}
+	}
+	if text := c.GetMessageBarText(80); text != "21:5: third" {
+		t.Errorf("Unexpected message: %q", text)
+	}
+}
+
+func TestSpellCheck(t *testing.T) {
+	checker := editor.NewSpellChecker([]string{"four", "score", "and", "seven", "years", "ago", "our", "brought", "forth", "on", "this"})
+	if checker.Check("fathers") || !checker.Check("Four") {
+		t.Errorf("Unexpected spell check results")
+	}
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.(*editor.Editor).SetSpellChecker(checker)
+	typeKeys(c, "(set-spell #t)")
+	pressKey(c, gott.KeyEnter)
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 80})
+	d.Render(e, c)
+	theme := e.GetTheme()
+	if cell := d.GetCell(gott.Point{Row: 3, Col: 35}); cell.Ch != 'f' || cell.Color != theme.Error {
+		t.Errorf("Misspelled word wasn't marked: %+v", cell)
+	}
+	if cell := d.GetCell(gott.Point{Row: 3, Col: 5}); cell.Ch != 's' || cell.Color == theme.Error {
+		t.Errorf("Correct word was marked: %+v", cell)
+	}
+
+	// move between misspellings
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	typeKeys(c, "]s")
+	if cursor := e.GetCursor(); cursor.Row != 3 || cursor.Col != 35 {
+		t.Errorf("Unexpected cursor after ]s: %+v", cursor)
+	}
+	typeKeys(c, "]s")
+	if cursor := e.GetCursor(); cursor.Row != 4 || cursor.Col != 0 {
+		t.Errorf("Unexpected cursor after a second ]s: %+v", cursor)
+	}
+	typeKeys(c, "[s")
+	if cursor := e.GetCursor(); cursor.Row != 3 || cursor.Col != 35 {
+		t.Errorf("Unexpected cursor after [s: %+v", cursor)
+	}
+}
+
+func TestSpellAdd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	saved := editor.UserDictionary
+	editor.UserDictionary = dir + "/spell"
+	defer func() { editor.UserDictionary = saved }()
+
+	checker := editor.NewSpellChecker([]string{"four", "score", "and", "seven", "years", "ago", "our"})
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.(*editor.Editor).SetSpellChecker(checker)
+	if checker.Check("fathers") {
+		t.Errorf("Unexpected spell check result for fathers")
+	}
+	e.SetCursor(gott.Point{Row: 3, Col: 37})
+	typeKeys(c, "zg")
+	if !checker.Check("fathers") {
+		t.Errorf("Added word is still flagged")
+	}
+	typeKeys(c, "(spell-add \"brought\")")
+	pressKey(c, gott.KeyEnter)
+	if !checker.Check("brought") {
+		t.Errorf("Added word is still flagged")
+	}
+
+	// added words are saved and loaded into new spell checkers
+	loaded := editor.NewSpellChecker(nil)
+	if err := loaded.Load(editor.UserDictionary); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Check("Fathers") || !loaded.Check("brought") || loaded.Check("score") {
+		t.Errorf("Unexpected words in the user dictionary")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("one\ntwo\nthree\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, ":changes")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); message != "No changes since the last save" {
+		t.Errorf("Unexpected message: %q", message)
+	}
+
+	// changes are reported against the last save
+	typeKeys(c, "ddAfirst")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, ":w")
+	pressKey(c, gott.KeyEnter)
+	e.SetCursor(gott.Point{Row: 1, Col: 0})
+	typeKeys(c, "cwTHREE")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, "ofour")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, ":changes")
+	pressKey(c, gott.KeyEnter)
+	expected := "-   2 three\n+   2 THREE\n+   3 four"
+	if text := string(e.Bytes()); text != expected {
+		t.Errorf("Unexpected changes:\n%s", text)
+	}
+}
+
+func TestChangeSigns(t *testing.T) {
+	saved := []byte("one\ntwo\nthree\nfour\nfive\n")
+	current := []byte("one\nTWO\nthree\nthree and a half\nfive\n")
+	signs := editor.ChangeSigns(saved, current)
+	expected := []rune{0, '~', 0, '~', 0, 0}
+	if string(signs) != string(expected) {
+		t.Errorf("Unexpected change signs: %q", signs)
+	}
+	signs = editor.ChangeSigns(saved, []byte("zero\none\nthree\nfour\n"))
+	expected = []rune{'+', 0, '-', 0, '-'}
+	if string(signs) != string(expected) {
+		t.Errorf("Unexpected change signs: %q", signs)
+	}
+
+	// large texts are compared without comparing every pair of lines
+	var first, second []string
+	for i := 0; i < 50000; i++ {
+		first = append(first, fmt.Sprintf("first %d", i))
+		second = append(second, fmt.Sprintf("second %d", i))
+	}
+	start := time.Now()
+	signs = editor.ChangeSigns([]byte(strings.Join(first, "\n")), []byte(strings.Join(second, "\n")))
+	if strings.Count(string(signs), "~") != len(second) {
+		t.Errorf("Expected every row of unrelated texts to differ")
+	}
+	changed := append([]string(nil), first...)
+	changed[100] = "changed"
+	changed = append(changed[:200], changed[201:]...)
+	signs = editor.ChangeSigns([]byte(strings.Join(first, "\n")), []byte(strings.Join(changed, "\n")))
+	if strings.Count(string(signs), "\x00") != len(changed)-2 || signs[100] != '~' || signs[200] != '-' {
+		t.Errorf("Unexpected signs for a large text with two changes")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Comparing large texts took %v", elapsed)
+	}
+
+	// signs are drawn in the gutter
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "(set-change-signs #t)")
+	pressKey(c, gott.KeyEnter)
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	typeKeys(c, "xjdd")
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 80})
+	d.Render(e, c)
+	if text := d.GetRowText(3); !strings.HasPrefix(text, "~ our score") {
+		t.Errorf("Unexpected gutter for a changed row: %q", text)
+	}
+	if text := d.GetRowText(4); !strings.HasPrefix(text, "- ") {
+		t.Errorf("Unexpected gutter after a removed row: %q", text)
+	}
+	if text := d.GetRowText(0); !strings.HasPrefix(text, "  THE") {
+		t.Errorf("Unexpected gutter for an unchanged row: %q", text)
+	}
+}
+
+func TestUndoToSave(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("one\ntwo\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "Afirst")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, ":w")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "jddOthree")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, "x")
+	typeKeys(c, ":u!")
+	pressKey(c, gott.KeyEnter)
+	if string(e.Bytes()) != "onefirst\ntwo\n" {
+		t.Errorf("Unexpected contents after :u!: %q", e.Bytes())
+	}
+	if e.GetActiveWindow().GetBuffer().GetModified() {
+		t.Errorf("Buffer is modified after :u!")
+	}
+	// the edits before the save remain
+	typeKeys(c, "u")
+	if string(e.Bytes()) != "one\ntwo\n" {
+		t.Errorf("Unexpected contents after undo: %q", e.Bytes())
+	}
+
+	// a saved version that can't be reached leaves the buffer unchanged
+	typeKeys(c, "jdd")
+	typeKeys(c, ":u!")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(200); !strings.Contains(message, "Not enough undo history") {
+		t.Errorf("Unexpected message after :u!: %q", message)
+	}
+	if string(e.Bytes()) != "one\n" {
+		t.Errorf("Unexpected contents after failed :u!: %q", e.Bytes())
+	}
+
+	// edits in other buffers aren't undone
+	e.SplitWindowVertically()
+	if err := e.ReadFileIntoActiveWindow("test/gettysburg-address.txt"); err != nil {
+		t.Fatal(err)
+	}
+	other := e.GetActiveWindow().GetBuffer()
+	text := string(other.GetBytes())
+	typeKeys(c, "dd")
+	e.SelectWindowNext()
+	typeKeys(c, "Ax")
+	pressKey(c, gott.KeyEsc)
+	edited := string(e.Bytes())
+	typeKeys(c, ":u!")
+	pressKey(c, gott.KeyEnter)
+	if string(e.Bytes()) != edited {
+		t.Errorf("Unexpected contents after :u! with two buffers: %q", e.Bytes())
+	}
+	if string(other.GetBytes()) == text {
+		t.Errorf(":u! undid an edit in another buffer")
+	}
+}
+
+func TestDiffThis(t *testing.T) {
+	a, b := editor.DiffRows([]byte("one\ntwo\nthree\nfour\n"), []byte("one\nTWO\nfour\nfive\n"))
+	if string(a) != string([]rune{0, '~', '-', 0, 0}) {
+		t.Errorf("Unexpected signs for the first text: %q", a)
+	}
+	if string(b) != string([]rune{0, '~', 0, '+', 0}) {
+		t.Errorf("Unexpected signs for the second text: %q", b)
+	}
+
+	// texts with carriage returns and no newlines are compared by line
+	a, b = editor.DiffRows([]byte("one\rtwo\rthree\r"), []byte("one\rTWO\rthree\r"))
+	if string(a) != string([]rune{0, '~', 0, 0}) || string(b) != string([]rune{0, '~', 0, 0}) {
+		t.Errorf("Unexpected signs for texts with carriage returns: %q %q", a, b)
+	}
+
+	// large texts are compared without comparing every pair of lines
+	var first, second []string
+	for i := 0; i < 50000; i++ {
+		first = append(first, fmt.Sprintf("first %d", i))
+		second = append(second, fmt.Sprintf("second %d", i))
+	}
+	start := time.Now()
+	a, b = editor.DiffRows([]byte(strings.Join(first, "\n")), []byte(strings.Join(second, "\n")))
+	if strings.Count(string(a), "~") != len(first) || strings.Count(string(b), "~") != len(second) {
+		t.Errorf("Expected every row of unrelated texts to differ")
+	}
+	changed := append([]string(nil), first...)
+	changed[100] = "changed"
+	changed = append(changed[:200], changed[201:]...)
+	a, b = editor.DiffRows([]byte(strings.Join(first, "\n")), []byte(strings.Join(changed, "\n")))
+	if strings.Count(string(a), "\x00") != len(first)-2 || a[100] != '~' || a[200] != '-' || b[100] != '~' {
+		t.Errorf("Unexpected signs for a large text with two changes")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Comparing large texts took %v", elapsed)
+	}
+
+	// compare the source with a copy that has a changed and a removed line
+	source, err := ioutil.ReadFile(source)
+	if err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	lines := strings.Split(string(source), "\n")
+	lines[0] = "THE GETTYSBURG SPEECH:"
+	lines = append(lines[0:3], lines[4:]...)
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte(strings.Join(lines, "\n")))
+	f.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	e.SetSize(gott.Size{Rows: 20, Cols: 80})
+	e.LayoutWindows()
+	typeKeys(c, ":diffthis")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, ":split "+f.Name())
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, ":diffthis")
+	pressKey(c, gott.KeyEnter)
+	d := display.NewDisplay(gott.Size{Rows: 20, Cols: 80})
+	d.Render(e, c)
+	theme := e.GetTheme()
+	plain := gott.Color(0xff) // unhighlighted text
+	for _, check := range []struct {
+		row   int
+		color gott.Color
+	}{
+		{0, theme.Keyword}, // changed in the copy
+		{3, plain},         // unchanged
+		{9, theme.Keyword}, // changed in the source
+		{12, theme.Error},  // removed from the source
+		{13, plain},
+	} {
+		if cell := d.GetCell(gott.Point{Row: check.row, Col: 0}); cell.Color != check.color {
+			t.Errorf("Unexpected color in row %d: %+v", check.row, cell)
+		}
+	}
+
+	typeKeys(c, ":diffoff")
+	pressKey(c, gott.KeyEnter)
+	d.Render(e, c)
+	if cell := d.GetCell(gott.Point{Row: 12, Col: 0}); cell.Color != plain {
+		t.Errorf("Diff colors remain after :diffoff: %+v", cell)
+	}
+}
+
+func TestReflow(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	original := "title\n\n  Four score and seven years ago our fathers brought forth on this continent\n" +
+		"  a new nation, conceived in liberty and dedicated to the\n" +
+		"  proposition that all men are created equal, antidisestablishmentarianism.\n\nend\n"
+	f.Write([]byte(original))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "(set-textwidth 20)")
+	pressKey(c, gott.KeyEnter)
+	e.SetCursor(gott.Point{Row: 3, Col: 5})
+	typeKeys(c, "gq")
+	b := e.GetActiveWindow().GetBuffer()
+	if b.GetRowString(0) != "title" || b.GetRowString(1) != "" {
+		t.Errorf("Rows before the paragraph changed")
+	}
+	var words []string
+	row := 2
+	for ; b.GetRowString(row) != ""; row++ {
+		line := b.GetRowString(row)
+		if !strings.HasPrefix(line, "  ") {
+			t.Errorf("Reflowed row %d lost its indentation: %q", row, line)
+		}
+		fields := strings.Fields(line)
+		if len(line) > 20 && len(fields) > 1 {
+			t.Errorf("Reflowed row %d is too long: %q", row, line)
+		}
+		words = append(words, fields...)
+	}
+	if len(words) != 31 || b.GetRowString(row+1) != "end" {
+		t.Errorf("Unexpected words after reflow: %q", words)
+	}
+	if row != 15 || b.GetRowString(14) != "  antidisestablishmentarianism." {
+		t.Errorf("Unexpected rows after reflow: %q", b.GetBytes())
+	}
+	// the reflow is undone in one step
+	typeKeys(c, "u")
+	if string(b.GetBytes()) != original {
+		t.Errorf("Unexpected text after undo: %q", b.GetBytes())
+	}
+	typeKeys(c, ":3,3reflow")
+	pressKey(c, gott.KeyEnter)
+	if b.GetRowString(2) != "  Four score and" || b.GetRowString(7) != "  a new nation, conceived in liberty and dedicated to the" {
+		t.Errorf("Unexpected text after :reflow: %q", b.GetBytes())
+	}
+}
+
+func TestAutoWrap(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("existing text that is long\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	typeKeys(c, "(set-textwidth 20)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "(set-autowrap #t)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "ofour score and  seven years ago our fathers brought forth")
+	pressKey(c, gott.KeyEsc)
+	expected := "existing text that is long\nfour score and\nseven years ago our\nfathers brought\nforth\n"
+	if text := string(e.Bytes()); text != expected {
+		t.Errorf("Unexpected text after wrapping: %q", text)
+	}
+	// existing text is not wrapped
+	e.SetCursor(gott.Point{Row: 0, Col: 0})
+	typeKeys(c, "A!")
+	pressKey(c, gott.KeyEsc)
+	if row := e.GetActiveWindow().GetBuffer().GetRowString(0); row != "existing text that is long!" {
+		t.Errorf("Unexpected row after appending: %q", row)
+	}
+	// wrapping is undone with the insert
+	typeKeys(c, "uu")
+	if text := string(e.Bytes()); text != "existing text that is long\n" {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	// wrapping counts characters and stays within the current insert
+	e.LoadBytes([]byte("aaaaaaa b\n"))
+	typeKeys(c, "(set-textwidth 10)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "Aéé")
+	pressKey(c, gott.KeyEsc)
+	typeKeys(c, "u")
+	if text := string(e.Bytes()); text != "aaaaaaa b\n" {
+		t.Errorf("Unexpected text after undoing a multibyte insert: %q", text)
+	}
+}
+
+func TestCommandPalette(t *testing.T) {
+	candidates := []string{"redraw", "delete-word", "down", "delete-row", "delete-around-word", ":windows"}
+	ranked := commander.FuzzyRank("drw", candidates)
+	expected := []string{"delete-row", "delete-around-word", "redraw"}
+	if strings.Join(ranked, " ") != strings.Join(expected, " ") {
+		t.Errorf("Unexpected ranking: %q", ranked)
+	}
+	if _, ok := commander.FuzzyMatch("DW", "delete-word"); !ok {
+		t.Errorf("Matching should ignore case")
+	}
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	pressKey(c, gott.KeyCtrlP)
+	typeKeys(c, "delrow")
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	d.Render(e, c)
+	if text := d.GetRowText(9); text != "> delrow" {
+		t.Errorf("Unexpected message bar: %q", text)
+	}
+	if text := d.GetRowText(8); text != " delete-row" {
+		t.Errorf("Unexpected best match: %q", text)
+	}
+	pressKey(c, gott.KeyEnter)
+	if row := e.GetActiveWindow().GetBuffer().GetRowString(0); row != "" {
+		t.Errorf("The palette didn't delete a row: %q", row)
+	}
+
+	// ex commands can be run from the palette
+	pressKey(c, gott.KeyCtrlP)
+	typeKeys(c, ":new")
+	pressKey(c, gott.KeyEnter)
+	if name := e.GetActiveWindow().GetBuffer().GetName(); name != "*scratch*" {
+		t.Errorf("The palette didn't open a scratch buffer: %q", name)
+	}
+
+	// backspace removes a character, not a byte
+	pressKey(c, gott.KeyCtrlP)
+	typeKeys(c, "éx")
+	pressKey(c, gott.KeyBackspace2)
+	d.Render(e, c)
+	if text := d.GetRowText(9); text != "> é" {
+		t.Errorf("Unexpected message bar after backspace: %q", text)
+	}
+	pressKey(c, gott.KeyEsc)
+
+	// matches are cut to the display width by runes
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := dir + "/" + strings.Repeat("é", 40) + ".txt"
+	if err := ioutil.WriteFile(path, []byte("text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e.ReadFile(path)
+	typeKeys(c, ":recent")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "é")
+	d.Render(e, c)
+	if text := d.GetRowText(8); text != string([]rune(" " + path)[0:40]) {
+		t.Errorf("Unexpected multibyte match: %q", text)
+	}
+	pressKey(c, gott.KeyEsc)
+}
+
+func TestFileFinder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, path := range []string{
+		"main.go",
+		"editor/buffer.go",
+		"editor/window.go",
+		"commander/commander.go",
+		".git/objects/buffer",
+		"a/b/c/d/e/f/g/h/i/deep.txt",
+	} {
+		path = dir + "/" + path
+		if err := os.MkdirAll(path[0:strings.LastIndex(path, "/")], 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := commander.WalkFiles(dir)
+	expected := []string{"commander/commander.go", "editor/buffer.go", "editor/window.go", "main.go"}
+	if strings.Join(files, " ") != strings.Join(expected, " ") {
+		t.Errorf("Unexpected files: %q", files)
+	}
+	ranked := commander.FuzzyRank("buf", files)
+	if len(ranked) != 1 || ranked[0] != "editor/buffer.go" {
+		t.Errorf("Unexpected ranking: %q", ranked)
+	}
+	ranked = commander.FuzzyRank("eo", files)
+	if len(ranked) != 3 || ranked[0] != "editor/buffer.go" {
+		t.Errorf("Unexpected ranking: %q", ranked)
+	}
+
+	// open the best match
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	e := editor.NewEditor()
+	c := commander.NewCommander(e)
+	typeKeys(c, ":find")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "edwin")
+	pressKey(c, gott.KeyEnter)
+	if name := e.GetActiveWindow().GetBuffer().GetFileName(); name != "editor/window.go" {
+		t.Errorf("Unexpected file opened: %q", name)
+	}
+}
+
+func TestRecentFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gott")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := dir+"/a.txt", dir+"/b.txt"
+	for _, path := range []string{a, b} {
+		if err := ioutil.WriteFile(path, []byte(path+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := dir + "/recent"
+
+	e := editor.NewEditor()
+	if err := e.LoadRecentFiles(recent); err != nil {
+		t.Fatal(err)
+	}
+	c := commander.NewCommander(e)
+	e.ReadFile(a)
+	typeKeys(c, "(edit-file \""+b+"\")")
+	pressKey(c, gott.KeyEnter)
+	e.ReadFile(a)
+	if files := e.GetRecentFiles(); strings.Join(files, " ") != a+" "+b {
+		t.Errorf("Unexpected recent files: %q", files)
+	}
+
+	// the list is saved and loaded at startup
+	e = editor.NewEditor()
+	if err := e.LoadRecentFiles(recent); err != nil {
+		t.Fatal(err)
+	}
+	if files := e.GetRecentFiles(); strings.Join(files, " ") != a+" "+b {
+		t.Errorf("Unexpected recent files after loading: %q", files)
+	}
+	c = commander.NewCommander(e)
+	typeKeys(c, ":recent")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "b.txt")
+	pressKey(c, gott.KeyEnter)
+	if name := e.GetActiveWindow().GetBuffer().GetFileName(); name != b {
+		t.Errorf("Unexpected file opened: %q", name)
+	}
+	if files := e.GetRecentFiles(); strings.Join(files, " ") != b+" "+a {
+		t.Errorf("Unexpected recent files after reopening: %q", files)
+	}
+}
+
+func TestSubstitutionPreview(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, ":%s/n")
+	if pattern := e.GetHighlightPattern(); pattern != "n" {
+		t.Errorf("Unexpected preview pattern: %q", pattern)
+	}
+	typeKeys(c, "ation")
+	if pattern := e.GetHighlightPattern(); pattern != "nation" {
+		t.Errorf("Unexpected preview pattern: %q", pattern)
+	}
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 80})
+	d.Render(e, c)
+	// "continent a new nation, conceived..."
+	if cell := d.GetCell(gott.Point{Row: 4, Col: 16}); cell.Ch != 'n' || !cell.Reversed {
+		t.Errorf("Match wasn't highlighted: %+v", cell)
+	}
+	if cell := d.GetCell(gott.Point{Row: 4, Col: 15}); cell.Reversed {
+		t.Errorf("Text before a match was highlighted: %+v", cell)
+	}
+	// incomplete patterns don't change the preview
+	typeKeys(c, "(")
+	if pattern := e.GetHighlightPattern(); pattern != "nation" {
+		t.Errorf("Unexpected preview pattern: %q", pattern)
+	}
+	pressKey(c, gott.KeyEsc)
+	if pattern := e.GetHighlightPattern(); pattern != "" {
+		t.Errorf("Preview wasn't cleared: %q", pattern)
+	}
+
+	// the preview ends when the substitution is performed
+	typeKeys(c, ":s/Four/Five/")
+	if pattern := e.GetHighlightPattern(); pattern != "Four" {
+		t.Errorf("Unexpected preview pattern: %q", pattern)
+	}
+	pressKey(c, gott.KeyEnter)
+	if pattern := e.GetHighlightPattern(); pattern != "" {
+		t.Errorf("Preview wasn't cleared: %q", pattern)
+	}
+	typeKeys(c, ":w")
+	if pattern := e.GetHighlightPattern(); pattern != "" {
+		t.Errorf("Unexpected preview for a write: %q", pattern)
+	}
+}
+
+func TestSearchCenter(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	d := display.NewDisplay(gott.Size{Rows: 11, Cols: 80})
+	d.Render(e, c)
+	// the first match is on row 13
+	typeKeys(c, "/living")
+	pressKey(c, gott.KeyEnter)
+	d.Render(e, c)
+	if text := d.GetRowText(8); !strings.HasPrefix(text, "The brave men, living") {
+		t.Errorf("The match isn't at the bottom of the window without centering: %q", text)
+	}
+
+	typeKeys(c, "gg(set-search-center #t)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "n")
+	d.Render(e, c)
+	if text := d.GetRowText(4); !strings.HasPrefix(text, "The brave men, living") {
+		t.Errorf("The match isn't centered: %q", text)
+	}
+
+	// zz centers the cursor row
+	typeKeys(c, "(set-search-center #f)")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "ggn")
+	d.Render(e, c)
+	typeKeys(c, "zz")
+	d.Render(e, c)
+	if text := d.GetRowText(4); !strings.HasPrefix(text, "The brave men, living") {
+		t.Errorf("The cursor row isn't centered after zz: %q", text)
+	}
+}
+
+func TestPendingCommand(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "3d")
+	if text := c.GetMessageBarText(20); text != strings.Repeat(" ", 18)+"3d" {
+		t.Errorf("Unexpected message bar while a command is pending: %q", text)
+	}
+	typeKeys(c, "d")
+	if text := c.GetMessageBarText(20); text != "" {
+		t.Errorf("Unexpected message bar after the command: %q", text)
+	}
+	if row := e.GetActiveWindow().GetBuffer().GetRowString(0); !strings.HasPrefix(row, "Four score") {
+		t.Errorf("Unexpected first row after 3dd: %q", row)
+	}
+	// Esc cancels a pending command
+	typeKeys(c, "2y")
+	pressKey(c, gott.KeyEsc)
+	if text := c.GetMessageBarText(20); text != "" {
+		t.Errorf("Unexpected message bar after a cancelled command: %q", text)
+	}
+}
+
+func TestLiteralInsert(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "i")
+	pressKey(c, gott.KeyCtrlV)
+	typeKeys(c, "u00e9")
+	pressKey(c, gott.KeyCtrlV)
+	typeKeys(c, "x41")
+	pressKey(c, gott.KeyCtrlV)
+	pressKey(c, gott.KeyTab)
+	// a short code ends at the first key that isn't a hex digit
+	pressKey(c, gott.KeyCtrlV)
+	typeKeys(c, "u2a-")
+	pressKey(c, gott.KeyEsc)
+	if row := e.GetActiveWindow().GetBuffer().GetRowString(0); !strings.HasPrefix(row, "éA\t*-THE GETTYSBURG") {
+		t.Errorf("Unexpected row after literal inserts: %q", row)
+	}
+}
+
+func TestCharacterInfo(t *testing.T) {
+	if info := commander.CharacterInfo('é'); info != "dec=233 hex=e9 U+00E9" {
+		t.Errorf("Unexpected info for é: %q", info)
+	}
+	if info := commander.CharacterInfo('😀'); info != "dec=128512 hex=1f600 U+1F600" {
+		t.Errorf("Unexpected info for 😀: %q", info)
+	}
+	e := setup(t)
+	c := commander.NewCommander(e)
+	typeKeys(c, "ga")
+	if text := c.GetMessageBarText(80); text != "dec=84 hex=54 U+0054" {
+		t.Errorf("Unexpected message for ga: %q", text)
+	}
+	// the second row is empty
+	typeKeys(c, "j")
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "ascii")
+	pressKey(c, gott.KeyEnter)
+	if text := c.GetMessageBarText(80); text != "Nothing under the cursor" {
+		t.Errorf("Unexpected message for :ascii on an empty row: %q", text)
+	}
+}
+
+func TestToggleComment(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.go")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	original := "package main\n\nfunc main() {\n    x := 1\n\n        println(x)\n}\n"
+	f.Write([]byte(original))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	// comment the body of the function at its smallest indentation
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	typeKeys(c, "3gcc")
+	commented := "package main\n\nfunc main() {\n    // x := 1\n\n    //     println(x)\n}\n"
+	if text := string(b.GetBytes()); text != commented {
+		t.Errorf("Unexpected text after commenting: %q", text)
+	}
+	// a partly commented range is commented again
+	e.SetCursor(gott.Point{Row: 2, Col: 0})
+	typeKeys(c, "gcj")
+	if row := b.GetRowString(3); row != "//     // x := 1" {
+		t.Errorf("Unexpected row after commenting a partly commented range: %q", row)
+	}
+	e.PerformUndo()
+	// a fully commented range is uncommented
+	e.SetCursor(gott.Point{Row: 5, Col: 0})
+	typeKeys(c, "gck")
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	typeKeys(c, "gcc")
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after uncommenting: %q", text)
+	}
+	// comment a selection, then undo it
+	e.SetCursor(gott.Point{Row: 0, Col: 0})
+	typeKeys(c, "vjjgc")
+	if row := b.GetRowString(2); row != "// func main() {" {
+		t.Errorf("Unexpected row after commenting a selection: %q", row)
+	}
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	// files without a comment prefix aren't changed
+	c = commander.NewCommander(setup(t))
+	typeKeys(c, "gcc")
+	if text := c.GetMessageBarText(80); text != "No comment prefix for this file" {
+		t.Errorf("Unexpected message for a text file: %q", text)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	original := "start\nx = 1\nname = \"gott\"\nno delimiter\nw=80\n\nend\n"
+	f.Write([]byte(original))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	// align the paragraph at the cursor
+	e.SetCursor(gott.Point{Row: 1, Col: 0})
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "align =")
+	pressKey(c, gott.KeyEnter)
+	aligned := "start\nx    = 1\nname = \"gott\"\nno delimiter\nw    =80\n\nend\n"
+	if text := string(b.GetBytes()); text != aligned {
+		t.Errorf("Unexpected text after align: %q", text)
+	}
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	// align the rows of a selection
+	e.SetCursor(gott.Point{Row: 0, Col: 0})
+	typeKeys(c, "vjjj:align =")
+	pressKey(c, gott.KeyEnter)
+	aligned = "start\nx    = 1\nname = \"gott\"\nno delimiter\nw=80\n\nend\n"
+	if text := string(b.GetBytes()); text != aligned {
+		t.Errorf("Unexpected text after aligning a selection: %q", text)
+	}
+	e.PerformUndo()
+	// align a line range
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "3,5align =")
+	pressKey(c, gott.KeyEnter)
+	aligned = "start\nx = 1\nname = \"gott\"\nno delimiter\nw    =80\n\nend\n"
+	if text := string(b.GetBytes()); text != aligned {
+		t.Errorf("Unexpected text after aligning a range: %q", text)
+	}
+}
+
+func TestUndoCursor(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	for _, keys := range []string{"dw", "x", "ihello", "ahello", "Ahello", "ohello", "Ohello", "cwhello"} {
+		e.SetCursor(gott.Point{Row: 4, Col: 5})
+		typeKeys(c, keys)
+		pressKey(c, gott.KeyEsc)
+		edited := e.GetCursor()
+		// motions between edits don't change where undo and redo go
+		e.SetCursor(gott.Point{Row: 10, Col: 3})
+		e.PerformUndo()
+		if cursor := e.GetCursor(); cursor != (gott.Point{Row: 4, Col: 5}) {
+			t.Errorf("Unexpected cursor after undoing %s: %+v", keys, cursor)
+		}
+		e.SetCursor(gott.Point{Row: 10, Col: 3})
+		e.PerformRedo()
+		if cursor := e.GetCursor(); cursor.Row != edited.Row {
+			t.Errorf("Unexpected cursor after redoing %s: %+v", keys, cursor)
+		}
+		e.PerformUndo()
+	}
+	final(t, e)
+}
+
+func TestVirtualEdit(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	length := len(b.GetRowString(4))
+	// by default, the cursor stays on the last character
+	e.SetCursor(gott.Point{Row: 4, Col: 0})
+	pressKey(c, gott.KeyCtrlE)
+	typeKeys(c, "l")
+	if cursor := e.GetCursor(); cursor.Col != length-1 {
+		t.Errorf("Unexpected cursor at the end of a row: %+v", cursor)
+	}
+	e.SetCursor(gott.Point{Row: 4, Col: length + 5})
+	e.KeepCursorInRow()
+	if cursor := e.GetCursor(); cursor.Col != length-1 {
+		t.Errorf("Unexpected cursor kept in a row: %+v", cursor)
+	}
+	// with virtual edit, it can move just past the last character
+	typeKeys(c, "(set-virtualedit #t)")
+	pressKey(c, gott.KeyEnter)
+	e.SetCursor(gott.Point{Row: 4, Col: 0})
+	pressKey(c, gott.KeyCtrlE)
+	if cursor := e.GetCursor(); cursor.Col != length {
+		t.Errorf("Unexpected cursor at the end of a row with virtual edit: %+v", cursor)
+	}
+	typeKeys(c, "ll")
+	if cursor := e.GetCursor(); cursor.Col != length {
+		t.Errorf("Unexpected cursor after moving right with virtual edit: %+v", cursor)
+	}
+	e.SetCursor(gott.Point{Row: 4, Col: length + 5})
+	e.KeepCursorInRow()
+	if cursor := e.GetCursor(); cursor.Col != length {
+		t.Errorf("Unexpected cursor kept in a row with virtual edit: %+v", cursor)
+	}
+	// moving to a shorter row stops just past its end
+	typeKeys(c, "k")
+	if cursor := e.GetCursor(); cursor.Col != len(b.GetRowString(3)) {
+		t.Errorf("Unexpected cursor after moving up with virtual edit: %+v", cursor)
+	}
+	// text inserted past the end is appended
+	e.SetCursor(gott.Point{Row: 4, Col: length})
+	typeKeys(c, "i!")
+	pressKey(c, gott.KeyEsc)
+	if row := b.GetRowString(4); !strings.HasSuffix(row, "the!") {
+		t.Errorf("Unexpected row after inserting past the end: %q", row)
+	}
+	// and deleting past the end does nothing
+	pressKey(c, gott.KeyCtrlE)
+	typeKeys(c, "x")
+	if row := b.GetRowString(4); !strings.HasSuffix(row, "the!") {
+		t.Errorf("Unexpected row after deleting past the end: %q", row)
+	}
+}
+
+func TestGoalColumn(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	// move up across empty rows to a shorter row and back down
+	e.SetCursor(gott.Point{Row: 3, Col: 40})
+	typeKeys(c, "kk")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 1, Col: 0}) {
+		t.Errorf("Unexpected cursor on an empty row: %+v", cursor)
+	}
+	typeKeys(c, "k")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 0, Col: 22}) {
+		t.Errorf("Unexpected cursor on a short row: %+v", cursor)
+	}
+	typeKeys(c, "3j")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 40}) {
+		t.Errorf("Unexpected cursor after returning to a long row: %+v", cursor)
+	}
+	// horizontal motions set a new goal
+	typeKeys(c, "hkkkjjj")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 39}) {
+		t.Errorf("Unexpected cursor after a horizontal motion: %+v", cursor)
+	}
+	// and so do edits
+	typeKeys(c, "kkkxjjj")
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 21}) {
+		t.Errorf("Unexpected cursor after an edit: %+v", cursor)
+	}
+}
+
+func TestReverseLines(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	original := "one\ntwo\nthree\nfour\nfive\n"
+	f.Write([]byte(original))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	// reverse a line range
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "2,4reverse")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "one\nfour\nthree\ntwo\nfive\n" {
+		t.Errorf("Unexpected text after reversing a range: %q", text)
+	}
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	// reverse a selection
+	e.SetCursor(gott.Point{Row: 3, Col: 0})
+	typeKeys(c, "vj:reverse")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "one\ntwo\nthree\nfive\nfour\n" {
+		t.Errorf("Unexpected text after reversing a selection: %q", text)
+	}
+	e.PerformUndo()
+	// reverse the whole buffer
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "reverse")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "five\nfour\nthree\ntwo\none\n" {
+		t.Errorf("Unexpected text after reversing the buffer: %q", text)
+	}
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after undoing a reverse of the buffer: %q", text)
+	}
+}
+
+func TestTextTransforms(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	original := "Hello, World!\nsecond line\n"
+	f.Write([]byte(original))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	command := func(text string) {
+		c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+		typeKeys(c, text)
+		pressKey(c, gott.KeyEnter)
+	}
+	// rot13 twice restores the original
+	command("rot13")
+	if text := string(b.GetBytes()); text != "Uryyb, Jbeyq!\nfrpbaq yvar\n" {
+		t.Errorf("Unexpected text after rot13: %q", text)
+	}
+	command("rot13")
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after rot13 twice: %q", text)
+	}
+	// base64 round trip
+	command("base64-encode")
+	if text := string(b.GetBytes()); text != "SGVsbG8sIFdvcmxkIQpzZWNvbmQgbGluZQ==\n" {
+		t.Errorf("Unexpected text after base64-encode: %q", text)
+	}
+	command("base64-decode")
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after base64-decode: %q", text)
+	}
+	// a transform is undone in one step
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != "SGVsbG8sIFdvcmxkIQpzZWNvbmQgbGluZQ==\n" {
+		t.Errorf("Unexpected text after undoing base64-decode: %q", text)
+	}
+	e.PerformUndo()
+	// invalid input isn't decoded
+	command("base64-decode")
+	if text := string(b.GetBytes()); text != original {
+		t.Errorf("Unexpected text after decoding invalid base64: %q", text)
+	}
+	if message := c.GetMessageBarText(80); !strings.Contains(message, "illegal base64") {
+		t.Errorf("Unexpected message after decoding invalid base64: %q", message)
+	}
+	// transform the characters of a selection
+	e.SetCursor(gott.Point{Row: 0, Col: 7})
+	e.StartSelection(e.GetCursor())
+	e.SetCursor(gott.Point{Row: 0, Col: 11})
+	typeKeys(c, "(rot13)")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "Hello, Jbeyq!\nsecond line\n" {
+		t.Errorf("Unexpected text after rot13 of a selection: %q", text)
+	}
+	// transform the rows of a selection
+	e.SetCursor(gott.Point{Row: 1, Col: 3})
+	typeKeys(c, "vl:rot13")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "Hello, Jbeyq!\nfrpbaq yvar\n" {
+		t.Errorf("Unexpected text after rot13 of a selection's rows: %q", text)
+	}
+}
+
+func TestJoinSelection(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	rowCount := b.GetRowCount()
+	expected := b.GetRowString(3) + b.GetRowString(4) + b.GetRowString(5) + b.GetRowString(6) + b.GetRowString(7)
+	// join the five rows of a selection
+	e.SetCursor(gott.Point{Row: 3, Col: 10})
+	typeKeys(c, "vjjjjJ")
+	if row := b.GetRowString(3); row != expected {
+		t.Errorf("Unexpected row after joining a selection: %q", row)
+	}
+	if count := b.GetRowCount(); count != rowCount-4 {
+		t.Errorf("Unexpected row count after joining a selection: %d", count)
+	}
+	if _, _, ok := e.GetSelection(); ok {
+		t.Errorf("Expected the selection to be cleared")
+	}
+	// the join is undone in one step
+	e.PerformUndo()
+	if count := b.GetRowCount(); count != rowCount {
+		t.Errorf("Unexpected row count after undo: %d", count)
+	}
+	// join a line range
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "4,6join")
+	pressKey(c, gott.KeyEnter)
+	if count := b.GetRowCount(); count != rowCount-2 {
+		t.Errorf("Unexpected row count after joining a range: %d", count)
+	}
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestSplitLine(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	rowCount := b.GetRowCount()
+	// split in the middle of "score"
+	e.SetCursor(gott.Point{Row: 3, Col: 7})
+	typeKeys(c, "gS")
+	if row := b.GetRowString(3); row != "Four sc" {
+		t.Errorf("Unexpected first row after split: %q", row)
+	}
+	if row := b.GetRowString(4); !strings.HasPrefix(row, "ore and seven years ago") {
+		t.Errorf("Unexpected second row after split: %q", row)
+	}
+	if cursor := e.GetCursor(); cursor.Row != 3 {
+		t.Errorf("Unexpected cursor after split: %+v", cursor)
+	}
+	if count := b.GetRowCount(); count != rowCount+1 {
+		t.Errorf("Unexpected row count after split: %d", count)
+	}
+	// undo rejoins the rows
+	e.PerformUndo()
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 7}) {
+		t.Errorf("Unexpected cursor after undo: %+v", cursor)
+	}
+	e.PerformRedo()
+	if count := b.GetRowCount(); count != rowCount+1 {
+		t.Errorf("Unexpected row count after redo: %d", count)
+	}
+	e.PerformUndo()
+	final(t, e)
+}
+
+func TestReplay(t *testing.T) {
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	// edits recorded as a script of motions and operations
+	script.Write([]byte("(goto-line 4)\n(right 5)\n(delete-word 2)\n(goto-line 1)\n(join-line)\n"))
+	script.Close()
+
+	// make the same edits directly in one buffer
+	expected := setup(t)
+	c := commander.NewCommander(expected)
+	expected.SetCursor(gott.Point{Row: 3, Col: 5})
+	typeKeys(c, "2dw")
+	expected.SetCursor(gott.Point{Row: 0, Col: 0})
+	typeKeys(c, "J")
+
+	// and replay them in another, starting away from the top
+	e := setup(t)
+	c = commander.NewCommander(e)
+	e.SetCursor(gott.Point{Row: 10, Col: 7})
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "replay "+script.Name())
+	pressKey(c, gott.KeyEnter)
+	if text, want := string(e.Bytes()), string(expected.Bytes()); text != want {
+		t.Errorf("Unexpected text after replay: %q, expected %q", text, want)
+	}
+	// missing scripts are reported
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "replay "+script.Name()+".missing")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(200); !strings.Contains(message, "no such file") {
+		t.Errorf("Unexpected message for a missing script: %q", message)
+	}
+}
+
+func TestCharacterNavigation(t *testing.T) {
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	// walk forward across rows to the first "v"
+	script.Write([]byte(`(define (find-char target)
+  (if (or (equal? (cursor-char) target) (eq? (char-forward) 2))
+    (cursor-column)
+    (find-char target)))
+(goto-line 1)
+(goto-column 20)
+(find-char "v")
+`))
+	script.Close()
+
+	e := setup(t)
+	c := commander.NewCommander(e)
+	if result := c.ParseEvalFile(script.Name()); result != "18" {
+		t.Errorf("Unexpected column from script: %s", result)
+	}
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 3, Col: 17}) {
+		t.Errorf("Unexpected cursor after script: %+v", cursor)
+	}
+	// moving back from the start of a row goes to the end of the previous one
+	back, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(back.Name())
+	back.Write([]byte("(goto-line 2)\n(char-backward)\n"))
+	back.Close()
+	if result := c.ParseEvalFile(back.Name()); result != "1" {
+		t.Errorf("Unexpected result from char-backward: %s", result)
+	}
+	if cursor := e.GetCursor(); cursor != (gott.Point{Row: 0, Col: 22}) {
+		t.Errorf("Unexpected cursor after char-backward: %+v", cursor)
+	}
+}
+
+func TestBufferHash(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	hash := func() string {
+		c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+		typeKeys(c, "hash")
+		pressKey(c, gott.KeyEnter)
+		return c.GetMessageBarText(80)
+	}
+	original := hash()
+	if len(original) != 64 {
+		t.Fatalf("Unexpected hash: %q", original)
+	}
+	if hash() != original {
+		t.Errorf("Expected the hash to be stable")
+	}
+	typeKeys(c, "x")
+	if hash() == original {
+		t.Errorf("Expected the hash to change after an edit")
+	}
+	typeKeys(c, "u")
+	if edited := hash(); edited != original {
+		t.Errorf("Unexpected hash after undo: %q, expected %q", edited, original)
+	}
+	// scripts get the same hash
+	script, err := ioutil.TempFile("", "gott*.lisp")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(script.Name())
+	script.Write([]byte("(buffer-hash)\n"))
+	script.Close()
+	if result := c.ParseEvalFile(script.Name()); result != `"`+original+`"` {
+		t.Errorf("Unexpected result from buffer-hash: %s", result)
+	}
+}
+
+func TestLineEndingStatus(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("one\r\ntwo\r\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	w := e.GetActiveWindow()
+	if text := w.GetInfoBarText(80); !strings.HasSuffix(text, " UTF-8 CRLF ") {
+		t.Errorf("Unexpected info bar text for a CRLF file: %q", text)
+	}
+	// mixed line endings and invalid UTF-8 are reported too
+	w.GetBuffer().LoadBytes([]byte("one\r\ntwo\nthree\xff"))
+	e.SetStatusLine("%e %t")
+	if text := w.GetInfoBarText(20); text != "unknown mixed......." {
+		t.Errorf("Unexpected info bar text for mixed text: %q", text)
+	}
+}
+
+func TestMixedLineEndings(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("one\r\ntwo\nthree\r\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	if !b.GetMixedLineEndings() || b.GetLineEnding() != "mixed" {
+		t.Errorf("Expected mixed line endings to be detected")
+	}
+	if message := c.GetMessageBarText(200); !strings.HasPrefix(message, "This file has mixed line endings") {
+		t.Errorf("Unexpected message for mixed line endings: %q", message)
+	}
+	// normalize the line endings
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "set ff=dos")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "one\r\ntwo\r\nthree\r\n" {
+		t.Errorf("Unexpected text after :set ff=dos: %q", text)
+	}
+	if ending := b.GetLineEnding(); ending != "CRLF" {
+		t.Errorf("Unexpected line ending after :set ff=dos: %s", ending)
+	}
+	if message := c.GetMessageBarText(200); message != "" {
+		t.Errorf("Unexpected message after normalizing line endings: %q", message)
+	}
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "set ff=unix")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "one\ntwo\nthree\n" {
+		t.Errorf("Unexpected text after :set ff=unix: %q", text)
+	}
+	// each conversion is undone in one step
+	e.PerformUndo()
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != "one\r\ntwo\nthree\r\n" {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	// files with consistent line endings aren't reported
+	if err := e.ReadFile(source); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	if e.GetActiveWindow().GetBuffer().GetMixedLineEndings() {
+		t.Errorf("Unexpected mixed line endings in %s", source)
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("one\ntwo\nthree\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	// convert to CRLF and write the file
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "set ff=dos")
+	pressKey(c, gott.KeyEnter)
+	if !b.GetModified() {
+		t.Errorf("Expected the buffer to be modified after :set ff=dos")
+	}
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "w")
+	pressKey(c, gott.KeyEnter)
+	written, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	if string(written) != "one\r\ntwo\r\nthree\r\n" {
+		t.Errorf("Unexpected bytes written after :set ff=dos: %q", written)
+	}
+	// convert to CR and read the file back
+	c.ProcessEvent(&gott.Event{Type: gott.EventKey, Ch: ':'})
+	typeKeys(c, "set ff=mac")
+	pressKey(c, gott.KeyEnter)
+	if text := string(b.GetBytes()); text != "one\rtwo\rthree\r" {
+		t.Errorf("Unexpected text after :set ff=mac: %q", text)
+	}
+	e.WriteFile(f.Name())
+	e2 := editor.NewEditor()
+	if err := e2.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	b2 := e2.GetActiveWindow().GetBuffer()
+	if b2.GetRowCount() != 4 || b2.GetLineEnding() != "CR" {
+		t.Errorf("Unexpected file read with CR line endings: %d rows, %s", b2.GetRowCount(), b2.GetLineEnding())
+	}
+	// undo restores the previous line endings
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != "one\r\ntwo\r\nthree\r\n" {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+	e.PerformUndo()
+	if text := string(b.GetBytes()); text != "one\ntwo\nthree\n" {
+		t.Errorf("Unexpected text after undo: %q", text)
+	}
+}
+
+func TestSecondaryCursors(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	b := e.GetActiveWindow().GetBuffer()
+	original := string(b.GetBytes())
+	// insert a prefix at the start of three rows
+	typeKeys(c, "3GvjjI")
+	typeKeys(c, "> ")
+	if cursors := e.GetActiveWindow().GetSecondaryCursors(); len(cursors) != 2 {
+		t.Errorf("Unexpected secondary cursors: %+v", cursors)
+	}
+	typeKeys(c, "x")
+	pressKey(c, gott.KeyBackspace2)
+	pressKey(c, gott.KeyEsc)
+	for row := 2; row <= 4; row++ {
+		if line := b.GetRowString(row); !strings.HasPrefix(line, "> ") || strings.HasPrefix(line, "> x") {
+			t.Errorf("Unexpected row %d after multi-cursor insert: %q", row, line)
+		}
+	}
+	if line := b.GetRowString(5); strings.HasPrefix(line, "> ") {
+		t.Errorf("Unexpected prefix on row 5: %q", line)
+	}
+	if cursors := e.GetActiveWindow().GetSecondaryCursors(); len(cursors) != 0 {
+		t.Errorf("Unexpected secondary cursors after insert: %+v", cursors)
+	}
+	// the insert is undone and redone in one step
+	e.PerformUndo()
+	if string(b.GetBytes()) != original {
+		t.Errorf("Unexpected text after undo: %q", b.GetBytes())
+	}
+	e.PerformRedo()
+	for row := 2; row <= 4; row++ {
+		if line := b.GetRowString(row); !strings.HasPrefix(line, "> ") || strings.HasPrefix(line, "> > ") {
+			t.Errorf("Unexpected row %d after redo: %q", row, line)
+		}
+	}
+
+	// a line break ends the insert at the secondary cursors, which is undone separately
+	e.LoadBytes([]byte("aaaa\nbbbb\ncccc\n"))
+	e.SetCursor(gott.Point{})
+	typeKeys(c, "vjjIxy")
+	pressKey(c, gott.KeyEnter)
+	typeKeys(c, "z")
+	pressKey(c, gott.KeyEsc)
+	if text := string(b.GetBytes()); text != "xy\nzaaaa\nxybbbb\nxycccc\n" {
+		t.Errorf("Unexpected text after a line break with secondary cursors: %q", text)
+	}
+	typeKeys(c, "u")
+	if text := string(b.GetBytes()); text != "xyaaaa\nxybbbb\nxycccc\n" {
+		t.Errorf("Unexpected text after undoing the line break: %q", text)
+	}
+	typeKeys(c, "u")
+	if text := string(b.GetBytes()); text != "aaaa\nbbbb\ncccc\n" {
+		t.Errorf("Unexpected text after undoing the insert at secondary cursors: %q", text)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	e := setup(t)
+	c := commander.NewCommander(e)
+	text := string(e.Bytes())
+	typeKeys(c, ":echo (+ 1 2)")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); message != "3" {
+		t.Errorf("Unexpected message after :echo: %q", message)
+	}
+	// expressions can inspect the editor
+	typeKeys(c, ":echo (cursor-column)")
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); message != "1" {
+		t.Errorf("Unexpected message after :echo: %q", message)
+	}
+	// spacing inside the expression is kept
+	typeKeys(c, `:echo "a  b"`)
+	pressKey(c, gott.KeyEnter)
+	if message := c.GetMessageBarText(80); message != `"a  b"` {
+		t.Errorf("Unexpected message after :echo: %q", message)
+	}
+	if string(e.Bytes()) != text {
+		t.Errorf("Unexpected change to the buffer after :echo")
+	}
+}
+
+func TestDetectIndentation(t *testing.T) {
+	samples := []struct {
+		Text    string
+		UseTabs bool
+		Width   int
+	}{
+		{"func main() {\n  if x {\n    y()\n  }\n}\n", false, 2},
+		{"class A:\n    def f(self):\n        pass\n\n    def g(self):\n        pass\n", false, 4},
+		{"func main() {\n\tif x {\n\t\ty()\n\t}\n}\n", true, 0},
+		{"/*\n * comment\n */\nx = 1\n", false, 0},
+	}
+	for _, sample := range samples {
+		useTabs, width := editor.DetectIndentation(strings.Split(sample.Text, "\n"))
+		if useTabs != sample.UseTabs || width != sample.Width {
+			t.Errorf("Unexpected indentation %t %d for %q", useTabs, width, sample.Text)
+		}
+	}
+
+	f, err := ioutil.TempFile("", "gott*.py")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte(samples[1].Text))
+	f.Close()
+	e := editor.NewEditor()
+	e.SetDetectIndentation(true)
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	if e.GetTabWidth() != 4 || e.GetLiteralTabs() {
+		t.Errorf("Unexpected indentation for a 4-space file: %d %t", e.GetTabWidth(), e.GetLiteralTabs())
+	}
+	// :set overrides the indentation that was found
+	typeKeys(c, ":set ts=2 noet")
+	pressKey(c, gott.KeyEnter)
+	if e.GetTabWidth() != 2 || !e.GetLiteralTabs() {
+		t.Errorf("Unexpected indentation after :set: %d %t", e.GetTabWidth(), e.GetLiteralTabs())
+	}
+	// detection is off by default, so the editor's settings are used
+	e2 := editor.NewEditor()
+	if err := e2.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	if e2.GetTabWidth() != editor.DefaultTabWidth {
+		t.Errorf("Unexpected tab width with detection off: %d", e2.GetTabWidth())
+	}
+
+	// tabs typed into a file indented with tabs line up with the tabs that were read
+	tabbed, err := ioutil.TempFile("", "gott*.go")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(tabbed.Name())
+	tabbed.Write([]byte(samples[2].Text))
+	tabbed.Close()
+	e3 := editor.NewEditor()
+	e3.SetDetectIndentation(true)
+	if err := e3.ReadFile(tabbed.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c3 := commander.NewCommander(e3)
+	typeKeys(c3, "jo")
+	pressKey(c3, gott.KeyTab)
+	pressKey(c3, gott.KeyTab)
+	typeKeys(c3, "z()")
+	pressKey(c3, gott.KeyEsc)
+	if text := e3.GetActiveWindow().GetBuffer().TextFromPosition(2, 0); text != "\t\tz()" {
+		t.Errorf("Unexpected row after typing tabs: %q", text)
+	}
+	d := display.NewDisplay(gott.Size{Rows: 10, Cols: 40})
+	d.Render(e3, c3)
+	if d.GetRowText(2) != d.GetRowText(3)[0:16]+"z()" {
+		t.Errorf("Typed tabs don't line up with tabs that were read: %q %q", d.GetRowText(2), d.GetRowText(3))
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	f, err := ioutil.TempFile("", "gott*.txt")
+	if err != nil {
+		t.Fatalf("Temp file creation failed: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("héllo wörld\n日本語 text\n"))
+	f.Close()
+
+	e := editor.NewEditor()
+	if err := e.ReadFile(f.Name()); err != nil {
+		t.Fatalf("Read failed: %+v", err)
+	}
+	c := commander.NewCommander(e)
+	w := e.GetActiveWindow()
+	// 13 bytes and a newline precede the second row, and its first four characters are 10 bytes
+	typeKeys(c, "jllll")
+	if offset := w.GetBuffer().ByteOffset(e.GetCursor()); offset != 24 {
+		t.Errorf("Unexpected byte offset: %d", offset)
+	}
+	if text := w.GetInfoBarText(80); strings.Contains(text, "@") {
+		t.Errorf("Unexpected byte offset on the info bar: %q", text)
+	}
+	typeKeys(c, ":set byteoffset")
+	pressKey(c, gott.KeyEnter)
+	if text := w.GetInfoBarText(80); !strings.HasSuffix(text, " 2/3 @24 UTF-8 LF ") {
+		t.Errorf("Unexpected info bar text with the byte offset: %q", text)
+	}
+}
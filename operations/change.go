@@ -0,0 +1,89 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	"unicode/utf8"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// Change replaces the text from the cursor up to an end position.
+// Like ChangeWord, it puts the editor in insert mode to collect the new text.
+type Change struct {
+	operation
+	End       gott.Point
+	Text      string
+	Inverse   *DeleteRange
+	Commander gott.Commander
+}
+
+func (op *Change) Perform(e gott.Editor, multiplier int) gott.Operation {
+	previous := op.Cursor
+	op.init(e, multiplier)
+
+	if op.Text != "" {
+		// when repeated, change a range of the same size at the cursor
+		if op.End.Row == previous.Row {
+			op.End.Col += op.Cursor.Col - previous.Col
+		}
+		op.End.Row += op.Cursor.Row - previous.Row
+	} else {
+		e.SetInsertOperation(op)
+	}
+
+	deletedText, insertEnd, newMode := e.ChangeRange(op.Cursor, op.End, op.Text)
+	if op.Commander != nil {
+		op.Commander.SetMode(newMode)
+	}
+
+	delete := &DeleteRange{End: insertEnd}
+	delete.copyForUndo(&op.operation)
+	op.Inverse = delete
+
+	reinsert := &Insert{
+		Position: gott.InsertAtCursor,
+		Text:     deletedText,
+	}
+	reinsert.copyForUndo(&op.operation)
+
+	inverse := &Sequence{
+		Operations: []gott.Operation{delete, reinsert},
+	}
+	inverse.copyForUndo(&op.operation)
+	return inverse
+}
+
+// Length returns the number of characters added by the change operation.
+func (op *Change) Length() int {
+	return utf8.RuneCountInString(op.Text)
+}
+
+// AddCharacter adds a character to the change operation.
+func (op *Change) AddCharacter(c rune) {
+	op.Text += string(c)
+}
+
+// DeleteCharacter deletes a character from the end of the change operation.
+func (op *Change) DeleteCharacter() {
+	_, size := utf8.DecodeLastRuneInString(op.Text)
+	op.Text = op.Text[0 : len(op.Text)-size]
+}
+
+// Close completes a change operation.
+func (op *Change) Close(e gott.Editor) {
+	// the inserted text ends at the cursor
+	op.Inverse.End = e.GetCursor()
+}
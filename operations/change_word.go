@@ -15,6 +15,8 @@
 package operations
 
 import (
+	"unicode/utf8"
+
 	gott "github.com/timburks/gott/types"
 )
 
@@ -23,7 +25,7 @@ import (
 type ChangeWord struct {
 	operation
 	Text      string
-	Inverse   *DeleteCharacter
+	Inverse   *DeleteRange
 	Commander gott.Commander
 }
 
@@ -37,14 +39,18 @@ func (op *ChangeWord) Perform(e gott.Editor, multiplier int) gott.Operation {
 		e.SetInsertOperation(op)
 	}
 
-	deletedText, newMode := e.ChangeWordAtCursor(op.Multiplier, op.Text)
+	deletedText, newMode := e.ChangeWordAtCursor(op.Multiplier, "")
+	// repeated changes insert their text now, and others end where typing ends
+	end := e.GetCursor()
+	if op.Text != "" {
+		_, end, newMode = e.InsertText(op.Text, gott.InsertAtCursor)
+	}
 	if op.Commander != nil {
 		op.Commander.SetMode(newMode)
 	}
 
-	delete := &DeleteCharacter{}
+	delete := &DeleteRange{End: end}
 	delete.copyForUndo(&op.operation)
-	delete.Multiplier = len(op.Text)
 	op.Inverse = delete
 
 	reinsert := &Insert{
@@ -68,9 +74,9 @@ func (op *ChangeWord) Perform(e gott.Editor, multiplier int) gott.Operation {
 	return inverse
 }
 
-// Length returns the length of text added by the change operation.
+// Length returns the number of characters added by the change operation.
 func (op *ChangeWord) Length() int {
-	return len(op.Text)
+	return utf8.RuneCountInString(op.Text)
 }
 
 // AddCharacter adds a character to the change operation.
@@ -80,10 +86,12 @@ func (op *ChangeWord) AddCharacter(c rune) {
 
 // DeleteCharacter deletes a character from the end of the change operation.
 func (op *ChangeWord) DeleteCharacter() {
-	op.Text = op.Text[0 : len(op.Text)-1]
+	_, size := utf8.DecodeLastRuneInString(op.Text)
+	op.Text = op.Text[0 : len(op.Text)-size]
 }
 
 // Close completes an insert operation.
-func (op *ChangeWord) Close() {
-	op.Inverse.Multiplier = len(op.Text)
+func (op *ChangeWord) Close(e gott.Editor) {
+	// the inserted text ends at the cursor
+	op.Inverse.End = e.GetCursor()
 }
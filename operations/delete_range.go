@@ -0,0 +1,60 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// DeleteRange deletes the text from the cursor up to an end position.
+// It is the inverse of an insert, so it can delete text that spans rows.
+type DeleteRange struct {
+	operation
+	End              gott.Point
+	FinallyDeleteRow bool
+	Origin           *gott.Point  // if set, the cursor is moved here after the deletion
+	Cursors          []gott.Point // secondary cursors where the same single-row text is deleted
+}
+
+func (op *DeleteRange) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	if op.End.Row == op.Cursor.Row {
+		// work from the bottom up so that the positions of other cursors stay valid
+		for i := len(op.Cursors) - 1; i >= 0; i-- {
+			start := op.Cursors[i]
+			e.DeleteRange(start, gott.Point{Row: start.Row, Col: start.Col + op.End.Col - op.Cursor.Col}, false)
+		}
+	}
+	deletedText := e.DeleteRange(op.Cursor, op.End, op.FinallyDeleteRow)
+	if op.Origin != nil {
+		e.SetCursor(*op.Origin)
+		e.KeepCursorInRow()
+	}
+	if deletedText == "" {
+		// an empty insert would start insert mode
+		return nil
+	}
+	inverse := &Insert{
+		Position: gott.InsertAtCursor,
+		Text:     deletedText,
+		Cursors:  op.Cursors,
+	}
+	if op.FinallyDeleteRow {
+		// restore the deleted row above the row that took its place
+		inverse.Position = gott.InsertAtNewLineAboveCursor
+	}
+	inverse.copyForUndo(&op.operation)
+	return inverse
+}
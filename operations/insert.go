@@ -15,6 +15,9 @@
 package operations
 
 import (
+	"strings"
+	"unicode/utf8"
+
 	gott "github.com/timburks/gott/types"
 )
 
@@ -26,29 +29,42 @@ type Insert struct {
 	operation
 	Position  int
 	Text      string
-	Inverse   *DeleteCharacter
+	Inverse   *DeleteRange
 	Commander gott.Commander
+	Cursors   []gott.Point // secondary cursors that insert the same text
 }
 
 func (op *Insert) Perform(e gott.Editor, multiplier int) gott.Operation {
 	op.init(e, multiplier)
 
+	window := e.GetActiveWindow()
 	if op.Text != "" {
 		e.SetCursor(op.Cursor)
+		// repeated inserts are only made at the cursor
+		if !op.Undo {
+			op.Cursors = nil
+		}
+		window.SetSecondaryCursors(op.Cursors)
+		defer window.SetSecondaryCursors(nil)
 	} else {
 		op.Cursor = e.GetCursor()
+		op.Cursors = append([]gott.Point(nil), window.GetSecondaryCursors()...)
 		e.SetInsertOperation(op)
 	}
+	// undo returns the cursor to where it was before the insert
+	origin := op.Cursor
+
+	text := op.Text + op.repetitions()
 
+	var end gott.Point
 	var newMode int
-	op.Cursor, newMode = e.InsertText(op.Text, op.Position)
+	op.Cursor, end, newMode = e.InsertText(text, op.Position)
 	if op.Commander != nil {
 		op.Commander.SetMode(newMode)
 	}
 
-	inverse := &DeleteCharacter{}
+	inverse := &DeleteRange{End: end, Origin: &origin, Cursors: op.Cursors}
 	inverse.copyForUndo(&op.operation)
-	inverse.Multiplier = len(op.Text)
 	if op.Position == gott.InsertAtNewLineBelowCursor ||
 		op.Position == gott.InsertAtNewLineAboveCursor {
 		inverse.FinallyDeleteRow = true
@@ -57,9 +73,9 @@ func (op *Insert) Perform(e gott.Editor, multiplier int) gott.Operation {
 	return inverse
 }
 
-// Length returns the length of text added by the insert operation.
+// Length returns the number of characters added by the insert operation.
 func (op *Insert) Length() int {
-	return len(op.Text)
+	return utf8.RuneCountInString(op.Text)
 }
 
 // AddCharacter adds a character to the insert operation.
@@ -69,10 +85,33 @@ func (op *Insert) AddCharacter(c rune) {
 
 // DeleteCharacter deletes a character from the end of the insert operation.
 func (op *Insert) DeleteCharacter() {
-	op.Text = op.Text[0 : len(op.Text)-1]
+	_, size := utf8.DecodeLastRuneInString(op.Text)
+	op.Text = op.Text[0 : len(op.Text)-size]
 }
 
 // Close completes an insert operation.
-func (op *Insert) Close() {
-	op.Inverse.Multiplier = len(op.Text)
+// If the insert has a multiplier, the collected text is inserted again
+// until it appears multiplier times; all copies are undone together.
+func (op *Insert) Close(e gott.Editor) {
+	repetitions := op.repetitions()
+	for _, c := range repetitions {
+		e.InsertChar(c)
+	}
+	// the inserted text ends at the cursor
+	op.Inverse.End = e.GetCursor()
+}
+
+// repetitions returns the text that follows the first copy of inserted text
+// when the insert is repeated by a multiplier. Undo inserts are never repeated.
+func (op *Insert) repetitions() string {
+	if op.Undo || op.Multiplier < 2 || op.Text == "" {
+		return ""
+	}
+	text := op.Text
+	if op.Position == gott.InsertAtNewLineBelowCursor ||
+		op.Position == gott.InsertAtNewLineAboveCursor {
+		// each repetition goes on its own new line
+		text = "\n" + text
+	}
+	return strings.Repeat(text, op.Multiplier-1)
 }
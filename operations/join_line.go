@@ -26,6 +26,10 @@ type JoinLine struct {
 func (op *JoinLine) Perform(e gott.Editor, multiplier int) gott.Operation {
 	op.init(e, multiplier)
 	cursors := e.JoinRow(op.Multiplier)
+	if len(cursors) == 0 {
+		return nil
+	}
+	// each join is undone by one insert
 	operations := make([]gott.Operation, 0)
 	for i := len(cursors) - 1; i >= 0; i-- {
 		insert := &Insert{}
@@ -0,0 +1,46 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// LineEndings converts the line endings of every row to the style of a file format:
+// LF for "unix", CRLF for "dos", and CR for "mac".
+type LineEndings struct {
+	operation
+	Format    string
+	lineBreak string       // separator to restore when undoing a conversion
+	rows      *ReplaceRows // restores the rows when undoing a conversion
+}
+
+func (op *LineEndings) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	b := e.GetActiveWindow().GetBuffer()
+	inverse := &LineEndings{lineBreak: b.GetLineBreak()}
+	if op.rows != nil {
+		inverse.rows = replaceRowsFrom(e, 0, op.rows.Lines)
+		b.SetLineBreak(op.lineBreak)
+	} else {
+		inverse.rows = replaceRowsFrom(e, 0, b.LineEndingRows(op.Format == "dos"))
+		if op.Format == "mac" {
+			b.SetLineBreak("\r")
+		} else {
+			b.SetLineBreak("\n")
+		}
+	}
+	return inverse
+}
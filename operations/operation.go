@@ -21,7 +21,7 @@ import (
 // operation is a utility class that is composed into all operation classes.
 // It provides common data storage and services for operations.
 type operation struct {
-	Cursor     gott.Point
+	Cursor     gott.Point // where the operation is performed; undo operations return here
 	Multiplier int
 	Undo       bool
 }
@@ -37,6 +37,10 @@ func (op *operation) init(e gott.Editor, multiplier int) {
 	}
 }
 
+func (op *operation) SetMultiplier(multiplier int) {
+	op.Multiplier = multiplier
+}
+
 func (op *operation) copyForUndo(other *operation) {
 	op.Cursor = other.Cursor
 	op.Multiplier = other.Multiplier
@@ -15,6 +15,8 @@
 package operations
 
 import (
+	"strings"
+
 	gott "github.com/timburks/gott/types"
 )
 
@@ -30,21 +32,19 @@ func (op *Paste) Perform(e gott.Editor, multiplier int) gott.Operation {
 
 	op.init(e, multiplier)
 
-	cursor := op.Cursor
-
-	for i := 0; i < op.Multiplier; i++ {
-		for _, c := range e.GetPasteText() {
-			e.InsertChar(c)
-		}
-	}
 	if e.GetPasteMode() == gott.PasteNewLine {
-		e.SetCursor(cursor)
-		inverse := &DeleteCharacter{}
+		start, end, _ := e.InsertText(strings.Repeat(e.GetPasteText(), op.Multiplier), gott.InsertAtCursor)
+		e.SetCursor(op.Cursor)
+		inverse := &DeleteRange{End: end}
 		inverse.copyForUndo(&op.operation)
-		inverse.Multiplier = len(e.GetPasteText()) * op.Multiplier
-		inverse.Cursor.Col = 0
+		inverse.Cursor = start
 		return inverse
 	} else {
+		for i := 0; i < op.Multiplier; i++ {
+			for _, c := range e.GetPasteText() {
+				e.InsertChar(c)
+			}
+		}
 		return nil
 	}
 }
@@ -0,0 +1,48 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	"strings"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// Reflow rewraps the rows from the cursor row through EndRow to the editor's text width.
+type Reflow struct {
+	operation
+	EndRow int
+}
+
+func (op *Reflow) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	b := e.GetActiveWindow().GetBuffer()
+	lines := b.ReflowRange(op.Cursor.Row, op.EndRow, e.GetTextWidth())
+	if len(lines) == 0 {
+		// there are no words to reflow
+		return nil
+	}
+	// replace the text of the rows with the reflowed lines
+	start := gott.Point{Row: op.Cursor.Row}
+	end := gott.Point{Row: op.EndRow, Col: len([]rune(b.GetRowString(op.EndRow)))}
+	e.SetCursor(start)
+	replacement := &Sequence{Operations: []gott.Operation{
+		&DeleteRange{End: end},
+		&Insert{Position: gott.InsertAtCursor, Text: strings.Join(lines, "\n")},
+	}}
+	inverse := replacement.Perform(e, 1)
+	e.SetCursor(start)
+	return inverse
+}
@@ -0,0 +1,42 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// ReplaceRange replaces the text from the cursor up to an end position with Text.
+// Unlike Change, it doesn't enter insert mode, and it is undone in one step.
+type ReplaceRange struct {
+	operation
+	End  gott.Point
+	Text string
+}
+
+func (op *ReplaceRange) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	start := op.Cursor
+	replacement := &Sequence{Operations: []gott.Operation{&DeleteRange{End: op.End}}}
+	if op.Text != "" {
+		// an empty insert would start insert mode
+		replacement.Operations = append(replacement.Operations,
+			&Insert{Position: gott.InsertAtCursor, Text: op.Text})
+	}
+	inverse := replacement.Perform(e, 1)
+	e.SetCursor(start)
+	e.KeepCursorInRow()
+	return inverse
+}
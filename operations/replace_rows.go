@@ -0,0 +1,48 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// ReplaceRows replaces the text of rows starting at the current cursor row.
+type ReplaceRows struct {
+	operation
+	Lines []string
+}
+
+func (op *ReplaceRows) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	inverse := &ReplaceRows{}
+	inverse.copyForUndo(&op.operation)
+	for i, line := range op.Lines {
+		inverse.Lines = append(inverse.Lines, e.ReplaceRow(op.Cursor.Row+i, line))
+	}
+	e.KeepCursorInRow()
+	return inverse
+}
+
+// replaceRowsFrom replaces the text of rows starting at a row, then returns to the original
+// cursor position. It returns the operation that restores the rows.
+func replaceRowsFrom(e gott.Editor, row int, lines []string) *ReplaceRows {
+	cursor := e.GetCursor()
+	e.SetCursor(gott.Point{Row: row})
+	replacement := &ReplaceRows{Lines: lines}
+	inverse := replacement.Perform(e, 1).(*ReplaceRows)
+	e.SetCursor(cursor)
+	e.KeepCursorInRow()
+	return inverse
+}
@@ -0,0 +1,39 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// Retab converts tabs to spaces throughout a buffer, or with ToTabs, converts
+// the spaces in indentation to tabs. Tab stops are set by the editor's tab width.
+type Retab struct {
+	operation
+	ToTabs      bool
+	LeadingOnly bool // when converting to spaces, only convert tabs in indentation
+}
+
+func (op *Retab) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	b := e.GetActiveWindow().GetBuffer()
+	var lines []string
+	if op.ToTabs {
+		lines = b.Entab(e.GetTabWidth())
+	} else {
+		lines = b.Retab(e.GetTabWidth(), op.LeadingOnly)
+	}
+	return replaceRowsFrom(e, 0, lines)
+}
@@ -27,8 +27,17 @@ type Sequence struct {
 
 func (op *Sequence) Perform(e gott.Editor, multiplier int) gott.Operation {
 	op.init(e, multiplier)
+	// the inverse performs the inverses of the operations in reverse order
+	var inverses []gott.Operation
 	for _, sub := range op.Operations {
-		sub.Perform(e, 1)
+		if inverse := sub.Perform(e, 1); inverse != nil {
+			inverses = append([]gott.Operation{inverse}, inverses...)
+		}
 	}
-	return nil // no undo
+	if len(inverses) == 0 {
+		return nil
+	}
+	inverse := &Sequence{Operations: inverses}
+	inverse.copyForUndo(&op.operation)
+	return inverse
 }
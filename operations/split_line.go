@@ -0,0 +1,35 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// SplitLine breaks the cursor row in two at the cursor and leaves the cursor on the first row.
+type SplitLine struct {
+	operation
+}
+
+func (op *SplitLine) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	e.InsertRow()
+	e.SetCursor(op.Cursor)
+	e.KeepCursorInRow()
+	inverse := &JoinLine{}
+	inverse.copyForUndo(&op.operation)
+	inverse.Multiplier = 1
+	return inverse
+}
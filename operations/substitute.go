@@ -0,0 +1,56 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	"regexp"
+
+	gott "github.com/timburks/gott/types"
+)
+
+// Substitute replaces matches of a regular expression in rows starting at the current cursor row.
+type Substitute struct {
+	operation
+	Pattern     string
+	Replacement string // can refer to submatches as described in regexp.Expand
+	Global      bool   // true to replace every match in a row instead of just the first
+	Rows        int
+}
+
+func (op *Substitute) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	re, err := regexp.Compile(op.Pattern)
+	if err != nil {
+		return nil
+	}
+	b := e.GetActiveWindow().GetBuffer()
+	replacement := &ReplaceRows{}
+	for row := op.Cursor.Row; row < op.Cursor.Row+op.Rows && row < b.GetRowCount(); row++ {
+		replacement.Lines = append(replacement.Lines, substitute(re, b.TextFromPosition(row, 0), op.Replacement, op.Global))
+	}
+	return replacement.Perform(e, 1)
+}
+
+func substitute(re *regexp.Regexp, text, replacement string, global bool) string {
+	if global {
+		return re.ReplaceAllString(text, replacement)
+	}
+	match := re.FindStringSubmatchIndex(text)
+	if match == nil {
+		return text
+	}
+	result := re.ExpandString([]byte(text[0:match[0]]), replacement, text, match)
+	return string(result) + text[match[1]:]
+}
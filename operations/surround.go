@@ -0,0 +1,50 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// Surround adds, changes, or deletes a pair of delimiters in the cursor row.
+// Without Replace, Open and Close are inserted around the text from Start to End.
+// With Replace, the characters at Start and End are replaced with Open and Close,
+// which may be empty to delete them.
+type Surround struct {
+	operation
+	Start   int
+	End     int
+	Open    string
+	Close   string
+	Replace bool
+}
+
+func (op *Surround) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	text := []rune(e.GetActiveWindow().GetBuffer().TextFromPosition(op.Cursor.Row, 0))
+	if op.Start < 0 || op.Start > op.End || op.End >= len(text) {
+		return nil
+	}
+	var line string
+	if op.Replace {
+		line = string(text[:op.Start]) + op.Open + string(text[op.Start+1:op.End]) + op.Close + string(text[op.End+1:])
+	} else {
+		line = string(text[:op.Start]) + op.Open + string(text[op.Start:op.End+1]) + op.Close + string(text[op.End+1:])
+	}
+	// the cursor finishes on the opening delimiter
+	e.SetCursor(gott.Point{Row: op.Cursor.Row, Col: op.Start})
+	replacement := &ReplaceRows{Lines: []string{line}}
+	return replacement.Perform(e, 1)
+}
@@ -0,0 +1,39 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package operations
+
+import (
+	gott "github.com/timburks/gott/types"
+)
+
+// ToggleComment comments or uncomments the rows starting at the cursor row.
+// The multiplier is the number of rows.
+type ToggleComment struct {
+	operation
+}
+
+func (op *ToggleComment) Perform(e gott.Editor, multiplier int) gott.Operation {
+	op.init(e, multiplier)
+	b := e.GetActiveWindow().GetBuffer()
+	end := op.Cursor.Row + op.Multiplier - 1
+	if last := b.GetRowCount() - 1; end > last {
+		end = last
+	}
+	lines := b.ToggleCommentRange(op.Cursor.Row, end)
+	if len(lines) == 0 {
+		return nil
+	}
+	return replaceRowsFrom(e, op.Cursor.Row, lines)
+}
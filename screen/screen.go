@@ -15,7 +15,11 @@
 package screen
 
 import (
+	"bytes"
 	"log"
+	"os"
+	"time"
+	"unicode/utf8"
 
 	"github.com/nsf/termbox-go"
 	gott "github.com/timburks/gott/types"
@@ -26,8 +30,13 @@ type Screen struct {
 	size        gott.Size // screen size
 	editor      gott.Editor
 	needsLayout bool
+	input       []byte // raw input that hasn't been converted to events
+	trueColor   bool   // true if the terminal displays 24-bit color
 }
 
+// Terminals send back-tab (shift-tab) as this escape sequence, which termbox doesn't recognize.
+var backtabSequence = []byte("\x1b[Z")
+
 // NewScreen creates a screen for use with a specified editor.
 func NewScreen(e gott.Editor) *Screen {
 	// Open the terminal.
@@ -36,8 +45,16 @@ func NewScreen(e gott.Editor) *Screen {
 		log.Output(1, err.Error())
 		return nil
 	}
-	termbox.SetOutputMode(termbox.Output256)
+	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
 	s := &Screen{editor: e}
+	// Use 24-bit color if the terminal says that it's available.
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		s.trueColor = true
+		termbox.SetOutputMode(termbox.OutputRGB)
+	default:
+		termbox.SetOutputMode(termbox.Output256)
+	}
 	s.needsLayout = true
 	return s
 }
@@ -63,18 +80,34 @@ func (s *Screen) Render(e gott.Editor, c gott.Commander) {
 		s.layout()
 		s.needsLayout = false
 	}
-	termbox.Clear(termbox.ColorWhite, termbox.ColorBlack)
+	termbox.Clear(s.attribute(gott.ColorWhite), s.attribute(gott.ColorBlack))
 	e.RenderWindows(s)
+	s.renderPalette(c)
 	s.renderMessageBar(c)
 	termbox.Flush()
 }
 
 func (s *Screen) SetCell(j int, i int, c rune, color gott.Color) {
-	termbox.SetCell(j, i, c, termbox.Attribute(color), 0x01)
+	termbox.SetCell(j, i, c, s.attribute(color), s.attribute(gott.ColorBlack))
 }
 
 func (s *Screen) SetCellReversed(j int, i int, c rune, color gott.Color) {
-	termbox.SetCell(j, i, c, termbox.Attribute(color), 0x08)
+	termbox.SetCell(j, i, c, s.attribute(color), s.attribute(gott.ColorWhite))
+}
+
+func (s *Screen) SetCellDimmed(j int, i int, c rune, color gott.Color) {
+	termbox.SetCell(j, i, c, s.attribute(color)|termbox.AttrDim, s.attribute(gott.ColorBlack))
+}
+
+// Convert a color to a termbox attribute for the current output mode.
+func (s *Screen) attribute(color gott.Color) termbox.Attribute {
+	if !s.trueColor {
+		return termbox.Attribute(color.Indexed())
+	}
+	if color == 0 {
+		return termbox.ColorDefault
+	}
+	return termbox.RGBToAttribute(color.RGB())
 }
 
 func (s *Screen) SetCursor(position gott.Point) {
@@ -85,24 +118,198 @@ func (s *Screen) SetCursor(position gott.Point) {
 func (s *Screen) renderMessageBar(c gott.Commander) {
 	text := c.GetMessageBarText(s.size.Cols)
 	for x, ch := range text {
-		termbox.SetCell(x, s.size.Rows-1,
-			rune(ch),
-			termbox.ColorWhite,
-			termbox.ColorBlack)
+		s.SetCell(x, s.size.Rows-1, rune(ch), gott.ColorWhite)
+	}
+}
+
+// Palette matches are drawn up from the message bar.
+func (s *Screen) renderPalette(c gott.Commander) {
+	for i, line := range c.GetPaletteLines(s.size.Rows-1, s.size.Cols) {
+		for x, ch := range line {
+			s.SetCellReversed(x, s.size.Rows-2-i, ch, gott.ColorWhite)
+		}
 	}
 }
 
 func (s *Screen) GetNextEvent() *gott.Event {
-	event := termbox.PollEvent()
-	if event.Type == termbox.EventResize {
+	return s.GetNextEventWithTimeout(0)
+}
+
+// GetNextEventWithTimeout returns the next event, or nil if no input arrives within d.
+// If d is zero, it waits indefinitely.
+func (s *Screen) GetNextEventWithTimeout(d time.Duration) *gott.Event {
+	timeout := time.Duration(s.editor.GetEscTimeout()) * time.Millisecond
+	waited := false
+	stale := false // true when no more input arrived to complete the pending input
+	for {
+		// a lone Esc may be the start of an Alt sequence, so wait briefly for the next key
+		if timeout > 0 && !waited && len(s.input) == 1 && s.input[0] == escape {
+			waited = true
+			if event := s.waitForInput(timeout); event != nil {
+				return event
+			}
+		}
+		// input that was split between reads waits for the rest
+		if len(s.input) > 0 && !stale && incomplete(s.input) {
+			wait := timeout
+			if wait == 0 {
+				wait = partialInputTimeout
+			}
+			pending := len(s.input)
+			if event := s.waitForInput(wait); event != nil {
+				return event
+			}
+			stale = len(s.input) == pending
+			continue
+		}
+		// convert any pending input to an event
+		if len(s.input) > 0 {
+			event, n := parseEvent(s.input, timeout > 0)
+			s.input = s.input[n:]
+			stale = false
+			if event != nil {
+				return event
+			}
+			continue
+		}
+		if event := s.waitForInput(d); event != nil {
+			return event
+		}
+		if d > 0 && len(s.input) == 0 {
+			return nil
+		}
+	}
+}
+
+// The Esc key and escape sequences start with this byte.
+const escape = 0x1b
+
+// When the Esc timeout is zero, this is how long to wait for the rest of a
+// character or escape sequence that was split between reads.
+const partialInputTimeout = 100 * time.Millisecond
+
+// Return true if input ends partway through its first character or escape sequence.
+// A lone Esc is a complete key.
+func incomplete(input []byte) bool {
+	if input[0] != escape {
+		return input[0] >= utf8.RuneSelf && !utf8.FullRune(input)
+	}
+	if len(input) < 2 {
+		return false
+	}
+	switch input[1] {
+	case '[':
+		// mouse events are followed by three bytes of button and position
+		if len(input) > 2 && input[2] == 'M' {
+			return len(input) < 6
+		}
+		// other sequences end with a byte that isn't a parameter
+		for _, c := range input[2:] {
+			if c < 0x20 || c > 0x3f {
+				return false
+			}
+		}
+		return true
+	case 'O':
+		return len(input) < 3
+	}
+	return false
+}
+
+// Wait for input and append it to the pending input.
+// If timeout is nonzero, stop waiting after that long.
+// Non-input events that should be returned by GetNextEvent are returned.
+func (s *Screen) waitForInput(timeout time.Duration) *gott.Event {
+	if timeout > 0 {
+		// a late interrupt just ends a later wait early, which is harmless
+		timer := time.AfterFunc(timeout, termbox.Interrupt)
+		defer timer.Stop()
+	}
+	data := make([]byte, 64)
+	event := termbox.PollRawEvent(data)
+	switch event.Type {
+	case termbox.EventRaw:
+		s.input = append(s.input, data[0:event.N]...)
+	case termbox.EventResize:
 		s.needsLayout = true
 		termbox.Flush()
+		return &gott.Event{Type: gott.EventResize}
+	case termbox.EventError:
+		log.Output(1, event.Err.Error())
+	}
+	return nil
+}
+
+// Convert the start of some input to an event and return the number of bytes used.
+// When alt is true, an Esc followed by another key is reported as that key with Alt.
+// A nil event is returned for input that can't be parsed.
+func parseEvent(input []byte, alt bool) (*gott.Event, int) {
+	if bytes.HasPrefix(input, backtabSequence) {
+		return &gott.Event{Type: gott.EventKey, Key: gott.KeyBacktab}, len(backtabSequence)
 	}
-	return &gott.Event{
-		Type: int(event.Type),
-		Key:  key(event.Key),
-		Ch:   event.Ch,
+	if event, n := parseModifiedKey(input); event != nil {
+		return event, n
 	}
+	event := termbox.ParseEvent(input)
+	if event.Type == termbox.EventKey && event.N > 0 {
+		if alt && event.Key == termbox.KeyEsc && event.N == 1 && len(input) > 1 {
+			if next, n := parseEvent(input[1:], false); next != nil && next.Type == gott.EventKey {
+				next.Mod |= gott.ModAlt
+				return next, n + 1
+			}
+		}
+		return &gott.Event{
+			Type: gott.EventKey,
+			Key:  key(event.Key),
+			Ch:   event.Ch,
+		}, event.N
+	}
+	if event.Type == termbox.EventMouse && event.N > 0 {
+		return &gott.Event{
+			Type:     gott.EventMouse,
+			Key:      mouseKey(event.Key),
+			Position: gott.Point{Row: event.MouseY, Col: event.MouseX},
+		}, event.N
+	}
+	// skip input that can't be parsed
+	if event.N == 0 {
+		event.N = 1
+	}
+	return nil, event.N
+}
+
+// Terminals send modified arrow keys as sequences like "\x1b[1;5D" (Ctrl-Left),
+// which termbox doesn't recognize. The number after the semicolon is one more
+// than a bitmask of modifiers.
+var modifiedKeys = map[byte]gott.Key{
+	'A': gott.KeyArrowUp,
+	'B': gott.KeyArrowDown,
+	'C': gott.KeyArrowRight,
+	'D': gott.KeyArrowLeft,
+	'F': gott.KeyEnd,
+	'H': gott.KeyHome,
+}
+
+// Convert a modified key sequence to an event and return the number of bytes used.
+func parseModifiedKey(input []byte) (*gott.Event, int) {
+	prefix := []byte("\x1b[1;")
+	if !bytes.HasPrefix(input, prefix) {
+		return nil, 0
+	}
+	mod := 0
+	for i := len(prefix); i < len(input); i++ {
+		c := input[i]
+		if c >= '0' && c <= '9' {
+			mod = mod*10 + int(c-'0')
+			continue
+		}
+		k, ok := modifiedKeys[c]
+		if !ok || mod < 2 {
+			return nil, 0
+		}
+		return &gott.Event{Type: gott.EventKey, Key: k, Mod: gott.Mod(mod - 1)}, i + 1
+	}
+	return nil, 0
 }
 
 // This conversion seems silly, but it keeps termbox dependencies isolated here.
@@ -188,3 +395,15 @@ func key(k termbox.Key) gott.Key {
 		return gott.KeyUnsupported
 	}
 }
+
+// Mouse buttons are reported as keys.
+func mouseKey(k termbox.Key) gott.Key {
+	switch k {
+	case termbox.MouseLeft:
+		return gott.KeyMouseLeft
+	case termbox.MouseRelease:
+		return gott.KeyMouseRelease
+	default:
+		return gott.KeyUnsupported
+	}
+}
@@ -0,0 +1,76 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package screen
+
+import (
+	"testing"
+
+	gott "github.com/timburks/gott/types"
+)
+
+func TestParseEvents(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Alt      bool
+		Expected gott.Event
+		N        int
+	}{
+		{"\x1b", true, gott.Event{Type: gott.EventKey, Key: gott.KeyEsc}, 1},
+		{"\x1b", false, gott.Event{Type: gott.EventKey, Key: gott.KeyEsc}, 1},
+		{"\x1bx", true, gott.Event{Type: gott.EventKey, Ch: 'x', Mod: gott.ModAlt}, 2},
+		{"\x1bx", false, gott.Event{Type: gott.EventKey, Key: gott.KeyEsc}, 1},
+		{"\x1b\x7f", true, gott.Event{Type: gott.EventKey, Key: gott.KeyBackspace2, Mod: gott.ModAlt}, 2},
+		{"\x1b\x1b", true, gott.Event{Type: gott.EventKey, Key: gott.KeyEsc, Mod: gott.ModAlt}, 2},
+		{"\x1b[Z", true, gott.Event{Type: gott.EventKey, Key: gott.KeyBacktab}, 3},
+		{"x", true, gott.Event{Type: gott.EventKey, Ch: 'x'}, 1},
+		{"\x1b[1;5D", false, gott.Event{Type: gott.EventKey, Key: gott.KeyArrowLeft, Mod: gott.ModCtrl}, 6},
+		{"\x1b[1;5Cx", false, gott.Event{Type: gott.EventKey, Key: gott.KeyArrowRight, Mod: gott.ModCtrl}, 6},
+		{"\x1b[1;2A", false, gott.Event{Type: gott.EventKey, Key: gott.KeyArrowUp, Mod: gott.ModShift}, 6},
+		{"\x1b[1;7H", false, gott.Event{Type: gott.EventKey, Key: gott.KeyHome, Mod: gott.ModCtrl | gott.ModAlt}, 6},
+	}
+	for _, tc := range cases {
+		event, n := parseEvent([]byte(tc.Input), tc.Alt)
+		if event == nil || *event != tc.Expected || n != tc.N {
+			t.Errorf("Unexpected event for %q: %+v (%d) expected %+v (%d)",
+				tc.Input, event, n, tc.Expected, tc.N)
+		}
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	cases := []struct {
+		Input      string
+		Incomplete bool
+	}{
+		{"x", false},
+		{"\xe4\xb8", true},
+		{"\xe4\xb8\xad", false},
+		{"\xe4\xb8\xadx", false},
+		{"\x1b", false},
+		{"\x1bx", false},
+		{"\x1b[", true},
+		{"\x1b[1;5", true},
+		{"\x1b[1;5D", false},
+		{"\x1b[M ", true},
+		{"\x1b[M !!", false},
+		{"\x1bO", true},
+		{"\x1bOP", false},
+	}
+	for _, tc := range cases {
+		if incomplete([]byte(tc.Input)) != tc.Incomplete {
+			t.Errorf("Unexpected result for %q: expected %t", tc.Input, tc.Incomplete)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package screen
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	gott "github.com/timburks/gott/types"
+)
+
+// TcellScreen draws the state of an editor using tcell.
+// It is an alternative to Screen, which uses termbox.
+type TcellScreen struct {
+	screen      tcell.Screen
+	size        gott.Size // screen size
+	editor      gott.Editor
+	needsLayout bool
+}
+
+// NewTcellScreen creates a tcell screen for use with a specified editor.
+// It returns an error if the terminal can't be opened.
+func NewTcellScreen(e gott.Editor) (*TcellScreen, error) {
+	// Open the terminal.
+	screen, err := tcell.NewScreen()
+	if err == nil {
+		err = screen.Init()
+	}
+	if err != nil {
+		return nil, err
+	}
+	screen.EnableMouse()
+	s := &TcellScreen{screen: screen, editor: e}
+	s.needsLayout = true
+	return s, nil
+}
+
+// Close shuts down the display of a screen.
+func (s *TcellScreen) Close() {
+	s.screen.Fini()
+}
+
+func (s *TcellScreen) layout() {
+	var screenSize gott.Size
+	screenSize.Cols, screenSize.Rows = s.screen.Size()
+	s.size = screenSize
+	editSize := screenSize
+	// Save the last row on the screen for the message bar.
+	editSize.Rows -= 1
+	s.editor.SetSize(editSize)
+	s.editor.LayoutWindows()
+}
+
+func (s *TcellScreen) Render(e gott.Editor, c gott.Commander) {
+	if s.needsLayout {
+		s.layout()
+		s.needsLayout = false
+	}
+	s.screen.Clear()
+	e.RenderWindows(s)
+	s.renderPalette(c)
+	s.renderMessageBar(c)
+	s.screen.Show()
+}
+
+func (s *TcellScreen) SetCell(j int, i int, c rune, color gott.Color) {
+	s.screen.SetContent(j, i, c, nil, s.style(color))
+}
+
+func (s *TcellScreen) SetCellReversed(j int, i int, c rune, color gott.Color) {
+	s.screen.SetContent(j, i, c, nil, s.style(color).Reverse(true))
+}
+
+func (s *TcellScreen) SetCellDimmed(j int, i int, c rune, color gott.Color) {
+	s.screen.SetContent(j, i, c, nil, s.style(color).Dim(true))
+}
+
+// Convert a color to a tcell style.
+func (s *TcellScreen) style(color gott.Color) tcell.Style {
+	style := tcell.StyleDefault.Background(tcell.ColorBlack)
+	if color.IsRGB() {
+		r, g, b := color.RGB()
+		return style.Foreground(tcell.NewRGBColor(int32(r), int32(g), int32(b)))
+	}
+	if color == 0 {
+		return style
+	}
+	// palette colors are numbered from 1
+	return style.Foreground(tcell.PaletteColor(int(color) - 1))
+}
+
+func (s *TcellScreen) SetCursor(position gott.Point) {
+	s.screen.ShowCursor(position.Col, position.Row)
+}
+
+// The message bar is a single line at the bottom of the screen.
+func (s *TcellScreen) renderMessageBar(c gott.Commander) {
+	text := c.GetMessageBarText(s.size.Cols)
+	for x, ch := range text {
+		s.SetCell(x, s.size.Rows-1, rune(ch), gott.ColorWhite)
+	}
+}
+
+// Palette matches are drawn up from the message bar.
+func (s *TcellScreen) renderPalette(c gott.Commander) {
+	for i, line := range c.GetPaletteLines(s.size.Rows-1, s.size.Cols) {
+		for x, ch := range line {
+			s.SetCellReversed(x, s.size.Rows-2-i, ch, gott.ColorWhite)
+		}
+	}
+}
+
+func (s *TcellScreen) GetNextEvent() *gott.Event {
+	return s.GetNextEventWithTimeout(0)
+}
+
+// GetNextEventWithTimeout returns the next event, or nil if no event arrives within d.
+// If d is zero, it waits indefinitely.
+func (s *TcellScreen) GetNextEventWithTimeout(d time.Duration) *gott.Event {
+	if d > 0 {
+		// a late interrupt just ends a later wait early, which is harmless
+		timer := time.AfterFunc(d, func() { s.screen.PostEvent(tcell.NewEventInterrupt(nil)) })
+		defer timer.Stop()
+	}
+	for {
+		switch event := s.screen.PollEvent().(type) {
+		case *tcell.EventKey:
+			return tcellKeyEvent(event.Key(), event.Rune(), event.Modifiers())
+		case *tcell.EventMouse:
+			col, row := event.Position()
+			return &gott.Event{
+				Type:     gott.EventMouse,
+				Key:      tcellMouseKey(event.Buttons()),
+				Position: gott.Point{Row: row, Col: col},
+			}
+		case *tcell.EventResize:
+			s.needsLayout = true
+			s.screen.Sync()
+			return &gott.Event{Type: gott.EventResize}
+		case *tcell.EventInterrupt:
+			if d > 0 {
+				return nil
+			}
+		case nil:
+			// the screen was closed
+			return &gott.Event{Type: gott.EventKey, Key: gott.KeyUnsupported}
+		}
+	}
+}
+
+// Convert a tcell key to an event like the ones created from termbox events.
+func tcellKeyEvent(k tcell.Key, ch rune, mod tcell.ModMask) *gott.Event {
+	event := &gott.Event{Type: gott.EventKey, Mod: tcellMod(mod)}
+	if k == tcell.KeyRune {
+		if ch == ' ' {
+			// termbox reports spaces as keys
+			event.Key = gott.KeySpace
+		} else {
+			event.Ch = ch
+		}
+		return event
+	}
+	event.Key = tcellKey(k)
+	return event
+}
+
+// Map tcell keys to gott keys, just as key() does for termbox keys.
+func tcellKey(k tcell.Key) gott.Key {
+	switch k {
+	case tcell.KeyDown:
+		return gott.KeyArrowDown
+	case tcell.KeyLeft:
+		return gott.KeyArrowLeft
+	case tcell.KeyRight:
+		return gott.KeyArrowRight
+	case tcell.KeyUp:
+		return gott.KeyArrowUp
+	case tcell.KeyBacktab:
+		return gott.KeyBacktab
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		return gott.KeyBackspace2
+	case tcell.KeyEnd:
+		return gott.KeyEnd
+	case tcell.KeyEnter:
+		return gott.KeyEnter
+	case tcell.KeyEsc:
+		return gott.KeyEsc
+	case tcell.KeyHome:
+		return gott.KeyHome
+	case tcell.KeyPgDn:
+		return gott.KeyPgdn
+	case tcell.KeyPgUp:
+		return gott.KeyPgup
+	case tcell.KeyTab:
+		return gott.KeyTab
+	}
+	// tcell numbers control keys contiguously, as gott does
+	if k >= tcell.KeyCtrlA && k <= tcell.KeyCtrlZ {
+		return gott.KeyCtrlA + gott.Key(k-tcell.KeyCtrlA)
+	}
+	return gott.KeyUnsupported
+}
+
+// Map tcell modifiers to gott modifiers.
+func tcellMod(mod tcell.ModMask) gott.Mod {
+	var m gott.Mod
+	if mod&tcell.ModShift != 0 {
+		m |= gott.ModShift
+	}
+	if mod&tcell.ModAlt != 0 {
+		m |= gott.ModAlt
+	}
+	if mod&tcell.ModCtrl != 0 {
+		m |= gott.ModCtrl
+	}
+	return m
+}
+
+// Mouse buttons are reported as keys, as they are by termbox.
+func tcellMouseKey(buttons tcell.ButtonMask) gott.Key {
+	switch {
+	case buttons&tcell.Button1 != 0:
+		return gott.KeyMouseLeft
+	case buttons == tcell.ButtonNone:
+		return gott.KeyMouseRelease
+	default:
+		return gott.KeyUnsupported
+	}
+}
@@ -0,0 +1,71 @@
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package screen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	gott "github.com/timburks/gott/types"
+)
+
+func TestTcellKeyEvents(t *testing.T) {
+	cases := []struct {
+		Key      tcell.Key
+		Ch       rune
+		Mod      tcell.ModMask
+		Expected gott.Event
+	}{
+		{tcell.KeyRune, 'x', 0, gott.Event{Type: gott.EventKey, Ch: 'x'}},
+		{tcell.KeyRune, ' ', 0, gott.Event{Type: gott.EventKey, Key: gott.KeySpace}},
+		{tcell.KeyEsc, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyEsc}},
+		{tcell.KeyEnter, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyEnter}},
+		{tcell.KeyTab, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyTab}},
+		{tcell.KeyBacktab, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyBacktab}},
+		{tcell.KeyBackspace, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyBackspace2}},
+		{tcell.KeyBackspace2, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyBackspace2}},
+		{tcell.KeyUp, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyArrowUp}},
+		{tcell.KeyPgDn, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyPgdn}},
+		{tcell.KeyCtrlA, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyCtrlA}},
+		{tcell.KeyCtrlU, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyCtrlU}},
+		{tcell.KeyCtrlZ, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyCtrlZ}},
+		{tcell.KeyLeft, 0, tcell.ModCtrl, gott.Event{Type: gott.EventKey, Key: gott.KeyArrowLeft, Mod: gott.ModCtrl}},
+		{tcell.KeyRune, 'x', tcell.ModAlt, gott.Event{Type: gott.EventKey, Ch: 'x', Mod: gott.ModAlt}},
+		{tcell.KeyF1, 0, 0, gott.Event{Type: gott.EventKey, Key: gott.KeyUnsupported}},
+	}
+	for _, tc := range cases {
+		if event := tcellKeyEvent(tc.Key, tc.Ch, tc.Mod); *event != tc.Expected {
+			t.Errorf("Unexpected event for tcell key %d (%q): %+v expected %+v",
+				tc.Key, tc.Ch, *event, tc.Expected)
+		}
+	}
+}
+
+func TestTcellEventTimeout(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("Simulation screen failed: %+v", err)
+	}
+	defer screen.Fini()
+	s := &TcellScreen{screen: screen}
+	if event := s.GetNextEventWithTimeout(10 * time.Millisecond); event != nil {
+		t.Errorf("Expected no event, got %+v", *event)
+	}
+	screen.InjectKey(tcell.KeyRune, 'x', 0)
+	if event := s.GetNextEventWithTimeout(time.Second); event == nil || event.Ch != 'x' {
+		t.Errorf("Expected a key event, got %+v", event)
+	}
+}
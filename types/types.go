@@ -14,6 +14,8 @@
 
 package types
 
+import "time"
+
 // The gott editor is modal and is always in one of these modes.
 const (
 	ModeEdit           = 0 // Normal editing, command keys are active.
@@ -22,6 +24,9 @@ const (
 	ModeLisp           = 3 // Input enters Lisp expressions.
 	ModeSearchForward  = 4 // Input enters search terms.
 	ModeSearchBackward = 5 // Key input enters search terms.
+	ModeVisual         = 6 // Cursor motion extends a selection.
+	ModePrompt         = 7 // Input answers a question asked by a script.
+	ModePalette        = 8 // Input finds a command to run.
 	ModeQuit           = 9 // The editor is ready to exit.
 )
 
@@ -88,9 +93,58 @@ type Editor interface {
 	// Set the size of the screen.
 	SetSize(size Size)
 
+	// Options.
+	SetTabWidth(width int)
+	GetTabWidth() int
+	SetTextWidth(width int)
+	GetTextWidth() int
+	SetSearchCenter(center bool)
+	GetSearchCenter() bool
+	SetVirtualEdit(virtual bool)
+	GetVirtualEdit() bool
+	SetShowByteOffset(show bool)
+	GetShowByteOffset() bool
+	SetAutoWrap(wrap bool)
+	GetAutoWrap() bool
+	SetLiteralTabs(literal bool)
+	GetLiteralTabs() bool
+	SetDetectIndentation(detect bool)
+	GetDetectIndentation() bool
+	SetStatusLine(format string)
+	GetStatusLine() string
+	SetTheme(name string) error
+	GetTheme() *Theme
+	SetDimInactive(dim bool)
+	GetDimInactive() bool
+	SetMinimap(minimap bool)
+	GetMinimap() bool
+	SetChangeSigns(changeSigns bool)
+	GetChangeSigns() bool
+	SetSpell(spell bool) error
+	GetSpell() bool
+	AddSpellingWord(word string) error
+	SetIgnoreCase(ignore bool)
+	GetIgnoreCase() bool
+	SetSmartCase(smart bool)
+	GetSmartCase() bool
+	SetEscTimeout(milliseconds int)
+	GetEscTimeout() int
+	SetIdleTimeout(milliseconds int)
+	GetIdleTimeout() int
+	SetSwapInterval(milliseconds int)
+	GetSwapInterval() int
+
 	// File operations.
 	ReadFile(path string) error
+	ReadFileIntoActiveWindow(path string) error
 	WriteFile(path string) error
+	ReloadActiveBuffer() error
+	WriteSwapFiles() error
+	RemoveSwapFiles()
+	AppendFile(path string) error
+	WriteLines(path string, start, end int, appending bool) error
+	SessionScript() string
+	SetFileHandlers(onOpen, onSave func(path string))
 
 	// Direct content manipulation
 	Bytes() []byte
@@ -105,51 +159,101 @@ type Editor interface {
 	SelectWindow(number int) error
 	SelectWindowNext() error
 	SelectWindowPrevious() error
+	CreateScratchWindow()
+	CreateSnapshotWindow()
+	ViewBinary() error
+	ShowText(name string, text string)
 
 	// Text being edited is stored in buffers.
 	// Buffers can be displayed in any number of windows (including zero).
 	ListWindows()
+	ListChanges() (int, error)
+	GetRecentFiles() []string
+	SetHighlightPattern(pattern string) error
+	GetHighlightPattern() string
+	DiffThis() error
+	DiffOff()
+	HasModifiedBuffers() bool
+
+	// Mouse input uses screen positions.
+	SelectWindowAtPosition(p Point) error
+	MoveCursorToPosition(p Point)
+
+	// Manage the selection in the active window.
+	StartSelection(anchor Point)
+	ClearSelection()
+	ReselectSelection() bool
+	GetSelection() (start, end Point, ok bool)
+	SwapSelectionEnds()
+	YankSelection()
 
 	// Manage the cursor location.
 	GetCursor() Point
 	SetCursor(cursor Point)
 	MoveCursor(direction int, multiplier int)
+	MoveCursorDisplayLine(direction int, multiplier int)
 	MoveCursorToNextWord(multiplier int)
 	MoveCursorToPreviousWord(multiplier int)
+	MoveToNextParagraph(multiplier int)
+	MoveToPreviousParagraph(multiplier int)
+	MoveToNextSentence(multiplier int)
+	MoveToPreviousSentence(multiplier int)
+	ParagraphObjectRange(around bool) (start, end Point)
+	WordObjectRange(around bool) (start, end Point)
+	DelimiterRange(open, close rune) (start, end Point, ok bool)
+	MoveCursorToMatchingBracket()
+	MoveToNextDiagnostic(multiplier int)
+	MoveToPreviousDiagnostic(multiplier int)
+	MoveToNextMisspelling(multiplier int)
+	MoveToPreviousMisspelling(multiplier int)
 	MoveCursorToStartOfLine()
 	MoveCursorToStartOfLineBelowCursor()
 	MoveToBeginningOfLine()
 	MoveToEndOfLine()
 	MoveCursorToLine(line int)
+	MoveCursorForward() int
+	MoveCursorBackward() int
 	KeepCursorInRow()
 	PageUp(multiplier int)
 	PageDown(multiplier int)
 	HalfPageUp(multiplier int)
 	HalfPageDown(multiplier int)
+	CenterCursor()
 
 	// Low-level editing functions.
 	ReplaceCharacterAtCursor(cursor Point, c rune) rune
+	ReplaceRow(row int, text string) string
 	DeleteRowsAtCursor(multiplier int) string
 	DeleteWordsAtCursor(multiplier int) string
 	DeleteCharactersAtCursor(multiplier int, undo bool, finallyDeleteRow bool) string
+	DeleteRange(start, end Point, finallyDeleteRow bool) string
+	ChangeRange(start, end Point, text string) (deletedText string, insertEnd Point, mode int)
 	InsertChar(c rune)
 	BackspaceChar() rune
-	InsertText(text string, position int) (Point, int)
+	InsertText(text string, position int) (start, end Point, mode int)
 	ReverseCaseCharactersAtCursor(multiplier int)
+	InsertRow()
 	JoinRow(multiplier int) []Point
 	ChangeWordAtCursor(multiplier int, text string) (string, int)
 
 	// Cut/copy and paste support
 	YankRow(multiplier int)
 	SetPasteBoard(text string, mode int)
+	GetKillRing() []string
+	GetKill() string
+	RotateKillRing()
 	GetPasteMode() int
 	GetPasteText() string
 
 	// Operations are the preferred way to make changes.
 	// Operations are designed to be repeated and undone.
 	Perform(op Operation, multiplier int)
-	Repeat()
+	Repeat(multiplier int)
 	PerformUndo()
+	PerformRedo()
+	UndoToSave() error
+	GetUndoTime() (time.Time, bool)
+	GetRedoTime() (time.Time, bool)
 
 	// When the editor is in insert mode, the Insert operation collects changes.
 	SetInsertOperation(insert InsertOperation)
@@ -159,6 +263,9 @@ type Editor interface {
 	// Search.
 	PerformSearchForward(text string)
 	PerformSearchBackward(text string)
+	PerformWholeWordSearchForward(text string)
+	PerformWholeWordSearchBackward(text string)
+	GetWordAtCursor() string
 
 	// Additional features.
 	Gofmt(filename string, inputBytes []byte) (outputBytes []byte, err error)
@@ -178,16 +285,34 @@ type Window interface {
 	GetNumber() int
 	GetName() string
 	GetBuffer() Buffer
+	GetInfoBarText(length int) string
 	GetParent() Window
 	SetParent(w Window)
 
 	GetCursor() Point
 	SetCursor(cursor Point)
+	GetCursorDisplayColumn() int
 
 	SetCursorForDisplay(d Display)
+	EnsureCursorVisible()
+	MoveCursorToPosition(p Point)
+
+	StartSelection(anchor Point)
+	ClearSelection()
+	ReselectSelection() bool
+	GetSelection() (start, end Point, ok bool)
+	SwapSelectionEnds()
+	SetSecondaryCursors(cursors []Point)
+	GetSecondaryCursors() []Point
+	YankSelection()
+
 	PerformSearchForward(text string)
 	PerformSearchBackward(text string)
+	PerformWholeWordSearchForward(text string)
+	PerformWholeWordSearchBackward(text string)
+	GetWordAtCursor() string
 	MoveCursor(direction int, multiplier int)
+	MoveCursorDisplayLine(direction int, multiplier int)
 	MoveCursorForward() int
 	MoveCursorBackward() int
 	MoveToBeginningOfLine()
@@ -198,6 +323,18 @@ type Window interface {
 	MoveCursorBackBeforeCurrentWord() int
 	MoveCursorBackToStartOfCurrentWord()
 	MoveCursorToPreviousWord(multiplier int)
+	MoveToNextParagraph(multiplier int)
+	MoveToPreviousParagraph(multiplier int)
+	MoveToNextSentence(multiplier int)
+	MoveToPreviousSentence(multiplier int)
+	ParagraphObjectRange(around bool) (start, end Point)
+	WordObjectRange(around bool) (start, end Point)
+	DelimiterRange(open, close rune) (start, end Point, ok bool)
+	MoveCursorToMatchingBracket()
+	MoveToNextDiagnostic(multiplier int)
+	MoveToPreviousDiagnostic(multiplier int)
+	MoveToNextMisspelling(multiplier int)
+	MoveToPreviousMisspelling(multiplier int)
 	KeepCursorInRow()
 	MoveCursorToStartOfLine()
 	MoveCursorToStartOfLineBelowCursor()
@@ -206,6 +343,7 @@ type Window interface {
 	PageDown(multiplier int)
 	HalfPageUp(multiplier int)
 	HalfPageDown(multiplier int)
+	CenterCursor()
 
 	InsertChar(c rune)
 	InsertRow()
@@ -213,18 +351,21 @@ type Window interface {
 	JoinRow(multiplier int) []Point
 	YankRow(multiplier int)
 
-	InsertText(text string, position int) (Point, int)
+	InsertText(text string, position int) (start, end Point, mode int)
 	ReverseCaseCharactersAtCursor(multiplier int)
 	ReplaceCharacterAtCursor(cursor Point, c rune) rune
+	ReplaceRow(row int, text string) string
 	DeleteRowsAtCursor(multiplier int) string
 
 	DeleteWordsAtCursor(multiplier int) string
 	DeleteCharactersAtCursor(multiplier int, undo bool, finallyDeleteRow bool) string
+	DeleteRange(start, end Point, finallyDeleteRow bool) string
+	ChangeRange(start, end Point, text string) (deletedText string, insertEnd Point, mode int)
 	ChangeWordAtCursor(multiplier int, text string) (string, int)
 
 	// Display
 	Layout(r Rect)
-	Render(d Display)
+	Render(d Display, focused Window)
 
 	// Window Operations
 	SplitVertically() (Window, Window)
@@ -233,6 +374,7 @@ type Window interface {
 	GetWindowNext() Window
 	GetWindowPrevious() Window
 	FindWindow(int) Window
+	FindWindowAtPosition(p Point) Window
 }
 
 // The Buffer interface supports file-level text manipulation.
@@ -246,13 +388,71 @@ type Buffer interface {
 	// Buffer information.
 	GetName() string
 	GetReadOnly() bool
+	GetBinary() bool
+	ByteOffset(cursor Point) int
+	GetIndentation() (useTabs bool, width int, ok bool)
+	SetIndentation(useTabs bool, width int)
+	GetModified() bool
 	GetFileName() string
 	GetRowCount() int
 	GetBytes() []byte
+	BytesForRange(start, end int) []byte
+	Retab(tabWidth int, leadingOnly bool) []string
+	Entab(tabWidth int) []string
+	ReflowRange(start, end, width int) []string
+	AlignRange(start, end int, delimiter string) []string
+	ReverseRange(start, end int)
+	LineEndingRows(crlf bool) []string
+	GetLineEnding() string
+	GetMixedLineEndings() bool
+	GetLineBreak() string
+	SetLineBreak(lineBreak string)
+	GetCommentPrefix() string
+	ToggleCommentRange(start, end int) []string
 	TextFromPosition(row, col int) string
+	GetCharacterAtCursor(cursor Point) rune
+	GetRowString(n int) string
+	GetText(start, end Point) string
 
 	SetNameAndReadOnly(string, bool)
 	SetFileName(string)
+	SetModified(bool)
+
+	// Buffer-local variables hold arbitrary values for scripts.
+	SetVariable(name string, value interface{})
+	GetVariable(name string) interface{}
+
+	// Swap files hold unsaved changes for recovery.
+	GetSwapFileName() string
+	WriteSwap() error
+	GetRecovered() bool
+
+	// Change listeners are called after each change to a buffer.
+	AddChangeListener(listener func(Buffer))
+
+	// Diagnostics are problems found in a buffer.
+	SetDiagnostics(diagnostics []Diagnostic)
+	GetDiagnostics() []Diagnostic
+	GetDiagnosticForRow(row int) (Diagnostic, bool)
+}
+
+// A Diagnostic describes a problem at a position in a buffer.
+type Diagnostic struct {
+	Row     int
+	Col     int
+	Message string
+}
+
+// A Theme specifies the colors used to highlight different kinds of text.
+type Theme struct {
+	Name        string
+	Keyword     Color
+	String      Color
+	Comment     Color
+	Number      Color
+	Punctuation Color
+	Match       Color // the bracket that matches the one at the cursor
+	Error       Color // a bracket at the cursor that has no match
 }
 
 // The Highlighter interface supports text highlighting.
@@ -265,6 +465,8 @@ type Highlighter interface {
 type Operation interface {
 	// Perform an operation and return its inverse.
 	Perform(e Editor, multiplier int) Operation
+	// Change the number of times an operation is applied when it is repeated.
+	SetMultiplier(multiplier int)
 }
 
 // The InsertOperation interface supports insert operations that respond to user key commands.
@@ -272,7 +474,7 @@ type InsertOperation interface {
 	Operation
 	AddCharacter(c rune)
 	DeleteCharacter()
-	Close()
+	Close(e Editor)
 	Length() int
 }
 
@@ -280,10 +482,13 @@ type InsertOperation interface {
 type Commander interface {
 	SetMode(int)
 	GetMessageBarText(length int) string
+	GetPaletteLines(count, length int) []string
 }
 
 // Color represents a displayable color.
-type Color uint16
+// Colors are either entries in the 256-color terminal palette, numbered
+// from 1 (zero is the terminal's default color), or 24-bit RGB values.
+type Color uint32
 
 // These are named colors for use in the Display interface.
 const (
@@ -291,13 +496,110 @@ const (
 	ColorBlack = 0x01
 )
 
+// This bit marks colors that contain RGB values.
+const colorRGB = 1 << 24
+
+// RGB returns a 24-bit color with the specified components.
+func RGB(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// IsRGB returns true if a color contains RGB values.
+func (c Color) IsRGB() bool {
+	return c&colorRGB != 0
+}
+
+// RGB returns the components of a color.
+// Palette colors are converted using the standard xterm palette.
+func (c Color) RGB() (r, g, b uint8) {
+	if c.IsRGB() {
+		return uint8(c >> 16), uint8(c >> 8), uint8(c)
+	}
+	if c == 0 || c > 256 {
+		return 0xff, 0xff, 0xff
+	}
+	i := int(c) - 1
+	switch {
+	case i < 16:
+		p := systemColors[i]
+		return p[0], p[1], p[2]
+	case i < 232:
+		i -= 16
+		return cubeLevels[i/36], cubeLevels[(i/6)%6], cubeLevels[i%6]
+	default:
+		v := uint8(8 + 10*(i-232))
+		return v, v, v
+	}
+}
+
+// Indexed returns the palette color that is closest to a color.
+func (c Color) Indexed() Color {
+	if !c.IsRGB() {
+		return c
+	}
+	r, g, b := c.RGB()
+	// find the closest color in the color cube
+	ri, gi, bi := nearestCubeLevel(r), nearestCubeLevel(g), nearestCubeLevel(b)
+	best := Color(17 + 36*ri + 6*gi + bi)
+	// compare it with the closest gray
+	gray := (int(r) + int(g) + int(b)) / 3
+	grayIndex := (gray - 3) / 10
+	if grayIndex < 0 {
+		grayIndex = 0
+	}
+	if grayIndex > 23 {
+		grayIndex = 23
+	}
+	if grayColor := Color(233 + grayIndex); colorDistance(c, grayColor) < colorDistance(c, best) {
+		best = grayColor
+	}
+	return best
+}
+
+// The 16 system colors of the xterm palette.
+var systemColors = [16][3]uint8{
+	{0, 0, 0}, {128, 0, 0}, {0, 128, 0}, {128, 128, 0},
+	{0, 0, 128}, {128, 0, 128}, {0, 128, 128}, {192, 192, 192},
+	{128, 128, 128}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{0, 0, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// The component levels of the 6x6x6 color cube in the xterm palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func nearestCubeLevel(v uint8) int {
+	best := 0
+	for i, level := range cubeLevels {
+		if absDifference(v, level) < absDifference(v, cubeLevels[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func colorDistance(c1, c2 Color) int {
+	r1, g1, b1 := c1.RGB()
+	r2, g2, b2 := c2.RGB()
+	dr, dg, db := absDifference(r1, r2), absDifference(g1, g2), absDifference(b1, b2)
+	return dr*dr + dg*dg + db*db
+}
+
+func absDifference(a, b uint8) int {
+	if a > b {
+		return int(a) - int(b)
+	}
+	return int(b) - int(a)
+}
+
 // The Display interface supports text and cursor display.
 type Display interface {
 	Close()
 	GetNextEvent() *Event
+	GetNextEventWithTimeout(d time.Duration) *Event // returns nil if no event arrives in time
 	Render(Editor, Commander)
 	SetCell(j int, i int, c rune, color Color)
 	SetCellReversed(j int, i int, c rune, color Color)
+	SetCellDimmed(j int, i int, c rune, color Color)
 	SetCursor(position Point)
 }
 
@@ -305,6 +607,7 @@ type Display interface {
 const (
 	EventKey = iota
 	EventResize
+	EventMouse
 )
 
 // Key represents a keystroke value.
@@ -317,6 +620,7 @@ const (
 	KeyArrowLeft
 	KeyArrowRight
 	KeyArrowUp
+	KeyBacktab
 	KeyBackspace2
 	KeyCtrlA
 	KeyCtrlB
@@ -348,6 +652,8 @@ const (
 	KeyEnter
 	KeyEsc
 	KeyHome
+	KeyMouseLeft
+	KeyMouseRelease
 	KeyPgdn
 	KeyPgup
 	KeySpace
@@ -356,7 +662,19 @@ const (
 
 // An Event represents user input events, typically keystrokes.
 type Event struct {
-	Type int
-	Key  Key
-	Ch   rune
+	Type     int
+	Key      Key
+	Ch       rune
+	Position Point // screen position of mouse events
+	Mod      Mod   // modifier keys held down with a key
 }
+
+// Mod is a bitmask of modifier keys.
+type Mod int
+
+// Modifier keys, numbered as they are in xterm key sequences.
+const (
+	ModShift Mod = 1 << iota
+	ModAlt
+	ModCtrl
+)